@@ -0,0 +1,449 @@
+// Package client is a Go client for the REST API of the store. It logs in with
+// a username and password, keeps the token fresh and turns error responses
+// back into the errors defined in common/errors.go.
+package client
+
+import (
+	"context"
+	"demo-store/common"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRetries is how many times an idempotent call is retried after a
+// network error or a 502, 503 or 504 response.
+const DefaultRetries = 2
+
+// DefaultRetryBackoff is the wait before the first retry, doubling for each
+// retry after it.
+const DefaultRetryBackoff = 100 * time.Millisecond
+
+// TokenRefreshMargin is how long before it expires a token is replaced.
+const TokenRefreshMargin = time.Minute
+
+// The headers and parameters of the REST API, as the server names them.
+const (
+	bearerPrefix          = "Bearer "
+	contentTypeHeader     = "Content-Type"
+	contentEncodingHeader = "Content-Encoding"
+	ttlParameter          = "ttl"
+	prefixParameter       = "prefix"
+	afterParameter        = "after"
+)
+
+type Config struct {
+	// URL is where the server is listening, such as "http://localhost:8080".
+	URL      string
+	Username string
+	Password string
+
+	// Token, such as one saved from an earlier Token call, is used until the
+	// server rejects it. Without a password the client can't replace it.
+	Token string
+
+	// HttpClient sends the requests, http.DefaultClient if nil.
+	HttpClient *http.Client
+
+	// Retries overrides DefaultRetries if positive; a negative value disables
+	// retries.
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// Client is safe for concurrent use.
+type Client struct {
+	baseURL      *url.URL
+	httpClient   *http.Client
+	username     string
+	password     string
+	retries      int
+	retryBackoff time.Duration
+
+	mutex   sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func NewClient(config Config) (*Client, error) {
+
+	baseURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, errors.New("client URL needs a scheme and host")
+	}
+
+	client := &Client{
+		baseURL:      baseURL,
+		httpClient:   config.HttpClient,
+		username:     config.Username,
+		password:     config.Password,
+		retries:      DefaultRetries,
+		retryBackoff: DefaultRetryBackoff,
+	}
+	if client.httpClient == nil {
+		client.httpClient = http.DefaultClient
+	}
+	if config.Retries > 0 {
+		client.retries = config.Retries
+	} else if config.Retries < 0 {
+		client.retries = 0
+	}
+	if config.RetryBackoff > 0 {
+		client.retryBackoff = config.RetryBackoff
+	}
+	if config.Token != "" {
+		client.token = config.Token
+		client.expires = tokenExpiry(strings.TrimPrefix(config.Token, bearerPrefix))
+	}
+
+	return client, nil
+}
+
+// Login fetches a new token. Other calls log in when they need to, so calling
+// it is only necessary to check the credentials up front.
+func (c *Client) Login(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.login(ctx)
+}
+
+// Token returns the current token, logging in first if there is none, so it
+// can be saved and passed to a later client.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	return c.authorization(ctx)
+}
+
+// Entry describes a key as /list returns it. Age and TTL are milliseconds.
+type Entry struct {
+	Key    string `json:"key"`
+	Owner  string `json:"owner"`
+	Reads  int    `json:"reads"`
+	Writes int    `json:"writes"`
+	Age    int64  `json:"age"`
+	TTL    int64  `json:"ttl,omitempty"`
+
+	Version uint64 `json:"version"`
+	Flags   uint32 `json:"flags,omitempty"`
+
+	ContentType     string `json:"content_type,omitempty"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+
+	// ACL lists the access, such as "read" or "write", granted to each user.
+	ACL     map[string][]string `json:"acl,omitempty"`
+	Private bool                `json:"private,omitempty"`
+}
+
+// Value is a value with the content type and encoding it was written with.
+type Value struct {
+	Data            []byte
+	ContentType     string
+	ContentEncoding string
+}
+
+// Get returns the value of key. Like every method taking a key it checks the
+// key with common.ValidateKey first, since the server's router redirects paths
+// such as a//b instead of rejecting them.
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	value, err := c.GetValue(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return string(value.Data), nil
+}
+
+// GetValue returns the value of key with its content type and encoding. The
+// value is returned as stored, so a gzip encoded value is not decompressed.
+func (c *Client) GetValue(ctx context.Context, key string) (*Value, error) {
+	if err := common.ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	// Asking for any encoding stops the transport from decompressing the value
+	// and dropping its Content-Encoding.
+	header := http.Header{"Accept-Encoding": []string{"identity"}}
+	body, responseHeader, err := c.exchange(ctx, http.MethodGet, "/store/"+key, nil, "", header, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Value{
+		Data:            []byte(body),
+		ContentType:     responseHeader.Get(contentTypeHeader),
+		ContentEncoding: responseHeader.Get(contentEncodingHeader),
+	}, nil
+}
+
+// Put writes value to key, expiring it after ttl unless ttl is zero.
+func (c *Client) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return c.PutValue(ctx, key, Value{Data: []byte(value)}, ttl)
+}
+
+// PutValue writes value to key with its content type and encoding, expiring
+// it after ttl unless ttl is zero.
+func (c *Client) PutValue(ctx context.Context, key string, value Value, ttl time.Duration) error {
+	if err := common.ValidateKey(key); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if ttl > 0 {
+		query.Set(ttlParameter, ttl.String())
+	}
+	header := http.Header{}
+	if value.ContentType != "" {
+		header.Set(contentTypeHeader, value.ContentType)
+	}
+	if value.ContentEncoding != "" {
+		header.Set(contentEncodingHeader, value.ContentEncoding)
+	}
+	_, _, err := c.exchange(ctx, http.MethodPut, "/store/"+key, query, string(value.Data), header, true)
+	return err
+}
+
+func (c *Client) Delete(ctx context.Context, key string) error {
+	if err := common.ValidateKey(key); err != nil {
+		return err
+	}
+	_, err := c.do(ctx, http.MethodDelete, "/store/"+key, nil, "", true)
+	return err
+}
+
+// List returns the details of a key, without its value.
+func (c *Client) List(ctx context.Context, key string) (*Entry, error) {
+	if err := common.ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	body, err := c.do(ctx, http.MethodGet, "/list/"+key, nil, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal([]byte(body), &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListAll returns the details of every key in least recently used order.
+func (c *Client) ListAll(ctx context.Context) ([]*Entry, error) {
+	body, err := c.do(ctx, http.MethodGet, "/list/", nil, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	if err := json.Unmarshal([]byte(body), &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Shutdown stops the server, which only admins may do. It is never retried.
+func (c *Client) Shutdown(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodGet, "/shutdown/", nil, "", false)
+	return err
+}
+
+// do sends an authorized request and returns the response body. Idempotent
+// requests are retried, and any request rejected with 401 is sent once more
+// with a new token in case the old one expired.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body string, idempotent bool) (string, error) {
+	responseBody, _, err := c.exchange(ctx, method, path, query, body, nil, idempotent)
+	return responseBody, err
+}
+
+// exchange is do sending the request with header as well, and returning the
+// response header with the body.
+func (c *Client) exchange(ctx context.Context, method string, path string, query url.Values, body string, requestHeader http.Header, idempotent bool) (string, http.Header, error) {
+
+	retries := 0
+	if idempotent {
+		retries = c.retries
+	}
+
+	reauthorized := false
+	for attempt := 0; ; attempt++ {
+		token, err := c.authorization(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+
+		header := requestHeader.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("Authorization", token)
+		code, responseBody, responseHeader, err := c.send(ctx, method, path, query, body, header)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return "", nil, ctx.Err()
+
+		case err == nil && code == http.StatusUnauthorized && !reauthorized:
+			reauthorized = true
+			c.invalidate(token)
+			attempt--
+			continue
+
+		case (err != nil || isRetryable(code)) && attempt < retries:
+			if err := c.wait(ctx, attempt); err != nil {
+				return "", nil, err
+			}
+			continue
+
+		case err != nil:
+			return "", nil, err
+
+		case code != http.StatusOK:
+			return "", nil, errorFromResponse(code, strings.TrimSpace(responseBody))
+		}
+
+		return responseBody, responseHeader, nil
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, body string, header http.Header) (int, string, http.Header, error) {
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url(path, query), reader)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, "", nil, err
+	}
+
+	responseBody, err := readBody(resp)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	return resp.StatusCode, responseBody, resp.Header, nil
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	target := *c.baseURL
+	target.Path = strings.TrimSuffix(target.Path, "/") + path
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+func readBody(resp *http.Response) (string, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func isRetryable(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+func (c *Client) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(c.retryBackoff << attempt)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// authorization returns the cached token, logging in first if there is none
+// or it is about to expire.
+func (c *Client) authorization(ctx context.Context) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.token != "" && (c.password == "" || c.expires.IsZero() || time.Until(c.expires) > TokenRefreshMargin) {
+		return c.token, nil
+	}
+	if err := c.login(ctx); err != nil {
+		return "", err
+	}
+	return c.token, nil
+}
+
+// invalidate drops token unless another call already replaced it.
+func (c *Client) invalidate(token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.token == token {
+		c.token = ""
+	}
+}
+
+func (c *Client) login(ctx context.Context) error {
+
+	header := http.Header{}
+	credentials := base64.StdEncoding.EncodeToString([]byte(c.username + ":" + c.password))
+	header.Set("Authorization", "Basic "+credentials)
+
+	var code int
+	var body string
+	var err error
+	for attempt := 0; ; attempt++ {
+		code, body, _, err = c.send(ctx, http.MethodGet, "/login/", nil, "", header)
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if (err == nil && !isRetryable(code)) || attempt >= c.retries {
+			break
+		}
+		if err := c.wait(ctx, attempt); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return errorFromResponse(code, strings.TrimSpace(body))
+	}
+
+	c.token = strings.TrimSpace(body)
+	c.expires = tokenExpiry(strings.TrimPrefix(c.token, bearerPrefix))
+	return nil
+}
+
+// tokenExpiry reads the expiry time from a JWT without verifying it, which is
+// the server's job, returning the zero time if there is none.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.ExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.ExpiresAt, 0)
+}
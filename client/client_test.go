@@ -0,0 +1,370 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"demo-store/client"
+	"demo-store/common"
+	"demo-store/server"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockTracer struct {
+}
+
+func (h *MockTracer) LogInfo(message ...any) {
+}
+
+func (h *MockTracer) LogError(message ...any) {
+}
+
+func (h *MockTracer) LogWarning(message ...any) {
+}
+
+func (h *MockTracer) Close() {
+}
+
+func CreateMockTracer() utils.Tracer {
+	return &MockTracer{}
+}
+
+// NewMockUserDatabase returns the users the tests log in as, with passwords
+// hashed at the lowest cost so logging in stays quick.
+func NewMockUserDatabase() users.UserDatabase {
+	users.PasswordCost = bcrypt.MinCost
+	userDatabase := users.CreateUserDatabase()
+	userDatabase.AddUser("user_a", "pass_a")
+	userDatabase.AddUser("user_b", "pass_b")
+	userDatabase.AddUser("admin", "admin")
+	return userDatabase
+}
+
+type mockServer struct {
+	*httptest.Server
+	store  *store.KvStore
+	logins int32
+
+	// intercept, if set, answers requests instead of the API
+	intercept func(resp http.ResponseWriter, req *http.Request) bool
+}
+
+// startMockServer serves the API for a new store in-process.
+func startMockServer(t *testing.T) *mockServer {
+
+	kvStore := store.CreateKvStore(CreateMockTracer(), NewMockUserDatabase(), 0)
+	handler := server.NewHandler(CreateMockTracer(), kvStore)
+
+	mock := &mockServer{store: kvStore}
+	mock.Server = httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, "/login/") {
+			atomic.AddInt32(&mock.logins, 1)
+		}
+		if mock.intercept != nil && mock.intercept(resp, req) {
+			return
+		}
+		handler.ServeHTTP(resp, req)
+	}))
+	t.Cleanup(mock.Close)
+
+	return mock
+}
+
+func newMockClient(t *testing.T, mock *mockServer, username string, password string) *client.Client {
+	c, err := client.NewClient(client.Config{URL: mock.URL, Username: username, Password: password, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	return c
+}
+
+func TestNewClientRejectsInvalidURL(t *testing.T) {
+
+	for _, url := range []string{"", "localhost:8080", "://"} {
+		if _, err := client.NewClient(client.Config{URL: url}); err == nil {
+			t.Errorf("Returned unexpected error for %q: got %v want an error", url, err)
+		}
+	}
+}
+
+func TestLoginFailsWithWrongPassword(t *testing.T) {
+
+	mock := startMockServer(t)
+	c := newMockClient(t, mock, "user_a", "wrong")
+
+	if err := c.Login(context.Background()); err != common.ErrorAuthorizationFailed {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorAuthorizationFailed)
+	}
+	if _, err := c.Get(context.Background(), "key1"); err != common.ErrorAuthorizationFailed {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorAuthorizationFailed)
+	}
+}
+
+func TestStoreOperations(t *testing.T) {
+
+	mock := startMockServer(t)
+	mock.store.MakePutRequest(context.Background(), "key2", "value2", "user_b")
+	c := newMockClient(t, mock, "user_a", "pass_a")
+	ctx := context.Background()
+
+	if err := c.Put(ctx, "key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	value, err := c.Get(ctx, "key1")
+	if err != nil || value != "value1" {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, "value1")
+	}
+
+	entry, err := c.List(ctx, "key1")
+	if err != nil || entry.Owner != "user_a" || entry.Reads != 1 || entry.TTL <= 0 {
+		t.Errorf("Returned unexpected entry: got %+v, %v", entry, err)
+	}
+
+	entries, err := c.ListAll(ctx)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("Returned unexpected entries: got %v, %v want %v", entries, err, 2)
+	}
+
+	errorTests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"get missing", getError(c.Get(ctx, "missing")), common.ErrorKeyNotFound},
+		{"delete other owner", c.Delete(ctx, "key2"), common.ErrorUnauthorisedOwner},
+		{"put empty value", c.Put(ctx, "key3", "", 0), common.ErrorStoreValueNotSet},
+		{"put empty key", c.Put(ctx, "", "value", 0), common.ErrorKeyNotSet},
+		{"shutdown as user", c.Shutdown(ctx), common.ErrorPermissionDenied},
+		{"delete", c.Delete(ctx, "key1"), nil},
+		{"get deleted", getError(c.Get(ctx, "key1")), common.ErrorKeyNotFound},
+	}
+
+	for _, test := range errorTests {
+		if test.err != test.expected {
+			t.Errorf("Returned unexpected error for %v: got %v want %v", test.name, test.err, test.expected)
+		}
+	}
+
+	if logins := atomic.LoadInt32(&mock.logins); logins != 1 {
+		t.Errorf("Returned unexpected number of logins: got %v want %v", logins, 1)
+	}
+}
+
+func getError(value string, err error) error {
+	return err
+}
+
+func TestKeysAreEscaped(t *testing.T) {
+
+	mock := startMockServer(t)
+	c := newMockClient(t, mock, "user_a", "pass_a")
+	ctx := context.Background()
+
+	key := "team/what?%#"
+	if err := c.Put(ctx, key, "value1", 0); err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if value, err := mock.store.MakeGetRequest(context.Background(), key, "user_a"); err != nil || value != "value1" {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, "value1")
+	}
+	if entry, err := c.List(ctx, key); err != nil || entry.Key != key {
+		t.Errorf("Returned unexpected entry: got %v, %v want %v", entry, err, key)
+	}
+
+	if err := c.Put(ctx, "team//service", "value1", 0); err != common.ErrorInvalidKey {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorInvalidKey)
+	}
+}
+
+func TestValuesKeepContentTypeAndEncoding(t *testing.T) {
+
+	mock := startMockServer(t)
+	c := newMockClient(t, mock, "user_a", "pass_a")
+	ctx := context.Background()
+
+	value := client.Value{Data: []byte{0x1f, 0x8b, 0xff, 0x00}, ContentType: "application/json", ContentEncoding: "gzip"}
+	if err := c.PutValue(ctx, "key1", value, 0); err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	got, err := c.GetValue(ctx, "key1")
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if !bytes.Equal(got.Data, value.Data) || got.ContentType != value.ContentType || got.ContentEncoding != value.ContentEncoding {
+		t.Errorf("Returned unexpected value: got %+v want %+v", got, value)
+	}
+}
+
+func TestRejectedTokenIsRefreshed(t *testing.T) {
+
+	mock := startMockServer(t)
+	c := newMockClient(t, mock, "user_a", "pass_a")
+	c.Put(context.Background(), "key1", "value1", 0)
+
+	var rejected int32
+	mock.intercept = func(resp http.ResponseWriter, req *http.Request) bool {
+		if strings.HasPrefix(req.URL.Path, "/store/") && atomic.CompareAndSwapInt32(&rejected, 0, 1) {
+			http.Error(resp, "Unauthorized", http.StatusUnauthorized)
+			return true
+		}
+		return false
+	}
+
+	value, err := c.Get(context.Background(), "key1")
+	if err != nil || value != "value1" {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, "value1")
+	}
+	if logins := atomic.LoadInt32(&mock.logins); logins != 2 {
+		t.Errorf("Returned unexpected number of logins: got %v want %v", logins, 2)
+	}
+}
+
+func TestIdempotentCallsAreRetried(t *testing.T) {
+
+	mock := startMockServer(t)
+	c := newMockClient(t, mock, "admin", "admin")
+	c.Put(context.Background(), "key1", "value1", 0)
+
+	var failures int32 = 2
+	mock.intercept = func(resp http.ResponseWriter, req *http.Request) bool {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			http.Error(resp, "Service unavailable", http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	}
+
+	value, err := c.Get(context.Background(), "key1")
+	if err != nil || value != "value1" {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, "value1")
+	}
+
+	// shutdown isn't idempotent so the first failure is returned
+	atomic.StoreInt32(&failures, 1)
+	err = c.Shutdown(context.Background())
+	var statusErr *client.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Returned unexpected error: got %v want %v", err, http.StatusServiceUnavailable)
+	}
+
+	// gives up after the configured retries
+	atomic.StoreInt32(&failures, 3)
+	if _, err = c.Get(context.Background(), "key1"); !errors.As(err, &statusErr) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, http.StatusServiceUnavailable)
+	}
+}
+
+func TestCallsStopWhenContextIsDone(t *testing.T) {
+
+	mock := startMockServer(t)
+	c := newMockClient(t, mock, "user_a", "pass_a")
+	c.Login(context.Background())
+
+	mock.intercept = func(resp http.ResponseWriter, req *http.Request) bool {
+		<-req.Context().Done()
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Get(ctx, "key1"); err != context.DeadlineExceeded {
+		t.Errorf("Returned unexpected error: got %v want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestShutdownAsAdmin(t *testing.T) {
+
+	mock := startMockServer(t)
+	c := newMockClient(t, mock, "admin", "admin")
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+}
+
+func TestSavedTokenIsUsed(t *testing.T) {
+
+	mock := startMockServer(t)
+	token, err := newMockClient(t, mock, "user_a", "pass_a").Token(context.Background())
+	if err != nil || !strings.HasPrefix(token, "Bearer ") {
+		t.Fatalf("Returned unexpected token: got %v, %v want %v", token, err, "Bearer ...")
+	}
+
+	c, _ := client.NewClient(client.Config{URL: mock.URL, Token: token})
+	if err := c.Put(context.Background(), "key1", "value1", 0); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	entry, _ := mock.store.MakeListRequest(context.Background(), "key1", "user_a")
+	if entry == nil || entry.Owner != "user_a" {
+		t.Errorf("Returned unexpected entry: got %v want owner %v", entry, "user_a")
+	}
+	if logins := atomic.LoadInt32(&mock.logins); logins != 1 {
+		t.Errorf("Returned unexpected number of logins: got %v want %v", logins, 1)
+	}
+
+	c, _ = client.NewClient(client.Config{URL: mock.URL, Token: "Bearer invalid"})
+	if _, err := c.Get(context.Background(), "key1"); err != common.ErrorAuthorizationHeaderMissing {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorAuthorizationHeaderMissing)
+	}
+}
+
+func TestWatchDeliversEvents(t *testing.T) {
+
+	mock := startMockServer(t)
+	c := newMockClient(t, mock, "user_a", "pass_a")
+	mock.store.MakePutRequest(context.Background(), "users/a", "value1", "user_a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	started := make(chan bool)
+	go func() {
+		// the watch is registered once the first frame arrives, poll until then
+		for {
+			select {
+			case <-started:
+				return
+			case <-time.After(10 * time.Millisecond):
+				mock.store.MakePutRequest(context.Background(), "users/b", "value2", "user_a")
+			}
+		}
+	}()
+
+	var events []client.Event
+	sequence, err := c.Watch(ctx, "users/", client.WatchOptions{Prefix: true}, func(event client.Event) error {
+		events = append(events, event)
+		close(started)
+		return errors.New("stop")
+	})
+	if err == nil || err.Error() != "stop" {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "stop")
+	}
+	if len(events) != 1 || events[0].Key != "users/b" || sequence != events[0].Sequence {
+		t.Fatalf("Returned unexpected events: got %v, %v", events, sequence)
+	}
+
+	// resuming after the first event sees the rest, ending with the last one
+	var keys []string
+	c.Watch(ctx, "", client.WatchOptions{After: 1}, func(event client.Event) error {
+		keys = append(keys, event.Key)
+		if event.Sequence == sequence {
+			return errors.New("stop")
+		}
+		return nil
+	})
+	if len(keys) == 0 || keys[len(keys)-1] != "users/b" {
+		t.Errorf("Returned unexpected keys: got %v want %v", keys, "users/b last")
+	}
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"demo-store/common"
+	"fmt"
+)
+
+// StatusError is returned for responses that don't match one of the errors in
+// common/errors.go.
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// errorFromResponse returns the sentinel error the server answered with.
+func errorFromResponse(code int, message string) error {
+	if err := common.ErrorFromHttpStatus(code, message); err != nil {
+		return err
+	}
+	return &StatusError{Code: code, Message: message}
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a change to a key. Value holds the new value of a put, except for
+// events replayed from before the watch started.
+type Event struct {
+	Sequence        uint64    `json:"sequence"`
+	Type            string    `json:"type"`
+	Key             string    `json:"key"`
+	Value           []byte    `json:"value,omitempty"`
+	ContentType     string    `json:"content_type,omitempty"`
+	ContentEncoding string    `json:"content_encoding,omitempty"`
+	Owner           string    `json:"owner,omitempty"`
+	Version         uint64    `json:"version,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+type WatchOptions struct {
+	// Prefix watches every key starting with the key.
+	Prefix bool
+
+	// After resumes from the event with that sequence number.
+	After uint64
+}
+
+// Watch calls handle with each event for key, or for every key if key is
+// empty, until ctx is done, handle returns an error or the server ends the
+// stream. It returns the sequence of the last event seen, which can be passed
+// as After to resume. The HTTP client must not have a timeout for long
+// watches.
+func (c *Client) Watch(ctx context.Context, key string, options WatchOptions, handle func(event Event) error) (uint64, error) {
+
+	query := url.Values{}
+	if options.Prefix {
+		query.Set(prefixParameter, "true")
+	}
+	if options.After > 0 {
+		query.Set(afterParameter, strconv.FormatUint(options.After, 10))
+	}
+
+	sequence := options.After
+	resp, err := c.open(ctx, "/watch/"+key, query)
+	if err != nil {
+		return sequence, err
+	}
+	defer resp.Body.Close()
+
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch {
+		case line == "":
+			// a blank line ends the event, frames with only an id give the
+			// position the watch started at
+			if data.Len() > 0 {
+				var event Event
+				if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+					return sequence, err
+				}
+				sequence = event.Sequence
+				if err := handle(event); err != nil {
+					return sequence, err
+				}
+				data.Reset()
+			}
+
+		case field == "id":
+			if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+				sequence = id
+			}
+
+		case field == "data":
+			data.WriteString(value)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return sequence, ctx.Err()
+	}
+	return sequence, scanner.Err()
+}
+
+// open starts a streaming GET request, logging in again if the token is
+// rejected. Streams aren't retried since the caller resumes them.
+func (c *Client) open(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+
+	for reauthorized := false; ; reauthorized = true {
+		token, err := c.authorization(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(path, query), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", token)
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		body, _ := readBody(resp)
+		if resp.StatusCode == http.StatusUnauthorized && !reauthorized {
+			c.invalidate(token)
+			continue
+		}
+		return nil, errorFromResponse(resp.StatusCode, strings.TrimSpace(body))
+	}
+}
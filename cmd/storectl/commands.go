@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"demo-store/client"
+	"demo-store/common"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const PasswordEnvironment = "STORECTL_PASSWORD"
+
+const (
+	outputTable = "table"
+	outputText  = "text"
+	outputJson  = "json"
+)
+
+// DumpRecord is a line of a dump. Value is written in base64, so values that
+// aren't text survive the round trip.
+type DumpRecord struct {
+	Key             string `json:"key"`
+	Value           []byte `json:"value"`
+	ContentType     string `json:"content_type,omitempty"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+
+	// TTL is the milliseconds the key had left when it was dumped.
+	TTL int64 `json:"ttl,omitempty"`
+}
+
+// runLogin takes the password from --password, $STORECTL_PASSWORD or the
+// first line of stdin, in that order.
+func runLogin(ctx context.Context, env *environment, args []string) error {
+
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	username := flags.String("username", env.config.Username, "user to log in as")
+	password := flags.String("password", os.Getenv(PasswordEnvironment), "password, read from stdin if not given")
+	if err := parseFlags(flags, env, args); err != nil {
+		return err
+	}
+	if *username == "" || flags.NArg() != 0 {
+		return errorUsage
+	}
+
+	if *password == "" {
+		fmt.Fprint(env.stderr, "Password: ")
+		line, err := bufio.NewReader(env.stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	c, err := client.NewClient(client.Config{URL: env.config.URL, Username: *username, Password: *password})
+	if err != nil {
+		return err
+	}
+	token, err := c.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	config := Config{URL: env.config.URL, Username: *username, Token: token}
+	if err := saveConfig(env.configPath, config); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.stdout, "Logged in to %s as %s\n", config.URL, config.Username)
+	return nil
+}
+
+func runGet(ctx context.Context, env *environment, args []string) error {
+	if len(args) != 1 {
+		return errorUsage
+	}
+
+	c, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	value, err := c.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(env.stdout, value)
+	return err
+}
+
+// runPut reads the value from --file, the argument after the key, or stdin if
+// there is none or it is "-".
+func runPut(ctx context.Context, env *environment, args []string) error {
+
+	flags := flag.NewFlagSet("put", flag.ContinueOnError)
+	ttl := flags.Duration("ttl", 0, "expire the key after this long, such as 90s or 1h")
+	file := flags.String("file", "", "read the value from this file")
+	if err := parseFlags(flags, env, args); err != nil {
+		return err
+	}
+
+	var value string
+	switch {
+	case flags.NArg() == 2 && *file == "" && flags.Arg(1) != "-":
+		value = flags.Arg(1)
+
+	case flags.NArg() == 1 && *file != "":
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+
+	case flags.NArg() == 1 || (flags.NArg() == 2 && *file == ""):
+		data, err := io.ReadAll(env.stdin)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+
+	default:
+		return errorUsage
+	}
+
+	c, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	return c.Put(ctx, flags.Arg(0), value, *ttl)
+}
+
+func runDelete(ctx context.Context, env *environment, args []string) error {
+	if len(args) != 1 {
+		return errorUsage
+	}
+
+	c, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	return c.Delete(ctx, args[0])
+}
+
+func runList(ctx context.Context, env *environment, args []string) error {
+
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	output := flags.String("output", outputTable, "output format, table or json")
+	if err := parseFlags(flags, env, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 || (*output != outputTable && *output != outputJson) {
+		return errorUsage
+	}
+
+	c, err := env.newClient()
+	if err != nil {
+		return err
+	}
+
+	var entries []*client.Entry
+	if flags.NArg() == 1 {
+		entry, err := c.List(ctx, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	} else if entries, err = c.ListAll(ctx); err != nil {
+		return err
+	}
+
+	if *output == outputJson {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		if flags.NArg() == 1 {
+			return encoder.Encode(entries[0])
+		}
+		return encoder.Encode(entries)
+	}
+
+	table := tabwriter.NewWriter(env.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "KEY\tOWNER\tREADS\tWRITES\tAGE\tTTL\tVERSION")
+	for _, entry := range entries {
+		ttl := "-"
+		if entry.TTL > 0 {
+			ttl = formatMilliseconds(entry.TTL)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%s\t%s\t%d\n",
+			entry.Key, entry.Owner, entry.Reads, entry.Writes, formatMilliseconds(entry.Age), ttl, entry.Version)
+	}
+	return table.Flush()
+}
+
+func formatMilliseconds(milliseconds int64) string {
+	return (time.Duration(milliseconds) * time.Millisecond).Round(time.Second).String()
+}
+
+// runWatch prints events until interrupted, reconnecting from the last event
+// seen whenever the server ends the stream.
+func runWatch(ctx context.Context, env *environment, args []string) error {
+
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	prefix := flags.Bool("prefix", false, "watch every key starting with the key")
+	after := flags.Uint64("after", 0, "resume after the event with this sequence number")
+	output := flags.String("output", outputText, "output format, text or json")
+	if err := parseFlags(flags, env, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 || (*output != outputText && *output != outputJson) {
+		return errorUsage
+	}
+
+	c, err := env.newClient()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(env.stdout)
+	print := func(event client.Event) error {
+		if *output == outputJson {
+			return encoder.Encode(event)
+		}
+		_, err := fmt.Fprintf(env.stdout, "%d\t%s\t%s\t%s\n", event.Sequence, event.Type, event.Key, event.Value)
+		return err
+	}
+
+	options := client.WatchOptions{Prefix: *prefix, After: *after}
+	for {
+		sequence, err := c.Watch(ctx, flags.Arg(0), options, print)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		options.After = sequence
+	}
+}
+
+// runDump writes a line for every key. Keys deleted or expired while the dump
+// runs are left out.
+func runDump(ctx context.Context, env *environment, args []string) error {
+
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	file := flags.String("file", "", "write the dump to this file instead of stdout")
+	if err := parseFlags(flags, env, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errorUsage
+	}
+
+	c, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	entries, err := c.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	writer := env.stdout
+	if *file != "" {
+		output, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer output.Close()
+		writer = output
+	}
+
+	buffered := bufio.NewWriter(writer)
+	encoder := json.NewEncoder(buffered)
+	for _, entry := range entries {
+		value, err := c.GetValue(ctx, entry.Key)
+		if errors.Is(err, common.ErrorKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		record := DumpRecord{
+			Key:             entry.Key,
+			Value:           value.Data,
+			ContentType:     value.ContentType,
+			ContentEncoding: value.ContentEncoding,
+			TTL:             entry.TTL,
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// runRestore writes every key in a dump as the logged in user, keeping what
+// was left of their TTLs.
+func runRestore(ctx context.Context, env *environment, args []string) error {
+
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	file := flags.String("file", "", "read the dump from this file instead of stdin")
+	if err := parseFlags(flags, env, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errorUsage
+	}
+
+	reader := env.stdin
+	if *file != "" {
+		input, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer input.Close()
+		reader = input
+	}
+
+	c, err := env.newClient()
+	if err != nil {
+		return err
+	}
+
+	restored := 0
+	decoder := json.NewDecoder(reader)
+	for {
+		var record DumpRecord
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading record %d: %w", restored+1, err)
+		}
+
+		ttl := time.Duration(record.TTL) * time.Millisecond
+		value := client.Value{Data: record.Value, ContentType: record.ContentType, ContentEncoding: record.ContentEncoding}
+		if err := c.PutValue(ctx, record.Key, value, ttl); err != nil {
+			return fmt.Errorf("restoring %s: %w", record.Key, err)
+		}
+		restored++
+	}
+
+	fmt.Fprintf(env.stderr, "Restored %d keys\n", restored)
+	return nil
+}
+
+func runShutdown(ctx context.Context, env *environment, args []string) error {
+	if len(args) != 0 {
+		return errorUsage
+	}
+
+	c, err := env.newClient()
+	if err != nil {
+		return err
+	}
+	return c.Shutdown(ctx)
+}
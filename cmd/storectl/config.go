@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const ConfigEnvironment = "STORECTL_CONFIG"
+const DefaultURL = "http://localhost:8080"
+
+// Config is saved by login so later commands know where the server is and
+// can reuse the token.
+type Config struct {
+	URL      string `json:"url"`
+	Username string `json:"username,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// defaultConfigPath is $STORECTL_CONFIG, or storectl/config.json in the
+// user's configuration directory.
+func defaultConfigPath() string {
+	if path := os.Getenv(ConfigEnvironment); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "storectl", "config.json")
+}
+
+// loadConfig returns the saved config, or the defaults if there is none yet.
+func loadConfig(path string) (Config, error) {
+	config := Config{URL: DefaultURL}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// saveConfig writes the config readable only by the user, since it holds a
+// token.
+func saveConfig(path string, config Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,148 @@
+// Command storectl manages a running store through its REST API.
+//
+//	storectl [--config path] [--url url] <command> [flags] [arguments]
+//
+// Run storectl help for the list of commands.
+package main
+
+import (
+	"context"
+	"demo-store/client"
+	"demo-store/common"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+const (
+	exitOk    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// environment is what a command runs with.
+type environment struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath string
+	config     Config
+}
+
+type command struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, env *environment, args []string) error
+}
+
+var commands = map[string]command{
+	"login":    {"login [--username name] [--password password]", "log in and save the token", runLogin},
+	"get":      {"get <key>", "print the value of a key", runGet},
+	"put":      {"put [--ttl duration] [--file path] <key> [value|-]", "write a key from an argument, a file or stdin", runPut},
+	"delete":   {"delete <key>", "delete a key", runDelete},
+	"list":     {"list [--output table|json] [key]", "list every key, or the details of one", runList},
+	"watch":    {"watch [--prefix] [--after sequence] [--output text|json] [key]", "print changes as they happen", runWatch},
+	"dump":     {"dump [--file path]", "write every key and value as JSON lines", runDump},
+	"restore":  {"restore [--file path]", "write the keys from a dump", runRestore},
+	"shutdown": {"shutdown", "stop the server", runShutdown},
+}
+
+// errorUsage is returned by commands given the wrong arguments.
+var errorUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+
+	flags := flag.NewFlagSet("storectl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", defaultConfigPath(), "config file holding the server URL and token")
+	url := flags.String("url", "", "server URL, overriding the config file")
+	flags.Usage = func() { printUsage(stderr) }
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 || flags.Arg(0) == "help" {
+		printUsage(stderr)
+		return exitUsage
+	}
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "storectl: unknown command %q\n", flags.Arg(0))
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "storectl: reading %s: %v\n", *configPath, err)
+		return exitError
+	}
+	if *url != "" {
+		config.URL = *url
+	}
+
+	env := &environment{stdin: stdin, stdout: stdout, stderr: stderr, configPath: *configPath, config: config}
+	err = command.run(ctx, env, flags.Args()[1:])
+	switch {
+	case err == nil:
+		return exitOk
+
+	case errors.Is(err, errorUsage):
+		fmt.Fprintf(stderr, "usage: storectl %s\n", command.usage)
+		return exitUsage
+
+	case errors.Is(err, common.ErrorAuthorizationHeaderMissing), errors.Is(err, common.ErrorAuthorizationFailed):
+		fmt.Fprintf(stderr, "storectl: %v, run storectl login\n", err)
+		return exitError
+
+	default:
+		fmt.Fprintf(stderr, "storectl: %v\n", err)
+		return exitError
+	}
+}
+
+func printUsage(writer io.Writer) {
+	fmt.Fprintln(writer, "usage: storectl [--config path] [--url url] <command> [flags] [arguments]")
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(writer, "  %-10s %s\n", name, commands[name].description)
+	}
+}
+
+// newClient returns a client using the saved token. Streams such as watch can
+// run for a long time, so the HTTP client has no timeout and relies on the
+// context instead.
+func (env *environment) newClient() (*client.Client, error) {
+	return client.NewClient(client.Config{URL: env.config.URL, Token: env.config.Token, HttpClient: &http.Client{}})
+}
+
+// parseFlags parses the flags of a command, returning errorUsage on failure
+// so the command's usage is printed.
+func parseFlags(flags *flag.FlagSet, env *environment, args []string) error {
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {}
+	if err := flags.Parse(args); err != nil {
+		return errorUsage
+	}
+	return nil
+}
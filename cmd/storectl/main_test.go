@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"demo-store/server"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockTracer struct {
+}
+
+func (h *MockTracer) LogInfo(message ...any) {
+}
+
+func (h *MockTracer) LogError(message ...any) {
+}
+
+func (h *MockTracer) LogWarning(message ...any) {
+}
+
+func (h *MockTracer) Close() {
+}
+
+func CreateMockTracer() utils.Tracer {
+	return &MockTracer{}
+}
+
+// NewMockUserDatabase returns the users the tests log in as, with passwords
+// hashed at the lowest cost so logging in stays quick.
+func NewMockUserDatabase() users.UserDatabase {
+	users.PasswordCost = bcrypt.MinCost
+	userDatabase := users.CreateUserDatabase()
+	userDatabase.AddUser("user_a", "pass_a")
+	userDatabase.AddUser("admin", "admin")
+	return userDatabase
+}
+
+type testEnvironment struct {
+	t          *testing.T
+	store      *store.KvStore
+	url        string
+	configPath string
+}
+
+func newTestEnvironment(t *testing.T) *testEnvironment {
+
+	kvStore := store.CreateKvStore(CreateMockTracer(), NewMockUserDatabase(), 0)
+	httpServer := httptest.NewServer(server.NewHandler(CreateMockTracer(), kvStore))
+	t.Cleanup(httpServer.Close)
+
+	return &testEnvironment{t: t, store: kvStore, url: httpServer.URL, configPath: filepath.Join(t.TempDir(), "config.json")}
+}
+
+// run runs storectl with stdin as its input, returning the exit code and
+// what it wrote to stdout.
+func (e *testEnvironment) run(stdin string, args ...string) (int, string) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"--config", e.configPath, "--url", e.url}, args...)
+	code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	if code != exitOk {
+		e.t.Logf("storectl %v: %s", args, stderr.String())
+	}
+	return code, stdout.String()
+}
+
+func (e *testEnvironment) login(username string, password string) {
+	if code, _ := e.run("", "login", "--username", username, "--password", password); code != exitOk {
+		e.t.Fatalf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+}
+
+func TestLoginSavesToken(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	if code, _ := env.run("pass_a\n", "login", "--username", "user_a"); code != exitOk {
+		t.Fatalf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+
+	config, err := loadConfig(env.configPath)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if config.Username != "user_a" || !strings.HasPrefix(config.Token, utils.BearerTokenHeader) || config.URL != env.url {
+		t.Errorf("Returned unexpected config: got %+v", config)
+	}
+
+	info, err := os.Stat(env.configPath)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Returned unexpected config permissions: got %v want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+}
+
+func TestLoginFailsWithWrongPassword(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	if code, _ := env.run("", "login", "--username", "user_a", "--password", "wrong"); code != exitError {
+		t.Errorf("Returned unexpected exit code: got %v want %v", code, exitError)
+	}
+	if _, err := os.Stat(env.configPath); !os.IsNotExist(err) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "not exist")
+	}
+}
+
+func TestCommandsNeedLogin(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	if code, _ := env.run("", "get", "key1"); code != exitError {
+		t.Errorf("Returned unexpected exit code: got %v want %v", code, exitError)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	for _, args := range [][]string{{}, {"unknown"}, {"get"}, {"put", "--ttl", "soon", "key1"}, {"list", "--output", "xml"}} {
+		if code, _ := env.run("", args...); code != exitUsage {
+			t.Errorf("Returned unexpected exit code for %v: got %v want %v", args, code, exitUsage)
+		}
+	}
+}
+
+func TestPutGetDelete(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	env.login("user_a", "pass_a")
+
+	file := filepath.Join(t.TempDir(), "value")
+	os.WriteFile(file, []byte("from file"), 0600)
+
+	tests := []struct {
+		stdin string
+		args  []string
+		want  string
+	}{
+		{"", []string{"put", "key1", "from argument"}, "from argument"},
+		{"from stdin", []string{"put", "key1"}, "from stdin"},
+		{"from dash", []string{"put", "key1", "-"}, "from dash"},
+		{"", []string{"put", "--file", file, "key1"}, "from file"},
+	}
+	for _, test := range tests {
+		if code, _ := env.run(test.stdin, test.args...); code != exitOk {
+			t.Fatalf("Returned unexpected exit code for %v: got %v want %v", test.args, code, exitOk)
+		}
+		if code, value := env.run("", "get", "key1"); code != exitOk || value != test.want {
+			t.Errorf("Returned unexpected value for %v: got %v want %v", test.args, value, test.want)
+		}
+	}
+
+	if code, _ := env.run("", "delete", "key1"); code != exitOk {
+		t.Errorf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+	if code, _ := env.run("", "get", "key1"); code != exitError {
+		t.Errorf("Returned unexpected exit code: got %v want %v", code, exitError)
+	}
+}
+
+func TestPutWithTTL(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	env.login("user_a", "pass_a")
+
+	if code, _ := env.run("", "put", "--ttl", "1h", "key1", "value1"); code != exitOk {
+		t.Fatalf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+	entry, err := env.store.MakeListRequest(context.Background(), "key1", "user_a")
+	if err != nil || entry.TTL <= 0 || entry.TTL > time.Hour.Milliseconds() {
+		t.Errorf("Returned unexpected TTL: got %v, %v want at most %v", entry.TTL, err, time.Hour.Milliseconds())
+	}
+}
+
+func TestList(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	env.login("user_a", "pass_a")
+	env.store.MakePutRequest(context.Background(), "key1", "value1", "user_a")
+	env.store.MakePutRequest(context.Background(), "key2", "value2", "user_a")
+
+	code, output := env.run("", "list")
+	if code != exitOk {
+		t.Fatalf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "KEY") || !strings.Contains(output, "key1") || !strings.Contains(output, "key2") {
+		t.Errorf("Returned unexpected table: got %v", output)
+	}
+
+	code, output = env.run("", "list", "--output", "json")
+	var entries []store.Entry
+	if code != exitOk || json.Unmarshal([]byte(output), &entries) != nil || len(entries) != 2 {
+		t.Errorf("Returned unexpected JSON: got %v", output)
+	}
+
+	code, output = env.run("", "list", "--output", "json", "key1")
+	var entry store.Entry
+	if code != exitOk || json.Unmarshal([]byte(output), &entry) != nil || entry.Key != "key1" || entry.Owner != "user_a" {
+		t.Errorf("Returned unexpected JSON: got %v", output)
+	}
+}
+
+func TestDumpAndRestore(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	env.login("user_a", "pass_a")
+	env.store.MakePutRequest(context.Background(), "key1", "value1", "user_a")
+	env.store.MakePutRequestWithOptions(context.Background(), "key2", []byte("line one\nline two"), "user_a", store.PutOptions{TTL: time.Hour})
+
+	file := filepath.Join(t.TempDir(), "dump.jsonl")
+	if code, _ := env.run("", "dump", "--file", file); code != exitOk {
+		t.Fatalf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+
+	restored := newTestEnvironment(t)
+	restored.login("user_a", "pass_a")
+	if code, _ := restored.run("", "restore", "--file", file); code != exitOk {
+		t.Fatalf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+
+	for key, want := range map[string]string{"key1": "value1", "key2": "line one\nline two"} {
+		if value, err := restored.store.MakeGetRequest(context.Background(), key, "user_a"); err != nil || value != want {
+			t.Errorf("Returned unexpected value for %v: got %v, %v want %v", key, value, err, want)
+		}
+	}
+	if entry, _ := restored.store.MakeListRequest(context.Background(), "key2", "user_a"); entry == nil || entry.TTL <= 0 {
+		t.Errorf("Returned unexpected entry: got %+v want a TTL", entry)
+	}
+}
+
+func TestDumpAndRestoreKeepBinaryValues(t *testing.T) {
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte("compressed value"))
+	writer.Close()
+	value := append(compressed.Bytes(), 0xff, 0xfe, 0x00)
+
+	env := newTestEnvironment(t)
+	env.login("user_a", "pass_a")
+	options := store.PutOptions{ContentType: "application/octet-stream", ContentEncoding: "gzip"}
+	env.store.MakePutRequestWithOptions(context.Background(), "key1", value, "user_a", options)
+
+	file := filepath.Join(t.TempDir(), "dump.jsonl")
+	if code, _ := env.run("", "dump", "--file", file); code != exitOk {
+		t.Fatalf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+
+	restored := newTestEnvironment(t)
+	restored.login("user_a", "pass_a")
+	if code, _ := restored.run("", "restore", "--file", file); code != exitOk {
+		t.Fatalf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+
+	entry, err := restored.store.MakeListRequest(context.Background(), "key1", "user_a")
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if !bytes.Equal(entry.Value, value) {
+		t.Errorf("Returned unexpected value: got %v want %v", entry.Value, value)
+	}
+	if entry.ContentType != options.ContentType || entry.ContentEncoding != options.ContentEncoding {
+		t.Errorf("Returned unexpected headers: got %v, %v want %v, %v", entry.ContentType, entry.ContentEncoding, options.ContentType, options.ContentEncoding)
+	}
+}
+
+func TestWatchPrintsEvents(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	env.login("user_a", "pass_a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	output := &syncBuffer{}
+	done := make(chan int)
+	go func() {
+		args := []string{"--config", env.configPath, "--url", env.url, "watch", "--prefix", "key"}
+		done <- run(ctx, args, strings.NewReader(""), output, &bytes.Buffer{})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(output.String(), "value1") && time.Now().Before(deadline) {
+		env.store.MakePutRequest(context.Background(), "key1", "value1", "user_a")
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+
+	if code := <-done; code != exitOk {
+		t.Errorf("Returned unexpected exit code: got %v want %v", code, exitOk)
+	}
+	if !strings.Contains(output.String(), "key1\tvalue1") {
+		t.Errorf("Returned unexpected output: got %v", output.String())
+	}
+}
+
+func TestShutdownNeedsAdmin(t *testing.T) {
+
+	env := newTestEnvironment(t)
+	env.login("user_a", "pass_a")
+
+	if code, _ := env.run("", "shutdown"); code != exitError {
+		t.Errorf("Returned unexpected exit code: got %v want %v", code, exitError)
+	}
+}
+
+// syncBuffer is written by a running command while the test reads it.
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(data []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(data)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
@@ -6,9 +6,37 @@ var ErrorAuthorizationHeaderMissing = errors.New("Authorization header missing")
 var ErrorInvalidAuthorizationHeader = errors.New("Invalid authorization header")
 var ErrorAuthorizationFailed = errors.New("Authorization failed")
 var ErrorKeyNotSet = errors.New("Store key not specified")
+var ErrorInvalidKey = errors.New("Invalid key")
 var ErrorStoreValueNotSet = errors.New("Store value not specified")
+var ErrorValueTooLarge = errors.New("Value too large")
 var ErrorKeyNotFound = errors.New("Key not found")
 var ErrorUnauthorisedOwner = errors.New("Owner not authorised to update value")
+var ErrorPermissionDenied = errors.New("Permission denied")
+var ErrorInvalidAccess = errors.New("Invalid access")
+var ErrorInvalidVisibility = errors.New("Invalid visibility")
 var ErrorCreatingJwtToken error = errors.New("Error creating the token")
 var ErrorParsigJwtToken error = errors.New("Error parsing the token")
 var ErrorValidatingJwtToken error = errors.New("Error validating token")
+var ErrorWriteLogCorrupt error = errors.New("Write log is corrupt")
+var ErrorSnapshotCorrupt error = errors.New("Snapshot is corrupt")
+var ErrorPersistenceDisabled error = errors.New("Persistence is disabled")
+var ErrorInvalidTtl error = errors.New("Invalid time to live")
+var ErrorPreconditionFailed error = errors.New("Precondition failed")
+var ErrorInvalidBatch error = errors.New("Invalid batch")
+var ErrorWatchPositionLost error = errors.New("Watch position is no longer available")
+var ErrorInvalidQuery error = errors.New("Invalid query")
+var ErrorInvalidMessage error = errors.New("Invalid message")
+var ErrorStoreClosed error = errors.New("Store closed")
+
+// The user and group errors are returned by package users, but live here so
+// clients can recognise them without importing the user database.
+var ErrorUserNotFound = errors.New("User not found")
+var ErrorUserExists = errors.New("User exists")
+var ErrorUserAuthentication = errors.New("User authentication failed. Username or Password is invalid")
+var ErrorUserDisabled = errors.New("User disabled")
+var ErrorInvalidUser = errors.New("Invalid username or password")
+var ErrorUserProtected = errors.New("The admin user cannot be disabled, deleted or lose the admin role")
+var ErrorInvalidRole = errors.New("Invalid role")
+var ErrorGroupNotFound = errors.New("Group not found")
+var ErrorGroupExists = errors.New("Group exists")
+var ErrorInvalidGroup = errors.New("Invalid group name")
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxKeyLength limits the length of a key in bytes.
+const MaxKeyLength = 1024
+
+// KeySeparator splits hierarchical keys such as team/service/config.
+const KeySeparator = "/"
+
+// ValidateKey checks that key is valid UTF-8 of at most MaxKeyLength bytes
+// without control characters. Keys may be hierarchical, but every segment
+// between slashes must be non-empty and neither "." nor "..", which HTTP
+// clients and servers would otherwise rewrite as paths.
+func ValidateKey(key string) error {
+	if key == "" {
+		return ErrorKeyNotSet
+	}
+	if len(key) > MaxKeyLength || !utf8.ValidString(key) {
+		return ErrorInvalidKey
+	}
+	for _, r := range key {
+		if r < ' ' || r == 0x7f {
+			return ErrorInvalidKey
+		}
+	}
+	for _, segment := range strings.Split(key, KeySeparator) {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrorInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// HttpStatusFromError returns the HTTP status and message the REST API
+// answers err with.
+func HttpStatusFromError(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrorValidatingJwtToken):
+		return http.StatusUnauthorized, err.Error()
+
+	case errors.Is(err, ErrorKeyNotFound):
+		return http.StatusNotFound, "Key not found"
+
+	case errors.Is(err, ErrorUnauthorisedOwner):
+		return http.StatusForbidden, "Forbiden"
+
+	case errors.Is(err, ErrorPermissionDenied):
+		return http.StatusForbidden, err.Error()
+
+	case errors.Is(err, ErrorInvalidAccess), errors.Is(err, ErrorInvalidVisibility):
+		return http.StatusBadRequest, err.Error()
+
+	case errors.Is(err, ErrorAuthorizationFailed):
+		return http.StatusUnauthorized, "Unauthorized"
+
+	case errors.Is(err, ErrorInvalidAuthorizationHeader):
+		return http.StatusUnprocessableEntity, err.Error()
+
+	case errors.Is(err, ErrorAuthorizationHeaderMissing):
+		return http.StatusForbidden, "Forbidden"
+
+	case errors.Is(err, ErrorKeyNotSet):
+		return http.StatusUnprocessableEntity, err.Error()
+
+	case errors.Is(err, ErrorInvalidKey):
+		return http.StatusBadRequest, err.Error()
+
+	case errors.Is(err, ErrorStoreValueNotSet):
+		return http.StatusUnprocessableEntity, err.Error()
+
+	case errors.Is(err, ErrorValueTooLarge):
+		return http.StatusRequestEntityTooLarge, err.Error()
+
+	case errors.Is(err, ErrorPreconditionFailed):
+		return http.StatusPreconditionFailed, "Precondition failed"
+
+	case errors.Is(err, ErrorInvalidTtl):
+		return http.StatusUnprocessableEntity, err.Error()
+
+	case errors.Is(err, ErrorInvalidBatch):
+		return http.StatusBadRequest, err.Error()
+
+	case errors.Is(err, ErrorInvalidQuery):
+		return http.StatusBadRequest, err.Error()
+
+	case errors.Is(err, ErrorInvalidMessage):
+		return http.StatusBadRequest, err.Error()
+
+	case errors.Is(err, ErrorWatchPositionLost):
+		return http.StatusGone, err.Error()
+
+	case errors.Is(err, ErrorPersistenceDisabled):
+		return http.StatusNotImplemented, err.Error()
+
+	case errors.Is(err, ErrorUserNotFound), errors.Is(err, ErrorGroupNotFound):
+		return http.StatusNotFound, err.Error()
+
+	case errors.Is(err, ErrorUserExists), errors.Is(err, ErrorGroupExists):
+		return http.StatusConflict, err.Error()
+
+	case errors.Is(err, ErrorInvalidUser), errors.Is(err, ErrorInvalidRole), errors.Is(err, ErrorInvalidGroup):
+		return http.StatusBadRequest, err.Error()
+
+	case errors.Is(err, ErrorUserProtected), errors.Is(err, ErrorUserAuthentication), errors.Is(err, ErrorUserDisabled):
+		return http.StatusForbidden, err.Error()
+
+	case errors.Is(err, ErrorStoreClosed):
+		return http.StatusServiceUnavailable, err.Error()
+
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, "Request cancelled"
+
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "Store timed out"
+
+	default:
+		return http.StatusInternalServerError, err.Error()
+	}
+}
+
+// knownErrors are the errors a response can be turned back into.
+var knownErrors = []error{
+	ErrorAuthorizationHeaderMissing,
+	ErrorInvalidAuthorizationHeader,
+	ErrorAuthorizationFailed,
+	ErrorValidatingJwtToken,
+	ErrorKeyNotSet,
+	ErrorInvalidKey,
+	ErrorStoreValueNotSet,
+	ErrorValueTooLarge,
+	ErrorKeyNotFound,
+	ErrorUnauthorisedOwner,
+	ErrorPermissionDenied,
+	ErrorInvalidAccess,
+	ErrorInvalidVisibility,
+	ErrorPreconditionFailed,
+	ErrorInvalidTtl,
+	ErrorInvalidBatch,
+	ErrorInvalidQuery,
+	ErrorInvalidMessage,
+	ErrorWatchPositionLost,
+	ErrorPersistenceDisabled,
+	ErrorStoreClosed,
+	ErrorUserNotFound,
+	ErrorUserExists,
+	ErrorInvalidUser,
+	ErrorInvalidRole,
+	ErrorUserProtected,
+	ErrorUserAuthentication,
+	ErrorUserDisabled,
+	ErrorGroupNotFound,
+	ErrorGroupExists,
+	ErrorInvalidGroup,
+	context.Canceled,
+	context.DeadlineExceeded,
+}
+
+// ErrorFromHttpStatus returns the error the REST API answers with code and
+// message, or nil if it is not one of the errors in this package.
+func ErrorFromHttpStatus(code int, message string) error {
+	for _, err := range knownErrors {
+		if knownCode, knownMessage := HttpStatusFromError(err); knownCode == code && knownMessage == message {
+			return err
+		}
+	}
+	return nil
+}
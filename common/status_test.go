@@ -0,0 +1,25 @@
+package common_test
+
+import (
+	"context"
+	"demo-store/common"
+	"net/http"
+	"testing"
+)
+
+func TestErrorFromHttpStatusReturnsSentinel(t *testing.T) {
+
+	for _, expected := range []error{common.ErrorKeyNotFound, common.ErrorUserDisabled, common.ErrorInvalidGroup, context.DeadlineExceeded} {
+		code, message := common.HttpStatusFromError(expected)
+		if err := common.ErrorFromHttpStatus(code, message); err != expected {
+			t.Errorf("Returned unexpected error: got %v want %v", err, expected)
+		}
+	}
+}
+
+func TestErrorFromHttpStatusReturnsNilIfUnknown(t *testing.T) {
+
+	if err := common.ErrorFromHttpStatus(http.StatusTeapot, "I'm a teapot"); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, nil)
+	}
+}
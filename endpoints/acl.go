@@ -0,0 +1,85 @@
+package endpoints
+
+import (
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/utils"
+	"encoding/json"
+	"net/http"
+)
+
+// AclRequest is the JSON body changing who may use a key. Revoking an empty
+// list removes all of a user's access, and Owner transfers the key.
+type AclRequest struct {
+	Grant  store.ACL `json:"grant,omitempty"`
+	Revoke store.ACL `json:"revoke,omitempty"`
+	Owner  string    `json:"owner,omitempty"`
+}
+
+// AclResponse shows who may use a key.
+type AclResponse struct {
+	Key   string    `json:"key"`
+	Owner string    `json:"owner"`
+	ACL   store.ACL `json:"acl"`
+}
+
+// AclHandler serves /acl/<key>, showing the key's owner and ACL with GET and
+// letting the owner grant and revoke access or transfer the key with PATCH.
+type AclHandler struct {
+	Tracer     utils.Tracer
+	httpMethod string
+	store      store.Store
+}
+
+func (p *AclHandler) HttpMethod() string {
+	return p.httpMethod
+}
+
+func (p *AclHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWriter, req *http.Request) HttpResult {
+	return p.handleRequest(args, req, resp)
+}
+
+func (p *AclHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
+
+	username := args.Get(UsernameParameter)
+	if username == "" {
+		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
+	}
+
+	key, err := GetKey(req, args.Get(PathParameter))
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+	if key == "" {
+		return CreateHttpResponseFromError(common.ErrorKeyNotSet)
+	}
+
+	var entry *store.Entry
+	if p.httpMethod == http.MethodPatch {
+		entry, err = p.change(key, username, req)
+	} else {
+		entry, err = p.store.MakeListRequest(req.Context(), key, username)
+	}
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	if err := writeResponse(AclResponse{Key: entry.Key, Owner: entry.Owner, ACL: entry.ACL}, resp); err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+	return CreateHttpResponse("Ok", http.StatusOK)
+}
+
+func (p *AclHandler) change(key string, username string, req *http.Request) (*store.Entry, error) {
+
+	var request AclRequest
+	if err := json.Unmarshal(GetBodyBytes(req), &request); err != nil {
+		return nil, common.ErrorInvalidMessage
+	}
+	if request.Grant == nil && request.Revoke == nil && request.Owner == "" {
+		return nil, common.ErrorInvalidAccess
+	}
+
+	change := store.AclChange{Grant: request.Grant, Revoke: request.Revoke, Owner: request.Owner}
+	return p.store.MakeAclRequest(req.Context(), key, username, change)
+}
@@ -0,0 +1,95 @@
+package endpoints_test
+
+import (
+	"bytes"
+	"context"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"demo-store/store"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func createMockAclRequest(kvStore store.Store, method string, key string, body string, username string) *httptest.ResponseRecorder {
+
+	route := endpoints.CreateAclRoute(&MockTracer{}, kvStore, NewMockAuthenticatorWithValue(username))
+	req, err := http.NewRequest(method, "/acl/"+key, bytes.NewBufferString(body))
+	if err != nil {
+		return nil
+	}
+
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestAclCanOnlyBeChangedByOwner(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+
+	rr := createMockAclRequest(mockStore, http.MethodPatch, input1.Key, `{"grant": {"user2": ["write"]}}`, input2.Owner)
+	AssertErrorHttpCode(common.ErrorUnauthorisedOwner, rr.Code, t)
+
+	rr = createMockAclRequest(mockStore, http.MethodPatch, input1.Key, `{"grant": {"user2": ["own"]}}`, input1.Owner)
+	AssertErrorHttpCode(common.ErrorInvalidAccess, rr.Code, t)
+
+	rr = createMockAclRequest(mockStore, http.MethodPatch, "missing", `{"grant": {"user2": ["write"]}}`, input1.Owner)
+	AssertErrorHttpCode(common.ErrorKeyNotFound, rr.Code, t)
+}
+
+func TestAclGrantsAccessToOtherUsers(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+
+	rr := createMockAclRequest(mockStore, http.MethodPatch, input1.Key, `{"grant": {"user2": ["write", "read"], "user3": ["delete"]}}`, input1.Owner)
+	var acl endpoints.AclResponse
+	json.Unmarshal(rr.Body.Bytes(), &acl)
+	expected := store.ACL{"user2": {store.AccessRead, store.AccessWrite}, "user3": {store.AccessDelete}}
+	if rr.Code != http.StatusOK || !reflect.DeepEqual(acl.ACL, expected) {
+		t.Errorf("handler returned unexpected acl: got %v %v want %v", rr.Code, acl.ACL, expected)
+	}
+
+	if err := mockStore.MakePutRequest(context.Background(), input1.Key, "shared", input2.Owner); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if _, err := mockStore.MakeGetRequest(context.Background(), input1.Key, "user3"); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	createMockAclRequest(mockStore, http.MethodPatch, input1.Key, `{"revoke": {"user2": []}}`, input1.Owner)
+	if err := mockStore.MakePutRequest(context.Background(), input1.Key, "mine", input2.Owner); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+
+	rr = createMockAclRequest(mockStore, http.MethodGet, input1.Key, "", input2.Owner)
+	acl = endpoints.AclResponse{}
+	json.Unmarshal(rr.Body.Bytes(), &acl)
+	expected = store.ACL{"user3": {store.AccessDelete}}
+	if rr.Code != http.StatusOK || acl.Owner != input1.Owner || !reflect.DeepEqual(acl.ACL, expected) {
+		t.Errorf("handler returned unexpected acl: got %v %+v want %v", rr.Code, acl, expected)
+	}
+}
+
+func TestAclTransfersOwnership(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+
+	rr := createMockAclRequest(mockStore, http.MethodPatch, input1.Key, `{"owner": "user2"}`, input1.Owner)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned unexpected code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	if err := mockStore.MakeDeleteRequest(context.Background(), input1.Key, input1.Owner); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+	if err := mockStore.MakeDeleteRequest(context.Background(), input1.Key, input2.Owner); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+}
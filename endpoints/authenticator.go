@@ -4,6 +4,7 @@ import (
 	"demo-store/common"
 	"demo-store/users"
 	"demo-store/utils"
+	"errors"
 	"strings"
 )
 
@@ -19,6 +20,12 @@ func NewRouteAuthenticator(tracer utils.Tracer) Authenticator {
 	return &RouteAuthenticator{Tokenizer: utils.NewJwtTokenizer(tracer)}
 }
 
+// NewRouteAuthenticatorWithUsers also turns away users who were disabled,
+// and tokens of users since deleted.
+func NewRouteAuthenticatorWithUsers(tracer utils.Tracer, users users.UserDatabase) Authenticator {
+	return &RouteAuthenticator{Tokenizer: utils.NewJwtTokenizer(tracer), UserDatabase: users}
+}
+
 func NewRouteAuthenticatorWithTokenizer(tracer utils.Tracer, tokenizer utils.Tokenizer) Authenticator {
 	return &RouteAuthenticator{Tokenizer: tokenizer}
 }
@@ -35,9 +42,23 @@ func (p *RouteAuthenticator) GetUsername(bearerToken string) (string, error) {
 		if err != nil {
 			return "", err
 		}
+		if strings.HasPrefix(username, users.GroupPrefix) {
+			return "", common.ErrorAuthorizationFailed
+		}
+		if p.UserDatabase != nil && p.UserDatabase.CheckUser(username) != nil {
+			return "", common.ErrorAuthorizationFailed
+		}
 
 		return username, nil
 	}
 
+	// plain usernames needn't be registered, but can't be ones disabled or
+	// names of groups, which would act as the group
+	if strings.HasPrefix(bearerToken, users.GroupPrefix) {
+		return "", common.ErrorAuthorizationFailed
+	}
+	if p.UserDatabase != nil && errors.Is(p.UserDatabase.CheckUser(bearerToken), users.ErrorUserDisabled) {
+		return "", common.ErrorAuthorizationFailed
+	}
 	return bearerToken, nil
 }
@@ -73,3 +73,32 @@ func TestAuthenticatorTokenizerErrorIsHandled(t *testing.T) {
 		t.Errorf("Returned unexpected error: got %v want %v", err, expected)
 	}
 }
+
+func TestAuthenticatorRejectsDisabledUsers(t *testing.T) {
+
+	userDatabase := CreateMockUserDatabase()
+	userDatabase.AddUser("user1", "1111")
+	userDatabase.SetDisabled("user1", true)
+	auth := endpoints.NewRouteAuthenticatorWithUsers(CreateMockTracer(), userDatabase)
+
+	if _, err := auth.GetUsername("user1"); err != common.ErrorAuthorizationFailed {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorAuthorizationFailed)
+	}
+	if username, err := auth.GetUsername("user2"); err != nil || username != "user2" {
+		t.Errorf("Returned unexpected username: got %v, %v want %v", username, err, "user2")
+	}
+}
+
+func TestAuthenticatorRejectsGroupNames(t *testing.T) {
+
+	auth := endpoints.NewRouteAuthenticatorWithUsers(CreateMockTracer(), CreateMockUserDatabase())
+	if _, err := auth.GetUsername("group:team"); err != common.ErrorAuthorizationFailed {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorAuthorizationFailed)
+	}
+
+	token := "Bearer 123456"
+	auth = endpoints.NewRouteAuthenticatorWithTokenizer(CreateMockTracer(), NewMockTokenizer("group:team", nil))
+	if _, err := auth.GetUsername(token); err != common.ErrorAuthorizationFailed {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorAuthorizationFailed)
+	}
+}
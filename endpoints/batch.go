@@ -0,0 +1,113 @@
+package endpoints
+
+import (
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const MaxBatchOperations = 1000
+
+// BatchOperation is the JSON form of a single operation in a POST /batch body.
+type BatchOperation struct {
+	Op          string `json:"op"`
+	Key         string `json:"key"`
+	Value       string `json:"value,omitempty"`
+	TTL         string `json:"ttl,omitempty"`
+	IfMatch     string `json:"if_match,omitempty"`
+	IfNoneMatch string `json:"if_none_match,omitempty"`
+}
+
+type BatchHandler struct {
+	Tracer     utils.Tracer
+	httpMethod string
+	store      store.Store
+}
+
+func (p *BatchHandler) HttpMethod() string {
+	return p.httpMethod
+}
+
+func (p *BatchHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWriter, req *http.Request) HttpResult {
+	return p.handleRequest(args, req, resp)
+}
+
+func (p *BatchHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
+
+	username := args.Get(UsernameParameter)
+	if username == "" {
+		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
+	}
+
+	operations, err := ParseBatch(GetBody(req))
+	if err != nil {
+		return createBatchResponseFromError(err)
+	}
+
+	results, err := p.store.MakeBatchRequest(req.Context(), operations, username)
+	if err != nil {
+		return createBatchResponseFromError(err)
+	}
+
+	err = writeResponse(results, resp)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	return CreateHttpResponse("Ok", http.StatusOK)
+}
+
+// ParseBatch converts a JSON list of operations into store operations,
+// checking each has what its op needs.
+func ParseBatch(body string) ([]store.BatchOperation, error) {
+
+	var requested []BatchOperation
+	if err := json.Unmarshal([]byte(body), &requested); err != nil {
+		return nil, common.ErrorInvalidBatch
+	}
+	if len(requested) == 0 || len(requested) > MaxBatchOperations {
+		return nil, common.ErrorInvalidBatch
+	}
+
+	operations := make([]store.BatchOperation, 0, len(requested))
+	for i, operation := range requested {
+		switch {
+		case operation.Op != store.BatchOpPut && operation.Op != store.BatchOpGet && operation.Op != store.BatchOpDelete:
+			return nil, &store.BatchError{Index: i, Err: common.ErrorInvalidBatch}
+		case operation.Key == "":
+			return nil, &store.BatchError{Index: i, Err: common.ErrorKeyNotSet}
+		}
+
+		ttl, err := ParseTtl(operation.TTL)
+		if err != nil {
+			return nil, &store.BatchError{Index: i, Err: err}
+		}
+
+		operations = append(operations, store.BatchOperation{
+			Op:    operation.Op,
+			Key:   operation.Key,
+			Value: []byte(operation.Value),
+			TTL:   ttl,
+			Precondition: store.Precondition{
+				IfMatch:     ParseVersionMatch(operation.IfMatch),
+				IfNoneMatch: ParseVersionMatch(operation.IfNoneMatch),
+			},
+		})
+	}
+
+	return operations, nil
+}
+
+func createBatchResponseFromError(err error) HttpResult {
+	result := CreateHttpResponseFromError(err)
+
+	var batchErr *store.BatchError
+	if errors.As(err, &batchErr) {
+		result.Message = fmt.Sprintf("Operation %d: %s", batchErr.Index, result.Message)
+	}
+	return result
+}
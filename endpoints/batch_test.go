@@ -0,0 +1,93 @@
+package endpoints_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"demo-store/store"
+	"demo-store/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func CreateMockRouteWithBatch(path string, tracer utils.Tracer, kvStore store.Store, authenticator endpoints.Authenticator) endpoints.Route {
+	var methods []endpoints.HttpMethodHandler
+	methods = append(methods, endpoints.CreateBatch(tracer, kvStore))
+
+	return &endpoints.SecureRoute{Path: path, Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+func createMockBatchRequestWithUsername(store store.Store, username string, body string) *httptest.ResponseRecorder {
+	path := "/batch"
+
+	route := CreateMockRouteWithBatch(path, CreateMockTracer(), store, NewMockAuthenticator(username))
+	req, err := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+
+	if err != nil {
+		return nil
+	}
+
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestBatchReturnsResults(t *testing.T) {
+
+	mockStore := NewMockStore()
+	body := `[{"op":"put","key":"key1","value":"some value 1","if_none_match":"*"},{"op":"get","key":"key1"}]`
+	rr := createMockBatchRequestWithUsername(mockStore, input1.Owner, body)
+
+	expected := http.StatusOK
+	if rr.Code != expected {
+		t.Fatalf("handler returned unexpected code: got %v want %v", rr.Code, expected)
+	}
+
+	var results []store.BatchResult
+	json.Unmarshal(rr.Body.Bytes(), &results)
+	if len(results) != 2 || results[1].Value != input1.Value {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), input1.Value)
+	}
+}
+
+func TestBatchReturnsErrorOfFailedOperation(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	body := `[{"op":"put","key":"key2","value":"v"},{"op":"put","key":"key1","value":"v","if_match":"\"999\""}]`
+	rr := createMockBatchRequestWithUsername(mockStore, input1.Owner, body)
+
+	AssertErrorHttpCode(common.ErrorPreconditionFailed, rr.Code, t)
+	if !strings.HasPrefix(rr.Body.String(), "Operation 1:") {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), "Operation 1: ...")
+	}
+
+	if _, err := mockStore.MakeGetRequest(context.Background(), "key2", input1.Owner); err != common.ErrorKeyNotFound {
+		t.Errorf("handler returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+}
+
+func TestBatchRejectsInvalidBody(t *testing.T) {
+
+	mockStore := NewMockStore()
+
+	for _, body := range []string{"", "[]", "{}", `[{"op":"rename","key":"k"}]`} {
+		rr := createMockBatchRequestWithUsername(mockStore, input1.Owner, body)
+		AssertErrorHttpCode(common.ErrorInvalidBatch, rr.Code, t)
+	}
+
+	rr := createMockBatchRequestWithUsername(mockStore, input1.Owner, `[{"op":"put","key":"key1"}]`)
+	AssertErrorHttpCode(common.ErrorStoreValueNotSet, rr.Code, t)
+}
+
+func TestBatchReturnsErrorIfOwnerIsMissing(t *testing.T) {
+
+	mockStore := NewMockStore()
+	rr := createMockBatchRequestWithUsername(mockStore, "", `[{"op":"get","key":"key1"}]`)
+
+	AssertErrorHttpCode(common.ErrorAuthorizationHeaderMissing, rr.Code, t)
+}
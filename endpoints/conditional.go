@@ -0,0 +1,49 @@
+package endpoints
+
+import (
+	"demo-store/store"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	ETagHeader        = "ETag"
+	IfMatchHeader     = "If-Match"
+	IfNoneMatchHeader = "If-None-Match"
+)
+
+func FormatETag(version uint64) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// ParseVersionMatch parses the value of an If-Match or If-None-Match header,
+// returning nil if the header is empty. ETags that are not versions issued by
+// the store are kept out of the list so they never match.
+func ParseVersionMatch(header string) *store.VersionMatch {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil
+	}
+	if header == "*" {
+		return &store.VersionMatch{Any: true}
+	}
+
+	match := store.VersionMatch{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		version, err := strconv.ParseUint(strings.Trim(tag, "\""), 10, 64)
+		if err == nil {
+			match.Versions = append(match.Versions, version)
+		}
+	}
+	return &match
+}
+
+func GetPrecondition(req *http.Request) store.Precondition {
+	return store.Precondition{
+		IfMatch:     ParseVersionMatch(req.Header.Get(IfMatchHeader)),
+		IfNoneMatch: ParseVersionMatch(req.Header.Get(IfNoneMatchHeader)),
+	}
+}
@@ -0,0 +1,113 @@
+package endpoints_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"demo-store/store"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func createMockConditionalRequest(store store.Store, method string, header string, etag string) *httptest.ResponseRecorder {
+	path := "store"
+
+	var route endpoints.Route
+	switch method {
+	case http.MethodPut:
+		route = CreateMockRouteWithPut(path, CreateMockTracer(), store, NewMockAuthenticator(input1.Owner))
+	case http.MethodDelete:
+		route = CreateMockRouteWithDelete(path, CreateMockTracer(), store, NewMockAuthenticator(input1.Owner))
+	}
+
+	req, _ := http.NewRequest(method, path+input1.Key, strings.NewReader(input2.Value))
+	req.Header.Set(header, etag)
+
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestParseVersionMatch(t *testing.T) {
+
+	if match := endpoints.ParseVersionMatch(""); match != nil {
+		t.Errorf("handler returned unexpected match: got %v want %v", match, nil)
+	}
+
+	if match := endpoints.ParseVersionMatch("*"); match == nil || !match.Any {
+		t.Errorf("handler returned unexpected match: got %v want %v", match, "any")
+	}
+
+	match := endpoints.ParseVersionMatch(`"3", W/"7", "other"`)
+	if len(match.Versions) != 2 || match.Versions[0] != 3 || match.Versions[1] != 7 {
+		t.Errorf("handler returned unexpected versions: got %v want %v", match.Versions, []uint64{3, 7})
+	}
+}
+
+func TestGetReturnsETag(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	entry, _ := mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
+
+	rr := createMockGetRequestWithUsername(mockStore, input1.Key, input1.Owner)
+	expected := endpoints.FormatETag(entry.Version)
+	if rr.Header().Get(endpoints.ETagHeader) != expected {
+		t.Errorf("handler returned unexpected etag: got %v want %v", rr.Header().Get(endpoints.ETagHeader), expected)
+	}
+
+	rr = createMockListRequestWithUsername(mockStore, input1.Key, input1.Owner)
+	if rr.Header().Get(endpoints.ETagHeader) != expected {
+		t.Errorf("handler returned unexpected etag: got %v want %v", rr.Header().Get(endpoints.ETagHeader), expected)
+	}
+}
+
+func TestPutIfNoneMatchAnyReturnsPreconditionFailed(t *testing.T) {
+
+	mockStore := NewMockStore()
+	rr := createMockConditionalRequest(mockStore, http.MethodPut, endpoints.IfNoneMatchHeader, "*")
+
+	expected := http.StatusOK
+	if rr.Code != expected {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
+	}
+
+	rr = createMockConditionalRequest(mockStore, http.MethodPut, endpoints.IfNoneMatchHeader, "*")
+	AssertErrorHttpCode(common.ErrorPreconditionFailed, rr.Code, t)
+}
+
+func TestPutIfMatchStaleETagReturnsPreconditionFailed(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	entry, _ := mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
+	etag := endpoints.FormatETag(entry.Version)
+
+	rr := createMockConditionalRequest(mockStore, http.MethodPut, endpoints.IfMatchHeader, etag)
+	expected := http.StatusOK
+	if rr.Code != expected {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
+	}
+
+	rr = createMockConditionalRequest(mockStore, http.MethodPut, endpoints.IfMatchHeader, etag)
+	AssertErrorHttpCode(common.ErrorPreconditionFailed, rr.Code, t)
+}
+
+func TestDeleteIfMatchStaleETagReturnsPreconditionFailed(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	entry, _ := mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
+
+	rr := createMockConditionalRequest(mockStore, http.MethodDelete, endpoints.IfMatchHeader, endpoints.FormatETag(entry.Version+1))
+	AssertErrorHttpCode(common.ErrorPreconditionFailed, rr.Code, t)
+
+	rr = createMockConditionalRequest(mockStore, http.MethodDelete, endpoints.IfMatchHeader, endpoints.FormatETag(entry.Version))
+	expected := http.StatusOK
+	if rr.Code != expected {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"demo-store/store"
 	"demo-store/utils"
 	"net/http"
-	"strings"
 )
 
 type DeleteHandler struct {
@@ -25,8 +24,10 @@ func (p *DeleteHandler) Handle(args *HttpMethodHandlerParams, resp http.Response
 func (p *DeleteHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
 
 	path := args.Get(PathParameter)
-	key := strings.Trim(req.URL.Path, path)
-
+	key, err := GetKey(req, path)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
 	if key == "" {
 		return CreateHttpResponseFromError(common.ErrorKeyNotSet)
 	}
@@ -36,7 +37,8 @@ func (p *DeleteHandler) handleRequest(args *HttpMethodHandlerParams, req *http.R
 		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
 	}
 
-	err := p.store.MakeDeleteRequest(key, username)
+	options := store.DeleteOptions{Precondition: GetPrecondition(req)}
+	err = p.store.MakeDeleteRequestWithOptions(req.Context(), key, username, options)
 	if err != nil {
 		return CreateHttpResponseFromError(err)
 	}
@@ -1,6 +1,7 @@
 package endpoints_test
 
 import (
+	"context"
 	"demo-store/common"
 	"demo-store/endpoints"
 	"demo-store/store"
@@ -33,7 +34,7 @@ func createMockDeleteRequestWithUsername(store store.Store, url string, username
 func TestDeleteRemovesKeyFromStore(t *testing.T) {
 	mockStore := NewMockStore()
 	// add data
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 
 	rr := createMockDeleteRequestWithUsername(mockStore, input1.Key, input1.Owner)
 	expected := http.StatusOK
@@ -41,7 +42,7 @@ func TestDeleteRemovesKeyFromStore(t *testing.T) {
 		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
 	}
 
-	_, err := mockStore.MakeListRequest(input1.Key)
+	_, err := mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
 	expectedError := common.ErrorKeyNotFound
 	if err != expectedError {
 		t.Errorf("handler returned unexpected code: got %v want %v", err.Error(), expectedError)
@@ -86,7 +87,7 @@ func TestDeleteReturnsForbiddenIfOwnerIsNotTheCreator(t *testing.T) {
 
 	mockStore := NewMockStore()
 	// add data
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 
 	rr := createMockDeleteRequestWithUsername(mockStore, input1.Key, input2.Owner)
 	AssertErrorHttpCode(common.ErrorUnauthorisedOwner, rr.Code, t)
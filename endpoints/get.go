@@ -5,9 +5,11 @@ import (
 	"demo-store/store"
 	"demo-store/utils"
 	"net/http"
-	"strings"
 )
 
+// DefaultContentType is returned for values put without a Content-Type.
+const DefaultContentType = "text/plain"
+
 type GetHandler struct {
 	Tracer     utils.Tracer
 	httpMethod string
@@ -25,8 +27,10 @@ func (p *GetHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWri
 func (p *GetHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
 
 	path := args.Get(PathParameter)
-	key := strings.Trim(req.URL.Path, path)
-
+	key, err := GetKey(req, path)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
 	if key == "" {
 		return CreateHttpResponseFromError(common.ErrorKeyNotSet)
 	}
@@ -36,13 +40,21 @@ func (p *GetHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Requ
 		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
 	}
 
-	value, err := p.store.MakeGetRequest(key)
+	entry, err := p.store.MakeReadRequest(req.Context(), key, username)
 	if err != nil {
 		return CreateHttpResponseFromError(err)
 	}
 
-	resp.Header().Set("Content-Type", "text/plain")
-	resp.Write([]byte(value))
+	contentType := entry.ContentType
+	if contentType == "" {
+		contentType = DefaultContentType
+	}
+	resp.Header().Set(ContentTypeHeader, contentType)
+	if entry.ContentEncoding != "" {
+		resp.Header().Set(ContentEncodingHeader, entry.ContentEncoding)
+	}
+	resp.Header().Set(ETagHeader, FormatETag(entry.Version))
+	resp.Write(entry.Value)
 
 	return CreateHttpResponse("Ok", http.StatusOK)
 }
@@ -1,6 +1,8 @@
 package endpoints_test
 
 import (
+	"bytes"
+	"context"
 	"demo-store/common"
 	"demo-store/endpoints"
 	"demo-store/store"
@@ -54,7 +56,7 @@ func TestGetReturnsErrorIfKeyNotFound(t *testing.T) {
 func TestGetReturnsSuccess(t *testing.T) {
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 	rr := createMockGetRequestWithUsername(mockStore, input1.Key, input1.Owner)
 
 	expectedCode := http.StatusOK
@@ -72,3 +74,54 @@ func TestGetReturnsSuccess(t *testing.T) {
 		}
 	}
 }
+
+func TestGetReplaysContentTypeAndEncoding(t *testing.T) {
+
+	mockStore := NewMockStore()
+	value := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0x00}
+
+	route := CreateMockRouteWithPut("store", CreateMockTracer(), mockStore, NewMockAuthenticator(input1.Owner))
+	req, _ := http.NewRequest(http.MethodPut, "store"+input1.Key, bytes.NewReader(value))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned unexpected code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	rr = createMockGetRequestWithUsername(mockStore, input1.Key, input1.Owner)
+	if rr.Code != http.StatusOK || !bytes.Equal(rr.Body.Bytes(), value) {
+		t.Errorf("handler returned unexpected body: got %v %v want %v", rr.Code, rr.Body.Bytes(), value)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("handler returned unexpected content type: got %v want %v", contentType, "application/json")
+	}
+	if encoding := rr.Header().Get("Content-Encoding"); encoding != "gzip" {
+		t.Errorf("handler returned unexpected content encoding: got %v want %v", encoding, "gzip")
+	}
+}
+
+func TestGetDefaultsToTextPlain(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	rr := createMockGetRequestWithUsername(mockStore, input1.Key, input1.Owner)
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != endpoints.DefaultContentType {
+		t.Errorf("handler returned unexpected content type: got %v want %v", contentType, endpoints.DefaultContentType)
+	}
+	if encoding := rr.Header().Get("Content-Encoding"); encoding != "" {
+		t.Errorf("handler returned unexpected content encoding: got %v want %v", encoding, "")
+	}
+}
+
+func TestGetReturnsErrorIfStoreIsShutDown(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	mockStore.MakeShutdownRequest(context.Background())
+	rr := createMockGetRequestWithUsername(mockStore, input1.Key, input1.Owner)
+
+	AssertErrorHttpCode(common.ErrorStoreClosed, rr.Code, t)
+}
@@ -0,0 +1,164 @@
+package endpoints
+
+import (
+	"demo-store/common"
+	"demo-store/users"
+	"demo-store/utils"
+	"encoding/json"
+	"net/http"
+)
+
+// GroupRequest is the JSON body creating a group with its members or adding
+// and removing members of one.
+type GroupRequest struct {
+	Name    string   `json:"name,omitempty"`
+	Members []string `json:"members,omitempty"`
+	Add     []string `json:"add,omitempty"`
+	Remove  []string `json:"remove,omitempty"`
+}
+
+// GroupResponse is how a group is shown, with the owner naming it on keys.
+type GroupResponse struct {
+	Name    string   `json:"name"`
+	Owner   string   `json:"owner"`
+	Members []string `json:"members"`
+}
+
+// GroupsHandler serves the group API under /admin/groups/ to users allowed to
+// manage users, creating groups with POST, listing or showing them with GET,
+// adding and removing members with PATCH and deleting them with DELETE.
+type GroupsHandler struct {
+	Tracer     utils.Tracer
+	httpMethod string
+	users      users.UserDatabase
+}
+
+func (p *GroupsHandler) HttpMethod() string {
+	return p.httpMethod
+}
+
+func (p *GroupsHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWriter, req *http.Request) HttpResult {
+	return p.handleRequest(args, req, resp)
+}
+
+func (p *GroupsHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
+
+	username := args.Get(UsernameParameter)
+	if username == "" {
+		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
+	}
+
+	if !p.users.HasPermission(username, users.PermissionManageUsers) {
+		return CreateHttpResponseFromError(common.ErrorPermissionDenied)
+	}
+
+	name, err := DecodeKeyPath(req, args.Get(PathParameter))
+	if err != nil {
+		return CreateHttpResponseFromError(users.ErrorInvalidGroup)
+	}
+
+	switch p.httpMethod {
+	case http.MethodPost:
+		err = p.create(name, req, resp)
+	case http.MethodPatch:
+		err = p.update(name, req, resp)
+	case http.MethodDelete:
+		err = p.users.DeleteGroup(name)
+	default:
+		err = p.show(name, resp)
+	}
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	return CreateHttpResponse("Ok", http.StatusOK)
+}
+
+func (p *GroupsHandler) show(name string, resp http.ResponseWriter) error {
+
+	if name == "" {
+		list := p.users.ListGroups()
+		responses := make([]GroupResponse, 0, len(list))
+		for _, group := range list {
+			responses = append(responses, p.groupResponse(&group))
+		}
+		return writeResponse(responses, resp)
+	}
+
+	group, err := p.users.FindGroup(name)
+	if err != nil {
+		return err
+	}
+	return writeResponse(p.groupResponse(group), resp)
+}
+
+func (p *GroupsHandler) create(name string, req *http.Request, resp http.ResponseWriter) error {
+
+	request, err := parseGroupRequest(req)
+	if err != nil {
+		return err
+	}
+	if request.Name == "" {
+		request.Name = name
+	}
+	if name != "" && name != request.Name {
+		return users.ErrorInvalidGroup
+	}
+
+	for _, member := range request.Members {
+		if _, err := p.users.FindUser(member); err != nil {
+			return err
+		}
+	}
+	if err := p.users.AddGroup(request.Name); err != nil {
+		return err
+	}
+	for _, member := range request.Members {
+		if err := p.users.AddGroupMember(request.Name, member); err != nil {
+			return err
+		}
+	}
+	return p.show(request.Name, resp)
+}
+
+func (p *GroupsHandler) update(name string, req *http.Request, resp http.ResponseWriter) error {
+
+	request, err := parseGroupRequest(req)
+	if err != nil {
+		return err
+	}
+	if name == "" || (request.Add == nil && request.Remove == nil) {
+		return users.ErrorInvalidGroup
+	}
+	if request.Name != "" && request.Name != name {
+		return users.ErrorInvalidGroup
+	}
+
+	for _, member := range request.Add {
+		if err := p.users.AddGroupMember(name, member); err != nil {
+			return err
+		}
+	}
+	for _, member := range request.Remove {
+		if err := p.users.RemoveGroupMember(name, member); err != nil {
+			return err
+		}
+	}
+	return p.show(name, resp)
+}
+
+func (p *GroupsHandler) groupResponse(group *users.Group) GroupResponse {
+	members := group.Members
+	if members == nil {
+		members = []string{}
+	}
+	return GroupResponse{Name: group.Name, Owner: users.GroupOwner(group.Name), Members: members}
+}
+
+func parseGroupRequest(req *http.Request) (*GroupRequest, error) {
+	var request GroupRequest
+	if err := json.Unmarshal(GetBodyBytes(req), &request); err != nil {
+		return nil, common.ErrorInvalidMessage
+	}
+	return &request, nil
+}
@@ -0,0 +1,42 @@
+package endpoints
+
+import (
+	"demo-store/common"
+	"demo-store/store"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GetKey returns the key following the route's path, or "" if there is none.
+func GetKey(req *http.Request, path string) (string, error) {
+
+	key, err := DecodeKeyPath(req, path)
+	if err != nil || key == "" {
+		return key, err
+	}
+
+	if err := store.ValidateKey(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// DecodeKeyPath returns what follows the route's path without validating it
+// as a key, since watches also take prefixes such as "team/". It is decoded
+// from the escaped path rather than URL.Path so keys can hold characters such
+// as "?" or "%" percent-encoded, %2F decoding to a slash like an unencoded
+// one.
+func DecodeKeyPath(req *http.Request, path string) (string, error) {
+
+	escaped := req.URL.EscapedPath()
+	if !strings.HasPrefix(escaped, path) {
+		return "", nil
+	}
+
+	key, err := url.PathUnescape(strings.TrimPrefix(escaped, path))
+	if err != nil {
+		return "", common.ErrorInvalidKey
+	}
+	return key, nil
+}
@@ -0,0 +1,67 @@
+package endpoints_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetKeyDecodesPath(t *testing.T) {
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"/store/", ""},
+		{"/store/report", "report"},
+		{"/store/team/service/config", "team/service/config"},
+		{"/store/team%2Fservice", "team/service"},
+		{"/store/what%3F%25%23", "what?%#"},
+		{"/store/with%20space", "with space"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.url, nil)
+		key, err := endpoints.GetKey(req, "/store/")
+		if err != nil || key != test.expected {
+			t.Errorf("Returned unexpected key for %v: got %q, %v want %q", test.url, key, err, test.expected)
+		}
+	}
+}
+
+func TestGetKeyRejectsInvalidKeys(t *testing.T) {
+
+	for _, url := range []string{"/store/team%2F", "/store/team%2F%2Fservice", "/store/%2E%2E", "/store/line%0Abreak", "/store/" + strings.Repeat("k", 1025)} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if _, err := endpoints.GetKey(req, "/store/"); err != common.ErrorInvalidKey {
+			t.Errorf("Returned unexpected error for %v: got %v want %v", url, err, common.ErrorInvalidKey)
+		}
+	}
+}
+
+func TestPutAndGetHierarchicalKey(t *testing.T) {
+
+	mockStore := NewMockStore()
+	route := CreateMockRouteWithPut("/store/", &MockTracer{}, mockStore, NewMockAuthenticator(input1.Owner))
+	req := httptest.NewRequest(http.MethodPut, "/store/team/what%3F", strings.NewReader(input1.Value))
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned unexpected code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if value, err := mockStore.MakeGetRequest(context.Background(), "team/what?", input1.Owner); err != nil || value != input1.Value {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, input1.Value)
+	}
+
+	route = CreateMockRouteWithGet("/store/", &MockTracer{}, mockStore, NewMockAuthenticator(input1.Owner))
+	rr = httptest.NewRecorder()
+	route.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/store/team%2Fwhat%3F", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != input1.Value {
+		t.Errorf("handler returned unexpected response: got %v %v want %v %v", rr.Code, rr.Body.String(), http.StatusOK, input1.Value)
+	}
+}
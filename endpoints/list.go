@@ -1,11 +1,40 @@
 package endpoints
 
 import (
+	"context"
 	"demo-store/common"
 	"demo-store/store"
 	"demo-store/utils"
 	"net/http"
-	"strings"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	PrefixParameter    = "prefix"
+	DelimiterParameter = "delimiter"
+	StartParameter     = "start"
+	EndParameter       = "end"
+	LimitParameter     = "limit"
+	CursorParameter    = "cursor"
+
+	OwnerParameter     = "owner"
+	MinAgeParameter    = "min_age"
+	MaxAgeParameter    = "max_age"
+	MinReadsParameter  = "min_reads"
+	MaxReadsParameter  = "max_reads"
+	MinWritesParameter = "min_writes"
+	MaxWritesParameter = "max_writes"
+	SortParameter      = "sort"
+	OrderParameter     = "order"
+
+	OwnerMe         = "me"
+	OrderAscending  = "asc"
+	OrderDescending = "desc"
+
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
 )
 
 type ListHandler struct {
@@ -30,17 +59,167 @@ func (p *ListHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Req
 	}
 
 	path := args.Get(PathParameter)
-	key := strings.Trim(req.URL.Path, path)
+	key, err := GetKey(req, path)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
 	if key != "" {
-		return p.handleFindRequest(key, resp)
+		return p.handleFindRequest(req.Context(), key, username, resp)
+	}
+
+	if IsScanQuery(req.URL.Query()) {
+		return p.handleScanRequest(req.Context(), req.URL.Query(), username, resp)
+	}
+
+	return p.handleFindAllRequest(req.Context(), username, resp)
+}
+
+// IsScanQuery reports whether the list request asked for a page of entries
+// rather than every entry in least recently used order.
+func IsScanQuery(values url.Values) bool {
+	for parameter := range values {
+		if isScanParameter(parameter) {
+			return true
+		}
 	}
+	return false
+}
 
-	return p.handleFindAllRequest(resp)
+func isScanParameter(parameter string) bool {
+	switch parameter {
+	case PrefixParameter, DelimiterParameter, StartParameter, EndParameter, LimitParameter, CursorParameter,
+		OwnerParameter, MinAgeParameter, MaxAgeParameter, MinReadsParameter, MaxReadsParameter,
+		MinWritesParameter, MaxWritesParameter, SortParameter, OrderParameter:
+		return true
+	}
+	return false
 }
 
-func (p *ListHandler) handleFindAllRequest(resp http.ResponseWriter) HttpResult {
-	entries := p.store.MakeListAllRequest()
-	err := writeResponse(entries, resp)
+// ParseScanQuery reads the list query parameters, resolving owner=me to the
+// user making the request.
+func ParseScanQuery(values url.Values, username string) (store.ScanQuery, error) {
+
+	query := store.ScanQuery{
+		Prefix:    values.Get(PrefixParameter),
+		Delimiter: values.Get(DelimiterParameter),
+		Start:     values.Get(StartParameter),
+		End:       values.Get(EndParameter),
+		Cursor:    values.Get(CursorParameter),
+		Limit:     DefaultListLimit,
+		Sort:      values.Get(SortParameter),
+		Reader:    username,
+	}
+
+	if value := values.Get(LimitParameter); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return query, common.ErrorInvalidQuery
+		}
+		query.Limit = limit
+		if limit > MaxListLimit {
+			query.Limit = MaxListLimit
+		}
+	}
+
+	if query.Sort != "" && !store.IsSortField(query.Sort) {
+		return query, common.ErrorInvalidQuery
+	}
+	switch values.Get(OrderParameter) {
+	case "", OrderAscending:
+	case OrderDescending:
+		query.Descending = true
+	default:
+		return query, common.ErrorInvalidQuery
+	}
+
+	query.Filter.Owner = values.Get(OwnerParameter)
+	if query.Filter.Owner == OwnerMe {
+		query.Filter.Owner = username
+	}
+
+	var err error
+	if query.Filter.Age, err = parseRange(values, MinAgeParameter, MaxAgeParameter, ParseAge); err != nil {
+		return query, err
+	}
+	if query.Filter.Reads, err = parseRange(values, MinReadsParameter, MaxReadsParameter, parseCount); err != nil {
+		return query, err
+	}
+	if query.Filter.Writes, err = parseRange(values, MinWritesParameter, MaxWritesParameter, parseCount); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
+
+// ParseAge accepts milliseconds, matching the age reported for each entry, or
+// a Go duration such as "1h".
+func ParseAge(value string) (int64, error) {
+	if milliseconds, err := strconv.ParseInt(value, 10, 64); err == nil && milliseconds >= 0 {
+		return milliseconds, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, common.ErrorInvalidQuery
+	}
+	return duration.Milliseconds(), nil
+}
+
+func parseCount(value string) (int64, error) {
+	count, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || count < 0 {
+		return 0, common.ErrorInvalidQuery
+	}
+	return count, nil
+}
+
+func parseRange(values url.Values, minParameter string, maxParameter string, parse func(string) (int64, error)) (store.Range, error) {
+
+	var bounds store.Range
+	if value := values.Get(minParameter); value != "" {
+		lower, err := parse(value)
+		if err != nil {
+			return bounds, err
+		}
+		bounds.Min = &lower
+	}
+	if value := values.Get(maxParameter); value != "" {
+		upper, err := parse(value)
+		if err != nil {
+			return bounds, err
+		}
+		bounds.Max = &upper
+	}
+
+	return bounds, nil
+}
+
+func (p *ListHandler) handleScanRequest(ctx context.Context, values url.Values, username string, resp http.ResponseWriter) HttpResult {
+	query, err := ParseScanQuery(values, username)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	result, err := p.store.MakeScanRequest(ctx, query)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	err = writeResponse(result, resp)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	return CreateHttpResponse("Ok", http.StatusOK)
+}
+
+func (p *ListHandler) handleFindAllRequest(ctx context.Context, username string, resp http.ResponseWriter) HttpResult {
+	entries, err := p.store.MakeListAllRequest(ctx, username)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	err = writeResponse(entries, resp)
 	if err != nil {
 		return CreateHttpResponseFromError(err)
 	}
@@ -48,12 +227,13 @@ func (p *ListHandler) handleFindAllRequest(resp http.ResponseWriter) HttpResult
 	return CreateHttpResponse("Ok", http.StatusOK)
 }
 
-func (p *ListHandler) handleFindRequest(key string, resp http.ResponseWriter) HttpResult {
-	entry, err := p.store.MakeListRequest(key)
+func (p *ListHandler) handleFindRequest(ctx context.Context, key string, username string, resp http.ResponseWriter) HttpResult {
+	entry, err := p.store.MakeListRequest(ctx, key, username)
 	if err != nil {
 		return CreateHttpResponseFromError(err)
 	}
 
+	resp.Header().Set(ETagHeader, FormatETag(entry.Version))
 	err = writeResponse(entry, resp)
 	if err != nil {
 		return CreateHttpResponseFromError(err)
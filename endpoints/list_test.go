@@ -1,9 +1,11 @@
 package endpoints_test
 
 import (
+	"context"
 	"demo-store/common"
 	"demo-store/endpoints"
 	"demo-store/store"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -33,7 +35,7 @@ func createMockListRequestWithUsername(store store.Store, url string, username s
 func TestListAllReturnsSuccess(t *testing.T) {
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 	rr := createMockListRequestWithUsername(mockStore, "", input1.Owner)
 
 	expectedStatus := http.StatusOK
@@ -41,7 +43,8 @@ func TestListAllReturnsSuccess(t *testing.T) {
 		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expectedStatus)
 	}
 
-	expectedBody, _ := common.ToJson(mockStore.MakeListAllRequest())
+	entries, _ := mockStore.MakeListAllRequest(context.Background(), input1.Owner)
+	expectedBody, _ := common.ToJson(entries)
 	if rr.Body.String() != expectedBody {
 		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expectedBody)
 	}
@@ -50,7 +53,7 @@ func TestListAllReturnsSuccess(t *testing.T) {
 func TestListReturnsFailed(t *testing.T) {
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 	rr := createMockListRequestWithUsername(mockStore, input2.Value, input1.Owner)
 
 	AssertErrorHttpCode(common.ErrorKeyNotFound, rr.Code, t)
@@ -59,7 +62,7 @@ func TestListReturnsFailed(t *testing.T) {
 func TestListAllReturnsFailed(t *testing.T) {
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 	rr := createMockListRequestWithUsername(mockStore, "", "")
 
 	AssertErrorHttpCode(common.ErrorUnauthorisedOwner, rr.Code, t)
@@ -68,7 +71,7 @@ func TestListAllReturnsFailed(t *testing.T) {
 func TestListReturnsSuccess(t *testing.T) {
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 	rr := createMockListRequestWithUsername(mockStore, input1.Key, input1.Owner)
 
 	expectedStatus := http.StatusOK
@@ -76,7 +79,7 @@ func TestListReturnsSuccess(t *testing.T) {
 		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expectedStatus)
 	}
 
-	expectedEntry, _ := mockStore.MakeListRequest(input1.Key)
+	expectedEntry, _ := mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
 	expectedBody, _ := common.ToJson(expectedEntry)
 	if rr.Body.String() != expectedBody {
 		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expectedBody)
@@ -88,12 +91,26 @@ func TestListReturnsSuccess(t *testing.T) {
 	}
 }
 
+func TestListShowsContentType(t *testing.T) {
+
+	mockStore := NewMockStore()
+	options := store.PutOptions{ContentType: "application/json", ContentEncoding: "gzip"}
+	mockStore.MakePutRequestWithOptions(context.Background(), input1.Key, []byte(input1.Value), input1.Owner, options)
+	rr := createMockListRequestWithUsername(mockStore, input1.Key, input1.Owner)
+
+	var entry store.Entry
+	json.Unmarshal(rr.Body.Bytes(), &entry)
+	if entry.ContentType != options.ContentType || entry.ContentEncoding != options.ContentEncoding {
+		t.Errorf("handler returned unexpected body: got %v", rr.Body.String())
+	}
+}
+
 func TestListReturnsStatusNotFoundIfKeyNotFound(t *testing.T) {
 
 	err := common.ErrorKeyNotFound
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 
 	rr := createMockListRequestWithAuthenticator(mockStore, input2.Key, NewMockAuthenticatorWithError(err))
 
@@ -105,8 +122,133 @@ func TestListReturnsErrorIfAuthenticationMissing(t *testing.T) {
 	err := common.ErrorAuthorizationHeaderMissing
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 	rr := createMockListRequestWithAuthenticator(mockStore, input1.Key, NewMockAuthenticatorWithValue(""))
 
 	AssertErrorHttpCode(err, rr.Code, t)
 }
+
+func TestListScanReturnsPage(t *testing.T) {
+
+	mockStore := NewMockStore()
+	for _, key := range []string{"a/1", "a/2", "a/3", "b/1"} {
+		mockStore.MakePutRequest(context.Background(), key, input1.Value, input1.Owner)
+	}
+	rr := createMockListRequestWithUsername(mockStore, "?prefix=a/&limit=2", input1.Owner)
+
+	expectedStatus := http.StatusOK
+	if rr.Code != expectedStatus {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expectedStatus)
+	}
+
+	var result store.ScanResult
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if len(result.Entries) != 2 || result.Entries[0].Key != "a/1" || result.Entries[1].Key != "a/2" {
+		t.Errorf("handler returned unexpected body: got %v", rr.Body.String())
+	}
+	if result.NextCursor == "" {
+		t.Errorf("handler returned unexpected cursor: got %v want a cursor", result.NextCursor)
+	}
+
+	rr = createMockListRequestWithUsername(mockStore, "?prefix=a/&limit=2&cursor="+result.NextCursor, input1.Owner)
+	result = store.ScanResult{}
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if len(result.Entries) != 1 || result.Entries[0].Key != "a/3" || result.NextCursor != "" {
+		t.Errorf("handler returned unexpected body: got %v", rr.Body.String())
+	}
+}
+
+func TestListScanWithDelimiterReturnsCommonPrefixes(t *testing.T) {
+
+	mockStore := NewMockStore()
+	for _, key := range []string{"team/a", "team/db/host", "team/db/port", "team/web/host", "other"} {
+		mockStore.MakePutRequest(context.Background(), key, input1.Value, input1.Owner)
+	}
+	rr := createMockListRequestWithUsername(mockStore, "?prefix=team/&delimiter=/", input1.Owner)
+
+	expectedStatus := http.StatusOK
+	if rr.Code != expectedStatus {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expectedStatus)
+	}
+
+	var result store.ScanResult
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if len(result.Entries) != 1 || result.Entries[0].Key != "team/a" {
+		t.Errorf("handler returned unexpected body: got %v", rr.Body.String())
+	}
+	if len(result.CommonPrefixes) != 2 || result.CommonPrefixes[0] != "team/db/" || result.CommonPrefixes[1] != "team/web/" {
+		t.Errorf("handler returned unexpected common prefixes: got %v", result.CommonPrefixes)
+	}
+}
+
+func TestListScanReturnsErrorIfQueryInvalid(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+
+	for _, url := range []string{"?limit=0", "?limit=ten", "?cursor=%21%21"} {
+		rr := createMockListRequestWithUsername(mockStore, url, input1.Owner)
+		AssertErrorHttpCode(common.ErrorInvalidQuery, rr.Code, t)
+	}
+}
+
+func TestParseScanQueryCapsLimit(t *testing.T) {
+
+	query, _ := endpoints.ParseScanQuery(map[string][]string{"limit": {"5000"}}, input1.Owner)
+	if query.Limit != endpoints.MaxListLimit {
+		t.Errorf("Returned unexpected limit: got %v want %v", query.Limit, endpoints.MaxListLimit)
+	}
+
+	query, _ = endpoints.ParseScanQuery(map[string][]string{"prefix": {"a"}}, input1.Owner)
+	if query.Limit != endpoints.DefaultListLimit {
+		t.Errorf("Returned unexpected limit: got %v want %v", query.Limit, endpoints.DefaultListLimit)
+	}
+}
+
+func TestListScanFiltersByOwnerMe(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input2.Key, input2.Value, input2.Owner)
+	rr := createMockListRequestWithUsername(mockStore, "?owner=me", input2.Owner)
+
+	var result store.ScanResult
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if len(result.Entries) != 1 || result.Entries[0].Key != input2.Key {
+		t.Errorf("handler returned unexpected body: got %v", rr.Body.String())
+	}
+}
+
+func TestListScanSortsDescending(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input2.Key, input2.Value, input1.Owner)
+	mockStore.MakeGetRequest(context.Background(), input2.Key, input1.Owner)
+	rr := createMockListRequestWithUsername(mockStore, "?sort=reads&order=desc&min_age=0", input1.Owner)
+
+	var result store.ScanResult
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if len(result.Entries) != 2 || result.Entries[0].Key != input2.Key {
+		t.Errorf("handler returned unexpected body: got %v", rr.Body.String())
+	}
+}
+
+func TestParseScanQueryRejectsInvalidFilters(t *testing.T) {
+
+	for _, url := range []string{"?sort=value", "?order=up", "?min_reads=-1", "?max_age=soon"} {
+		rr := createMockListRequestWithUsername(NewMockStore(), url, input1.Owner)
+		AssertErrorHttpCode(common.ErrorInvalidQuery, rr.Code, t)
+	}
+}
+
+func TestParseAgeAcceptsDurations(t *testing.T) {
+
+	tests := map[string]int64{"1500": 1500, "1h": 3600000, "90s": 90000}
+	for value, expected := range tests {
+		age, err := endpoints.ParseAge(value)
+		if err != nil || age != expected {
+			t.Errorf("Returned unexpected age for %v: got %v want %v", value, age, expected)
+		}
+	}
+}
@@ -37,7 +37,12 @@ func (p *LoginHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Re
 		return CreateHttpResponseFromError(common.ErrorAuthorizationFailed)
 	}
 
-	tokenString, err := p.Tokenizer.CreateToken(username)
+	user, err := p.Users.FindUser(username)
+	if err != nil {
+		return CreateHttpResponseFromError(common.ErrorAuthorizationFailed)
+	}
+
+	tokenString, err := p.Tokenizer.CreateToken(username, users.RoleNames(user.Roles))
 	if err != nil {
 		return CreateHttpResponseFromError(common.ErrorAuthorizationFailed)
 	}
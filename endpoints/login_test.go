@@ -128,7 +128,7 @@ func (t *MockTokenizer) GetUsernameFromToken(tokenString string) (string, error)
 	return t.MockValue, t.MockError
 }
 
-func (t *MockTokenizer) CreateToken(username string) (string, error) {
+func (t *MockTokenizer) CreateToken(username string, roles []string) (string, error) {
 	return t.MockValue, t.MockError
 }
 
@@ -0,0 +1,52 @@
+package endpoints
+
+import (
+	"demo-store/common"
+	"demo-store/users"
+	"demo-store/utils"
+	"encoding/json"
+	"net/http"
+)
+
+// PasswordRequest is the JSON body of PUT /me/password. The current password
+// is asked for again so a stolen token isn't enough to take over the account.
+type PasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password"`
+}
+
+type PasswordHandler struct {
+	Tracer     utils.Tracer
+	httpMethod string
+	users      users.UserDatabase
+}
+
+func (p *PasswordHandler) HttpMethod() string {
+	return p.httpMethod
+}
+
+func (p *PasswordHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWriter, req *http.Request) HttpResult {
+	return p.handleRequest(args, req, resp)
+}
+
+func (p *PasswordHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
+
+	username := args.Get(UsernameParameter)
+	if username == "" {
+		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
+	}
+
+	var request PasswordRequest
+	if err := json.Unmarshal(GetBodyBytes(req), &request); err != nil {
+		return CreateHttpResponseFromError(common.ErrorInvalidMessage)
+	}
+
+	if err := p.users.Authenticate(username, request.CurrentPassword); err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+	if err := p.users.SetPassword(username, request.Password); err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	return CreateHttpResponse("Ok", http.StatusOK)
+}
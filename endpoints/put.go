@@ -5,7 +5,19 @@ import (
 	"demo-store/store"
 	"demo-store/utils"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
+)
+
+const TtlHeader = "X-TTL"
+const TtlParameter = "ttl"
+
+const VisibilityHeader = "X-Visibility"
+const VisibilityParameter = "visibility"
+
+const (
+	ContentTypeHeader     = "Content-Type"
+	ContentEncodingHeader = "Content-Encoding"
 )
 
 type PutHandler struct {
@@ -25,8 +37,10 @@ func (p *PutHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWri
 func (p *PutHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
 
 	path := args.Get(PathParameter)
-	key := strings.Trim(req.URL.Path, path)
-
+	key, err := GetKey(req, path)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
 	if key == "" {
 		return CreateHttpResponseFromError(common.ErrorKeyNotSet)
 	}
@@ -35,15 +49,71 @@ func (p *PutHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Requ
 	if username == "" {
 		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
 	}
-	body := GetBody(req)
-	if body == "" {
-		return CreateHttpResponseFromError(common.ErrorStoreValueNotSet)
+	body := GetBodyBytes(req)
+
+	ttl, err := GetTtl(req)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+	visibility, err := GetVisibility(req)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
 	}
 
-	err := p.store.MakePutRequest(key, body, username)
+	// the headers are stored as sent and replayed on GET
+	options := store.PutOptions{
+		TTL:             ttl,
+		ContentType:     req.Header.Get(ContentTypeHeader),
+		ContentEncoding: req.Header.Get(ContentEncodingHeader),
+		Visibility:      visibility,
+		Precondition:    GetPrecondition(req),
+	}
+	entry, err := p.store.MakeWriteRequest(req.Context(), key, body, username, options)
 	if err != nil {
 		return CreateHttpResponseFromError(err)
 	}
 
+	// the new version lets the client make its next write conditional
+	resp.Header().Set(ETagHeader, FormatETag(entry.Version))
+
 	return CreateHttpResponse("Ok", http.StatusOK)
 }
+
+// GetTtl reads the time to live from the X-TTL header or ttl query parameter,
+// either as whole seconds or as a duration such as "1m30s".
+func GetTtl(req *http.Request) (time.Duration, error) {
+	value := req.Header.Get(TtlHeader)
+	if value == "" {
+		value = req.URL.Query().Get(TtlParameter)
+	}
+	return ParseTtl(value)
+}
+
+// GetVisibility reads public or private from the X-Visibility header or
+// visibility query parameter, returning "" if neither is set.
+func GetVisibility(req *http.Request) (store.Visibility, error) {
+	value := req.Header.Get(VisibilityHeader)
+	if value == "" {
+		value = req.URL.Query().Get(VisibilityParameter)
+	}
+	return store.ParseVisibility(value)
+}
+
+// ParseTtl parses whole seconds or a duration, returning zero for no expiry.
+func ParseTtl(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	var ttl time.Duration
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		ttl = time.Duration(seconds) * time.Second
+	} else if ttl, err = time.ParseDuration(value); err != nil {
+		return 0, common.ErrorInvalidTtl
+	}
+
+	if ttl <= 0 {
+		return 0, common.ErrorInvalidTtl
+	}
+	return ttl, nil
+}
@@ -1,13 +1,16 @@
 package endpoints_test
 
 import (
+	"context"
 	"demo-store/common"
 	"demo-store/endpoints"
 	"demo-store/store"
+	"demo-store/users"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func createMockPutRequestWithAuthenticator(store store.Store, url string, authenticator endpoints.Authenticator, body string) *httptest.ResponseRecorder {
@@ -42,6 +45,40 @@ func TestPutReturnsSuccess(t *testing.T) {
 	}
 }
 
+func TestPutReturnsETag(t *testing.T) {
+
+	mockStore := NewMockStore()
+	rr := createMockPutRequestWithUsername(mockStore, input1.Key, input1.Owner, input1.Value)
+	entry, _ := mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
+
+	expected := endpoints.FormatETag(entry.Version)
+	if rr.Header().Get(endpoints.ETagHeader) != expected {
+		t.Errorf("handler returned unexpected etag: got %v want %v", rr.Header().Get(endpoints.ETagHeader), expected)
+	}
+
+	// the etag of a put is enough for the next conditional put
+	rr = createMockConditionalRequest(mockStore, http.MethodPut, endpoints.IfMatchHeader, rr.Header().Get(endpoints.ETagHeader))
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	entry, _ = mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
+	expected = endpoints.FormatETag(entry.Version)
+	if rr.Header().Get(endpoints.ETagHeader) != expected {
+		t.Errorf("handler returned unexpected etag: got %v want %v", rr.Header().Get(endpoints.ETagHeader), expected)
+	}
+}
+
+func TestPutReturnsNoETagIfRefused(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+
+	rr := createMockPutRequestWithUsername(mockStore, input1.Key, input2.Owner, input2.Value)
+	if etag := rr.Header().Get(endpoints.ETagHeader); etag != "" {
+		t.Errorf("handler returned unexpected etag: got %v want %v", etag, "")
+	}
+}
+
 func TestPutStatusUnprocessableEntityIfNotKeyPassed(t *testing.T) {
 
 	mockStore := NewMockStore()
@@ -55,7 +92,7 @@ func TestPutStatusUnprocessableEntityIfNoAuthenticationIsPassed(t *testing.T) {
 	err := common.ErrorAuthorizationHeaderMissing
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 
 	rr := createMockPutRequestWithAuthenticator(mockStore, input1.Key, NewMockAuthenticatorWithValue(""), "")
 
@@ -67,18 +104,94 @@ func TestPutStatusUnprocessableEntityIfNoBodyIsPassed(t *testing.T) {
 	err := common.ErrorStoreValueNotSet
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 
 	rr := createMockPutRequestWithUsername(mockStore, input1.Key, input1.Owner, "")
 
 	AssertErrorHttpCode(err, rr.Code, t)
 }
 
+func TestPutAcceptsEmptyBodyIfStoreAllowsEmptyValues(t *testing.T) {
+
+	config := store.Config{AllowEmptyValues: true}
+	mockStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), config)
+	rr := createMockPutRequestWithUsername(mockStore, input1.Key, input1.Owner, "")
+
+	expected := http.StatusOK
+	if rr.Code != expected {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
+	}
+	if value, err := mockStore.MakeGetRequest(context.Background(), input1.Key, input1.Owner); err != nil || value != "" {
+		t.Errorf("Returned unexpected value: got %q, %v want %q", value, err, "")
+	}
+}
+
 func TestPutReturnsFobiddenIfNotOwnerAttemptsToUpdateKey(t *testing.T) {
 
 	mockStore := NewMockStore()
-	mockStore.MakePutRequest(input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
 	rr := createMockPutRequestWithUsername(mockStore, input1.Key, input2.Owner, input1.Value)
 
 	AssertErrorHttpCode(common.ErrorUnauthorisedOwner, rr.Code, t)
 }
+
+func TestPutWithTtlQueryParameterExpiresKey(t *testing.T) {
+
+	mockStore := NewMockStore()
+	rr := createMockPutRequestWithUsername(mockStore, input1.Key+"?ttl=1", input1.Owner, input1.Value)
+
+	expected := http.StatusOK
+	if rr.Code != expected {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
+	}
+
+	entry, _ := mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
+	if entry.TTL <= 0 || entry.TTL > 1000 {
+		t.Errorf("handler returned unexpected ttl: got %v want %v", entry.TTL, 1000)
+	}
+}
+
+func TestPutWithTtlHeader(t *testing.T) {
+
+	mockStore := NewMockStore()
+	req, _ := http.NewRequest(http.MethodPut, "", nil)
+	req.Header.Set(endpoints.TtlHeader, "1m30s")
+
+	ttl, err := endpoints.GetTtl(req)
+	if err != nil || ttl != 90*time.Second {
+		t.Errorf("handler returned unexpected ttl: got %v want %v", ttl, 90*time.Second)
+	}
+
+	rr := createMockPutRequestWithUsername(mockStore, input1.Key+"?ttl=soon", input1.Owner, input1.Value)
+	AssertErrorHttpCode(common.ErrorInvalidTtl, rr.Code, t)
+
+	rr = createMockPutRequestWithUsername(mockStore, input1.Key+"?ttl=-5", input1.Owner, input1.Value)
+	AssertErrorHttpCode(common.ErrorInvalidTtl, rr.Code, t)
+}
+
+func TestPutWithVisibilityMakesKeyPrivate(t *testing.T) {
+
+	mockStore := NewMockStore()
+	req, _ := http.NewRequest(http.MethodPut, "", nil)
+	req.Header.Set(endpoints.VisibilityHeader, "private")
+
+	visibility, err := endpoints.GetVisibility(req)
+	if err != nil || visibility != store.VisibilityPrivate {
+		t.Errorf("handler returned unexpected visibility: got %v want %v", visibility, store.VisibilityPrivate)
+	}
+
+	rr := createMockPutRequestWithUsername(mockStore, input1.Key+"?visibility=secret", input1.Owner, input1.Value)
+	AssertErrorHttpCode(common.ErrorInvalidVisibility, rr.Code, t)
+
+	rr = createMockPutRequestWithUsername(mockStore, input1.Key+"?visibility=private", input1.Owner, input1.Value)
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	rr = createMockListRequestWithUsername(mockStore, "", input2.Owner)
+	if rr.Body.String() != "[]" {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), "[]")
+	}
+	rr = createMockListRequestWithUsername(mockStore, input1.Key, input2.Owner)
+	AssertErrorHttpCode(common.ErrorKeyNotFound, rr.Code, t)
+}
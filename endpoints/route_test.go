@@ -152,6 +152,28 @@ func TestCreateShutdownRouteMethods(t *testing.T) {
 	}
 }
 
+func TestCreateSnapshotRouteMethods(t *testing.T) {
+	mockStore := NewMockStore()
+	auth := NewMockAuthenticator("")
+	route := endpoints.CreateSnapshotRoute(mockStore.Tracer, mockStore, auth)
+
+	secureRoute := route.(*endpoints.SecureRoute)
+	expectedPath := "/admin/snapshot"
+	if secureRoute.RootPath() != expectedPath {
+		t.Errorf("handler returned unexpected path: got %v want %v", secureRoute.RootPath(), expectedPath)
+	}
+
+	expected := []string{
+		"POST",
+	}
+	for i, handlder := range secureRoute.MethodHandlers {
+
+		if handlder.HttpMethod() != expected[i] {
+			t.Errorf("handler returned unexpected method: got %v want %v", handlder.HttpMethod(), expected[i])
+		}
+	}
+}
+
 func TestCreateLoginRouteMethods(t *testing.T) {
 	mockStore := NewMockStore()
 	route := endpoints.CreateLoginRoute(mockStore.Tracer, mockStore.UserDatabase())
@@ -180,6 +202,7 @@ func TestCreateInsecureApiRoutes(t *testing.T) {
 	expectedPaths := []string{
 		"/ping/",
 		"/login/",
+		"/ws",
 	}
 	for i, route := range routes.Insecure {
 		if route.RootPath() != expectedPaths[i] {
@@ -195,7 +218,15 @@ func TestCreateSecureApiRoutes(t *testing.T) {
 	expectedPaths := []string{
 		"/store/",
 		"/list/",
+		"/acl/",
+		"/watch/",
+		"/batch",
 		"/shutdown/",
+		"/admin/snapshot",
+		"/admin/stats",
+		"/admin/users/",
+		"/admin/groups/",
+		"/me/password",
 	}
 	for i, route := range routes.Secure {
 		if route.RootPath() != expectedPaths[i] {
@@ -3,6 +3,7 @@ package endpoints
 import (
 	"demo-store/common"
 	"demo-store/store"
+	"demo-store/users"
 	"demo-store/utils"
 	"net/http"
 )
@@ -27,10 +28,12 @@ func (p *ShutdownHandler) handleRequest(args *HttpMethodHandlerParams, req *http
 		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
 	}
 
-	if !p.store.UserDatabase().IsAdmin(username) {
-		return CreateHttpResponseFromError(common.ErrorUnauthorisedOwner)
+	if !p.store.UserDatabase().HasPermission(username, users.PermissionOperate) {
+		return CreateHttpResponseFromError(common.ErrorPermissionDenied)
 	}
 
-	p.store.MakeShutdownRequest()
+	if err := p.store.MakeShutdownRequest(req.Context()); err != nil {
+		return CreateHttpResponseFromError(err)
+	}
 	return CreateHttpResponse("Ok", http.StatusOK)
 }
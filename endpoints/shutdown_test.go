@@ -60,3 +60,16 @@ func TestShutdownReturnsSuccessIfOwnerIsAdmin(t *testing.T) {
 		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
 	}
 }
+
+func TestShutdownReturnsServiceUnavailableOnceShutDown(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.UserDatabase().AddUser("admin", "123")
+	createMockShutdownRequestWithUsername(mockStore, "admin")
+	rr := createMockShutdownRequestWithUsername(mockStore, "admin")
+
+	expected := http.StatusServiceUnavailable
+	if rr.Code != expected {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
+	}
+}
@@ -0,0 +1,41 @@
+package endpoints
+
+import (
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"net/http"
+)
+
+type SnapshotHandler struct {
+	Tracer     utils.Tracer
+	httpMethod string
+	store      store.Store
+}
+
+func (p *SnapshotHandler) HttpMethod() string {
+	return p.httpMethod
+}
+
+func (p *SnapshotHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWriter, req *http.Request) HttpResult {
+	return p.handleRequest(args, req, resp)
+}
+
+func (p *SnapshotHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
+	username := args.Get(UsernameParameter)
+	if username == "" {
+		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
+	}
+
+	if !p.store.UserDatabase().HasPermission(username, users.PermissionOperate) {
+		return CreateHttpResponseFromError(common.ErrorPermissionDenied)
+	}
+
+	err := p.store.MakeSnapshotRequest(req.Context())
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	return CreateHttpResponse("Ok", http.StatusOK)
+}
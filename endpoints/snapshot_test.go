@@ -0,0 +1,81 @@
+package endpoints_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func CreateMockRouteWithSnapshot(path string, tracer utils.Tracer, kvStore store.Store, authenticator endpoints.Authenticator) endpoints.Route {
+	var methods []endpoints.HttpMethodHandler
+	methods = append(methods, endpoints.CreateSnapshot(tracer, kvStore))
+
+	return &endpoints.SecureRoute{Path: path, Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+func createMockSnapshotRequestWithUsername(store store.Store, username string) *httptest.ResponseRecorder {
+
+	path := "/admin/snapshot"
+
+	route := CreateMockRouteWithSnapshot(path, &MockTracer{}, store, NewMockAuthenticatorWithValue(username))
+	req, err := http.NewRequest(http.MethodPost, path, nil)
+
+	if err != nil {
+		return nil
+	}
+
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func NewMockPersistentStore(t *testing.T) *store.KvStore {
+	writeLog, _ := store.OpenWriteLog(CreateMockTracer(), t.TempDir(), store.FsyncNever, 0)
+	kvStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), store.Config{WriteLog: writeLog})
+	return kvStore
+}
+
+func TestSnapshotReturnsForbiddenForNonAdminUser(t *testing.T) {
+
+	mockStore := NewMockPersistentStore(t)
+	rr := createMockSnapshotRequestWithUsername(mockStore, input1.Owner)
+
+	AssertErrorHttpCode(common.ErrorUnauthorisedOwner, rr.Code, t)
+}
+
+func TestSnapshotReturnsErrorIfOwnerIsMissing(t *testing.T) {
+
+	mockStore := NewMockPersistentStore(t)
+	rr := createMockSnapshotRequestWithUsername(mockStore, "")
+
+	AssertErrorHttpCode(common.ErrorAuthorizationHeaderMissing, rr.Code, t)
+}
+
+func TestSnapshotReturnsNotImplementedWithoutPersistence(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.UserDatabase().AddUser("admin", "123")
+	rr := createMockSnapshotRequestWithUsername(mockStore, "admin")
+
+	AssertErrorHttpCode(common.ErrorPersistenceDisabled, rr.Code, t)
+}
+
+func TestSnapshotReturnsSuccessIfOwnerIsAdmin(t *testing.T) {
+
+	mockStore := NewMockPersistentStore(t)
+	mockStore.UserDatabase().AddUser("admin", "123")
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	rr := createMockSnapshotRequestWithUsername(mockStore, "admin")
+
+	expected := http.StatusOK
+	if rr.Code != expected {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
+	}
+}
@@ -0,0 +1,421 @@
+package endpoints
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/utils"
+	"demo-store/websocket"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	SocketOpAuth    = "auth"
+	SocketOpGet     = "get"
+	SocketOpPut     = "put"
+	SocketOpDelete  = "delete"
+	SocketOpList    = "list"
+	SocketOpWatch   = "watch"
+	SocketOpUnwatch = "unwatch"
+)
+
+// SocketAuthTimeout is how long a connection that didn't authenticate during
+// the handshake has to send its auth request.
+const SocketAuthTimeout = 10 * time.Second
+
+// SocketPingInterval is how often idle connections are pinged so dead peers
+// are noticed and proxies keep the connection open.
+const SocketPingInterval = 30 * time.Second
+
+// SocketRequest is a JSON frame sent by the client. Id is echoed in every
+// frame sent in reply, including the events of a watch.
+type SocketRequest struct {
+	Id              string            `json:"id"`
+	Op              string            `json:"op"`
+	Token           string            `json:"token,omitempty"`
+	Key             string            `json:"key,omitempty"`
+	Value           string            `json:"value,omitempty"`
+	ContentType     string            `json:"content_type,omitempty"`
+	ContentEncoding string            `json:"content_encoding,omitempty"`
+	Visibility      string            `json:"visibility,omitempty"`
+	TTL             string            `json:"ttl,omitempty"`
+	IfMatch         string            `json:"if_match,omitempty"`
+	IfNoneMatch     string            `json:"if_none_match,omitempty"`
+	Query           map[string]string `json:"query,omitempty"`
+	Prefix          bool              `json:"prefix,omitempty"`
+	After           uint64            `json:"after,omitempty"`
+	Watch           string            `json:"watch,omitempty"`
+}
+
+// SocketResponse is a JSON frame sent by the server. Status follows the HTTP
+// status the equivalent REST request would return.
+type SocketResponse struct {
+	Id       string       `json:"id"`
+	Status   int          `json:"status,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Username string       `json:"username,omitempty"`
+	Value    string       `json:"value,omitempty"`
+	Version  uint64       `json:"version,omitempty"`
+	Result   any          `json:"result,omitempty"`
+	Sequence uint64       `json:"sequence,omitempty"`
+	Event    *store.Event `json:"event,omitempty"`
+	Done     bool         `json:"done,omitempty"`
+}
+
+type SocketHandler struct {
+	Tracer        utils.Tracer
+	httpMethod    string
+	store         store.Store
+	authenticator Authenticator
+}
+
+func (p *SocketHandler) HttpMethod() string {
+	return p.httpMethod
+}
+
+func (p *SocketHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWriter, req *http.Request) HttpResult {
+	return p.handleRequest(args, req, resp)
+}
+
+// handleRequest authenticates from the Authorization header when the client
+// can send one, otherwise from the first frame, since browsers can't set
+// headers on a WebSocket handshake.
+func (p *SocketHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
+
+	username := ""
+	if header := req.Header.Get("Authorization"); header != "" {
+		var err error
+		username, err = p.authenticator.GetUsername(header)
+		if err != nil {
+			return CreateHttpResponseFromError(err)
+		}
+	}
+
+	conn, err := websocket.Upgrade(resp, req)
+	if errors.Is(err, websocket.ErrorUnsupportedVersion) {
+		return CreateHttpResponse(err.Error(), http.StatusUpgradeRequired)
+	}
+	if err != nil {
+		return CreateHttpResponse(err.Error(), http.StatusBadRequest)
+	}
+
+	session := &socketSession{handler: p, ctx: req.Context(), conn: conn, username: username, watches: make(map[string]*socketWatch)}
+	session.serve()
+
+	return CreateHttpResponse("Ok", http.StatusOK)
+}
+
+type socketSession struct {
+	handler  *SocketHandler
+	ctx      context.Context
+	conn     *websocket.Conn
+	username string
+
+	mutex   sync.Mutex
+	watches map[string]*socketWatch
+	group   sync.WaitGroup
+}
+
+// socketWatch is a watch forwarded to the client. Only its forwarding
+// goroutine reads the watcher's events, and it unwatches when stop is closed,
+// closing done once it has sent the done frame.
+type socketWatch struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (s *socketSession) serve() {
+
+	defer s.conn.Close()
+
+	if s.username == "" && !s.authenticate() {
+		return
+	}
+
+	stopPings := make(chan bool)
+	go s.ping(stopPings)
+
+	defer func() {
+		close(stopPings)
+		s.unwatchAll()
+		s.group.Wait()
+	}()
+
+	for {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var request SocketRequest
+		if messageType != websocket.TextMessage || json.Unmarshal(data, &request) != nil {
+			s.writeError(request.Id, common.ErrorInvalidMessage)
+			continue
+		}
+		s.dispatch(request)
+	}
+}
+
+func (s *socketSession) authenticate() bool {
+
+	s.conn.SetReadDeadline(time.Now().Add(SocketAuthTimeout))
+	messageType, data, err := s.conn.ReadMessage()
+	if err != nil {
+		s.conn.WriteClose(websocket.ClosePolicyViolation, "authentication required")
+		return false
+	}
+	s.conn.SetReadDeadline(time.Time{})
+
+	var request SocketRequest
+	if messageType != websocket.TextMessage || json.Unmarshal(data, &request) != nil || request.Op != SocketOpAuth {
+		s.writeError(request.Id, common.ErrorAuthorizationHeaderMissing)
+		s.conn.WriteClose(websocket.ClosePolicyViolation, "authentication required")
+		return false
+	}
+
+	username, err := s.handler.authenticator.GetUsername(request.Token)
+	if err != nil {
+		s.writeError(request.Id, err)
+		s.conn.WriteClose(websocket.ClosePolicyViolation, "authentication failed")
+		return false
+	}
+
+	s.username = username
+	s.write(SocketResponse{Id: request.Id, Status: http.StatusOK, Username: username})
+	return true
+}
+
+func (s *socketSession) ping(stop chan bool) {
+	ticker := time.NewTicker(SocketPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.conn.WriteMessage(websocket.PingMessage, nil)
+		}
+	}
+}
+
+func (s *socketSession) dispatch(request SocketRequest) {
+
+	kvStore := s.handler.store
+	response := SocketResponse{Id: request.Id, Status: http.StatusOK}
+
+	switch request.Op {
+	case SocketOpGet:
+		if request.Key == "" {
+			s.writeError(request.Id, common.ErrorKeyNotSet)
+			return
+		}
+		entry, err := kvStore.MakeReadRequest(s.ctx, request.Key, s.username)
+		if err != nil {
+			s.writeError(request.Id, err)
+			return
+		}
+		response.Value = string(entry.Value)
+		response.Version = entry.Version
+
+	case SocketOpPut:
+		options, err := s.putOptions(request)
+		if err != nil {
+			s.writeError(request.Id, err)
+			return
+		}
+		if err := kvStore.MakePutRequestWithOptions(s.ctx, request.Key, []byte(request.Value), s.username, options); err != nil {
+			s.writeError(request.Id, err)
+			return
+		}
+
+	case SocketOpDelete:
+		if request.Key == "" {
+			s.writeError(request.Id, common.ErrorKeyNotSet)
+			return
+		}
+		options := store.DeleteOptions{Precondition: socketPrecondition(request)}
+		if err := kvStore.MakeDeleteRequestWithOptions(s.ctx, request.Key, s.username, options); err != nil {
+			s.writeError(request.Id, err)
+			return
+		}
+
+	case SocketOpList:
+		result, err := s.list(request)
+		if err != nil {
+			s.writeError(request.Id, err)
+			return
+		}
+		response.Result = result
+
+	case SocketOpWatch:
+		s.watch(request)
+		return
+
+	case SocketOpUnwatch:
+		watch, ok := s.removeWatch(request.Watch)
+		if !ok {
+			s.writeError(request.Id, common.ErrorInvalidMessage)
+			return
+		}
+		close(watch.stop)
+		<-watch.done
+
+	default:
+		s.writeError(request.Id, common.ErrorInvalidMessage)
+		return
+	}
+
+	s.write(response)
+}
+
+func (s *socketSession) putOptions(request SocketRequest) (store.PutOptions, error) {
+	if request.Key == "" {
+		return store.PutOptions{}, common.ErrorKeyNotSet
+	}
+
+	ttl, err := ParseTtl(request.TTL)
+	if err != nil {
+		return store.PutOptions{}, err
+	}
+	visibility, err := store.ParseVisibility(request.Visibility)
+	if err != nil {
+		return store.PutOptions{}, err
+	}
+	return store.PutOptions{
+		TTL:             ttl,
+		Precondition:    socketPrecondition(request),
+		ContentType:     request.ContentType,
+		ContentEncoding: request.ContentEncoding,
+		Visibility:      visibility,
+	}, nil
+}
+
+func socketPrecondition(request SocketRequest) store.Precondition {
+	return store.Precondition{
+		IfMatch:     ParseVersionMatch(request.IfMatch),
+		IfNoneMatch: ParseVersionMatch(request.IfNoneMatch),
+	}
+}
+
+// list answers with the same body GET /list would for the key and query.
+func (s *socketSession) list(request SocketRequest) (any, error) {
+
+	kvStore := s.handler.store
+	if request.Key != "" {
+		return kvStore.MakeListRequest(s.ctx, request.Key, s.username)
+	}
+
+	values := url.Values{}
+	for name, value := range request.Query {
+		values.Set(name, value)
+	}
+	if !IsScanQuery(values) {
+		return kvStore.MakeListAllRequest(s.ctx, s.username)
+	}
+
+	query, err := ParseScanQuery(values, s.username)
+	if err != nil {
+		return nil, err
+	}
+	return kvStore.MakeScanRequest(s.ctx, query)
+}
+
+// watch starts forwarding events tagged with the request id until the watch
+// is unwatched or ends on the store's side, finishing with a done frame.
+func (s *socketSession) watch(request SocketRequest) {
+
+	s.mutex.Lock()
+	_, exists := s.watches[request.Id]
+	s.mutex.Unlock()
+	if request.Id == "" || exists {
+		s.writeError(request.Id, common.ErrorInvalidMessage)
+		return
+	}
+
+	query := store.WatchQuery{Key: request.Key, Prefix: request.Prefix || request.Key == "", After: request.After, Reader: s.username}
+	watcher, err := s.handler.store.MakeWatchRequest(s.ctx, query)
+	if err != nil {
+		s.writeError(request.Id, err)
+		return
+	}
+
+	watch := &socketWatch{stop: make(chan struct{}), done: make(chan struct{})}
+	s.mutex.Lock()
+	s.watches[request.Id] = watch
+	s.mutex.Unlock()
+
+	s.write(SocketResponse{Id: request.Id, Status: http.StatusOK, Sequence: watcher.Sequence})
+
+	s.group.Add(1)
+	go func() {
+		defer s.group.Done()
+		defer close(watch.done)
+
+		s.forward(request.Id, watcher, watch.stop)
+		s.mutex.Lock()
+		if s.watches[request.Id] == watch {
+			delete(s.watches, request.Id)
+		}
+		s.mutex.Unlock()
+		s.write(SocketResponse{Id: request.Id, Status: http.StatusOK, Done: true})
+	}()
+}
+
+// forward sends the watcher's events until they end on the store's side or
+// stop is closed, when it unwatches, dropping the events still queued.
+func (s *socketSession) forward(id string, watcher *store.Watcher, stop chan struct{}) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			s.write(SocketResponse{Id: id, Event: &event})
+
+		case <-stop:
+			s.handler.store.MakeUnwatchRequest(watcher)
+			return
+		}
+	}
+}
+
+// removeWatch takes the watch out of the session so only its caller stops it.
+func (s *socketSession) removeWatch(id string) (*socketWatch, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	watch, ok := s.watches[id]
+	delete(s.watches, id)
+	return watch, ok
+}
+
+func (s *socketSession) unwatchAll() {
+	s.mutex.Lock()
+	watches := s.watches
+	s.watches = make(map[string]*socketWatch)
+	s.mutex.Unlock()
+
+	for _, watch := range watches {
+		close(watch.stop)
+	}
+}
+
+func (s *socketSession) writeError(id string, err error) {
+	result := CreateHttpResponseFromError(err)
+	s.write(SocketResponse{Id: id, Status: result.Code, Error: result.Message})
+}
+
+func (s *socketSession) write(response SocketResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		s.handler.Tracer.LogError("Error encoding WebSocket response", err)
+		return
+	}
+	s.conn.WriteMessage(websocket.TextMessage, data)
+}
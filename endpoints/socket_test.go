@@ -0,0 +1,246 @@
+package endpoints_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"demo-store/store"
+	"demo-store/utils"
+	"demo-store/websocket"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func CreateMockRouteWithSocket(path string, tracer utils.Tracer, kvStore store.Store, authenticator endpoints.Authenticator) endpoints.Route {
+	var methods []endpoints.HttpMethodHandler
+	methods = append(methods, endpoints.CreateSocket(tracer, kvStore, authenticator))
+
+	return &endpoints.InsecureRoute{Path: path, Tracer: tracer, MethodHandlers: methods}
+}
+
+// dialMockSocket connects to a socket route whose bearer tokens all belong to
+// username, closing the connection when the test ends.
+func dialMockSocket(t *testing.T, kvStore store.Store, username string, header http.Header) (*websocket.Conn, error) {
+
+	authenticator := endpoints.NewRouteAuthenticatorWithTokenizer(&MockTracer{}, NewMockTokenizer(username, nil))
+	server := httptest.NewServer(CreateMockRouteWithSocket("/ws", &MockTracer{}, kvStore, authenticator))
+	t.Cleanup(server.Close)
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", header)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, err
+}
+
+func sendSocketRequest(t *testing.T, conn *websocket.Conn, request endpoints.SocketRequest) {
+	data, _ := json.Marshal(request)
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		t.Fatalf("handler returned unexpected error: got %v want %v", err, "nil")
+	}
+}
+
+func readSocketResponse(t *testing.T, conn *websocket.Conn) endpoints.SocketResponse {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("handler returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	var response endpoints.SocketResponse
+	json.Unmarshal(data, &response)
+	return response
+}
+
+func socketRoundTrip(t *testing.T, conn *websocket.Conn, request endpoints.SocketRequest) endpoints.SocketResponse {
+	sendSocketRequest(t, conn, request)
+	return readSocketResponse(t, conn)
+}
+
+func authenticatedMockSocket(t *testing.T, kvStore store.Store, username string) *websocket.Conn {
+	conn, err := dialMockSocket(t, kvStore, username, nil)
+	if err != nil {
+		t.Fatalf("handler returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	response := socketRoundTrip(t, conn, endpoints.SocketRequest{Id: "auth", Op: endpoints.SocketOpAuth, Token: "Bearer token"})
+	if response.Status != http.StatusOK || response.Username != username {
+		t.Fatalf("handler returned unexpected response: got %+v", response)
+	}
+	return conn
+}
+
+func TestSocketAuthenticatesWithFirstFrame(t *testing.T) {
+
+	mockStore := NewMockStore()
+	conn := authenticatedMockSocket(t, mockStore, input1.Owner)
+
+	response := socketRoundTrip(t, conn, endpoints.SocketRequest{Id: "1", Op: endpoints.SocketOpPut, Key: input1.Key, Value: input1.Value})
+	if response.Id != "1" || response.Status != http.StatusOK {
+		t.Errorf("handler returned unexpected response: got %+v", response)
+	}
+
+	entry, _ := mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
+	if entry.Owner != input1.Owner {
+		t.Errorf("handler returned unexpected owner: got %v want %v", entry.Owner, input1.Owner)
+	}
+}
+
+func TestSocketPutKeepsContentTypeAndVisibility(t *testing.T) {
+
+	mockStore := NewMockStore()
+	conn := authenticatedMockSocket(t, mockStore, input1.Owner)
+
+	request := endpoints.SocketRequest{Id: "1", Op: endpoints.SocketOpPut, Key: input1.Key, Value: input1.Value, ContentType: "application/json", ContentEncoding: "identity", Visibility: "private"}
+	if response := socketRoundTrip(t, conn, request); response.Status != http.StatusOK {
+		t.Fatalf("handler returned unexpected response: got %+v", response)
+	}
+
+	entry, _ := mockStore.MakeListRequest(context.Background(), input1.Key, input1.Owner)
+	if entry.ContentType != request.ContentType || entry.ContentEncoding != request.ContentEncoding || !entry.Private {
+		t.Errorf("handler returned unexpected entry: got %+v", entry)
+	}
+
+	request = endpoints.SocketRequest{Id: "2", Op: endpoints.SocketOpPut, Key: input1.Key, Value: input1.Value, Visibility: "hidden"}
+	AssertErrorHttpCode(common.ErrorInvalidVisibility, socketRoundTrip(t, conn, request).Status, t)
+}
+
+func TestSocketAuthenticatesWithHeader(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+
+	conn, err := dialMockSocket(t, mockStore, input1.Owner, http.Header{"Authorization": {"Bearer token"}})
+	if err != nil {
+		t.Fatalf("handler returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	response := socketRoundTrip(t, conn, endpoints.SocketRequest{Id: "1", Op: endpoints.SocketOpGet, Key: input1.Key})
+	if response.Status != http.StatusOK || response.Value != input1.Value || response.Version == 0 {
+		t.Errorf("handler returned unexpected response: got %+v", response)
+	}
+}
+
+func TestSocketClosesIfNotAuthenticated(t *testing.T) {
+
+	conn, _ := dialMockSocket(t, NewMockStore(), input1.Owner, nil)
+
+	response := socketRoundTrip(t, conn, endpoints.SocketRequest{Id: "1", Op: endpoints.SocketOpGet, Key: input1.Key})
+	AssertErrorHttpCode(common.ErrorAuthorizationHeaderMissing, response.Status, t)
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation {
+		t.Errorf("handler returned unexpected error: got %v want %v", err, websocket.ClosePolicyViolation)
+	}
+}
+
+func TestSocketReturnsStoreErrors(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	conn := authenticatedMockSocket(t, mockStore, input2.Owner)
+
+	tests := []struct {
+		request endpoints.SocketRequest
+		err     error
+	}{
+		{endpoints.SocketRequest{Id: "1", Op: endpoints.SocketOpGet, Key: input2.Key}, common.ErrorKeyNotFound},
+		{endpoints.SocketRequest{Id: "2", Op: endpoints.SocketOpDelete, Key: input1.Key}, common.ErrorUnauthorisedOwner},
+		{endpoints.SocketRequest{Id: "3", Op: endpoints.SocketOpPut, Key: input2.Key}, common.ErrorStoreValueNotSet},
+		{endpoints.SocketRequest{Id: "4", Op: endpoints.SocketOpPut, Key: input2.Key, Value: "v", IfMatch: "\"99\""}, common.ErrorPreconditionFailed},
+		{endpoints.SocketRequest{Id: "5", Op: "rename"}, common.ErrorInvalidMessage},
+	}
+
+	for _, test := range tests {
+		response := socketRoundTrip(t, conn, test.request)
+		if response.Id != test.request.Id || response.Error == "" {
+			t.Errorf("handler returned unexpected response: got %+v", response)
+		}
+		AssertErrorHttpCode(test.err, response.Status, t)
+	}
+}
+
+func TestSocketListsWithQuery(t *testing.T) {
+
+	mockStore := NewMockStore()
+	for _, key := range []string{"a/1", "a/2", "b/1"} {
+		mockStore.MakePutRequest(context.Background(), key, input1.Value, input1.Owner)
+	}
+	conn := authenticatedMockSocket(t, mockStore, input1.Owner)
+
+	sendSocketRequest(t, conn, endpoints.SocketRequest{Id: "1", Op: endpoints.SocketOpList, Query: map[string]string{"prefix": "a/"}})
+	_, data, _ := conn.ReadMessage()
+
+	var response struct {
+		Status int              `json:"status"`
+		Result store.ScanResult `json:"result"`
+	}
+	json.Unmarshal(data, &response)
+	if response.Status != http.StatusOK || len(response.Result.Entries) != 2 {
+		t.Errorf("handler returned unexpected response: got %v", string(data))
+	}
+}
+
+func TestSocketMultiplexesWatches(t *testing.T) {
+
+	mockStore := NewMockStore()
+	conn := authenticatedMockSocket(t, mockStore, input1.Owner)
+
+	response := socketRoundTrip(t, conn, endpoints.SocketRequest{Id: "w1", Op: endpoints.SocketOpWatch, Key: input1.Key})
+	if response.Status != http.StatusOK {
+		t.Fatalf("handler returned unexpected response: got %+v", response)
+	}
+
+	response = socketRoundTrip(t, conn, endpoints.SocketRequest{Id: "w1", Op: endpoints.SocketOpWatch, Key: input2.Key})
+	AssertErrorHttpCode(common.ErrorInvalidMessage, response.Status, t)
+
+	// frames for different requests may arrive in any order
+	sendSocketRequest(t, conn, endpoints.SocketRequest{Id: "2", Op: endpoints.SocketOpPut, Key: input1.Key, Value: input1.Value})
+	received := readSocketResponses(t, conn, 2)
+	if received["2"].Status != http.StatusOK || received["w1"].Event == nil || received["w1"].Event.Type != store.EventPut {
+		t.Errorf("handler returned unexpected responses: got %+v", received)
+	}
+
+	// the watch is done, with no more events, by the time unwatch answers
+	sendSocketRequest(t, conn, endpoints.SocketRequest{Id: "3", Op: endpoints.SocketOpUnwatch, Watch: "w1"})
+	if response := readSocketResponse(t, conn); response.Id != "w1" || !response.Done {
+		t.Errorf("handler returned unexpected response: got %+v", response)
+	}
+	if response := readSocketResponse(t, conn); response.Id != "3" || response.Status != http.StatusOK {
+		t.Errorf("handler returned unexpected response: got %+v", response)
+	}
+
+	response = socketRoundTrip(t, conn, endpoints.SocketRequest{Id: "4", Op: endpoints.SocketOpPut, Key: input1.Key, Value: input2.Value})
+	if response.Id != "4" || response.Status != http.StatusOK {
+		t.Errorf("handler returned unexpected response: got %+v", response)
+	}
+}
+
+func readSocketResponses(t *testing.T, conn *websocket.Conn, count int) map[string]endpoints.SocketResponse {
+	received := map[string]endpoints.SocketResponse{}
+	for i := 0; i < count; i++ {
+		response := readSocketResponse(t, conn)
+		received[response.Id] = response
+	}
+	return received
+}
+
+func TestSocketRejectsPlainRequest(t *testing.T) {
+
+	route := CreateMockRouteWithSocket("/ws", &MockTracer{}, NewMockStore(), NewMockAuthenticator(input1.Owner))
+	req, _ := http.NewRequest(http.MethodGet, "/ws", nil)
+
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	expected := http.StatusBadRequest
+	if rr.Code != expected {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, expected)
+	}
+}
@@ -0,0 +1,46 @@
+package endpoints
+
+import (
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"net/http"
+)
+
+type StatsHandler struct {
+	Tracer     utils.Tracer
+	httpMethod string
+	store      store.Store
+}
+
+func (p *StatsHandler) HttpMethod() string {
+	return p.httpMethod
+}
+
+func (p *StatsHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWriter, req *http.Request) HttpResult {
+	return p.handleRequest(args, req, resp)
+}
+
+func (p *StatsHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
+	username := args.Get(UsernameParameter)
+	if username == "" {
+		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
+	}
+
+	if !p.store.UserDatabase().HasPermission(username, users.PermissionOperate) {
+		return CreateHttpResponseFromError(common.ErrorPermissionDenied)
+	}
+
+	stats, err := p.store.MakeStatsRequest(req.Context())
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	err = writeResponse(stats, resp)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	return CreateHttpResponse("Ok", http.StatusOK)
+}
@@ -0,0 +1,78 @@
+package endpoints_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func CreateMockRouteWithStats(path string, tracer utils.Tracer, kvStore store.Store, authenticator endpoints.Authenticator) endpoints.Route {
+	var methods []endpoints.HttpMethodHandler
+	methods = append(methods, endpoints.CreateStats(tracer, kvStore))
+
+	return &endpoints.SecureRoute{Path: path, Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+func createMockStatsRequestWithUsername(store store.Store, username string) *httptest.ResponseRecorder {
+
+	path := "/admin/stats"
+
+	route := CreateMockRouteWithStats(path, &MockTracer{}, store, NewMockAuthenticatorWithValue(username))
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+
+	if err != nil {
+		return nil
+	}
+
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestStatsReturnsForbiddenForNonAdminUser(t *testing.T) {
+
+	mockStore := NewMockStore()
+	rr := createMockStatsRequestWithUsername(mockStore, input1.Owner)
+
+	AssertErrorHttpCode(common.ErrorUnauthorisedOwner, rr.Code, t)
+}
+
+func TestStatsReturnsErrorIfOwnerIsMissing(t *testing.T) {
+
+	mockStore := NewMockStore()
+	rr := createMockStatsRequestWithUsername(mockStore, "")
+
+	AssertErrorHttpCode(common.ErrorAuthorizationHeaderMissing, rr.Code, t)
+}
+
+func TestStatsReturnsUsageAndEvictionsToAdmin(t *testing.T) {
+
+	config := store.Config{Depth: 1, MaxBytes: 1 << 20}
+	mockStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), config)
+	mockStore.UserDatabase().AddUser("admin", "123")
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input2.Key, input2.Value, input2.Owner)
+	rr := createMockStatsRequestWithUsername(mockStore, "admin")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned unexpected code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var stats store.Stats
+	json.Unmarshal(rr.Body.Bytes(), &stats)
+	entry, _ := mockStore.MakeListRequest(context.Background(), input2.Key, input1.Owner)
+	if stats.Keys != 1 || stats.Bytes != entry.Size() || stats.Evictions != 1 {
+		t.Errorf("handler returned unexpected stats: got %+v", stats)
+	}
+	if stats.MaxKeys != config.Depth || stats.MaxBytes != config.MaxBytes {
+		t.Errorf("handler returned unexpected limits: got %v %v want %v %v", stats.MaxKeys, stats.MaxBytes, config.Depth, config.MaxBytes)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"demo-store/store"
 	"demo-store/users"
 	"demo-store/utils"
-	"errors"
 	"io/ioutil"
 	"net/http"
 )
@@ -50,34 +49,8 @@ type HttpResult struct {
 }
 
 func CreateHttpResponseFromError(err error) HttpResult {
-	switch {
-	case errors.Is(err, common.ErrorValidatingJwtToken):
-		return CreateHttpResponse(err.Error(), http.StatusUnauthorized)
-
-	case errors.Is(err, common.ErrorKeyNotFound):
-		return CreateHttpResponse("Key not found", http.StatusNotFound)
-
-	case errors.Is(err, common.ErrorUnauthorisedOwner):
-		return CreateHttpResponse("Forbiden", http.StatusForbidden)
-
-	case errors.Is(err, common.ErrorAuthorizationFailed):
-		return CreateHttpResponse("Unauthorized", http.StatusUnauthorized)
-
-	case errors.Is(err, common.ErrorInvalidAuthorizationHeader):
-		return CreateHttpResponse(err.Error(), http.StatusUnprocessableEntity)
-
-	case errors.Is(err, common.ErrorAuthorizationHeaderMissing):
-		return CreateHttpResponse("Forbidden", http.StatusForbidden)
-
-	case errors.Is(err, common.ErrorKeyNotSet):
-		return CreateHttpResponse(err.Error(), http.StatusUnprocessableEntity)
-
-	case errors.Is(err, common.ErrorStoreValueNotSet):
-		return CreateHttpResponse(err.Error(), http.StatusUnprocessableEntity)
-
-	default:
-		return CreateHttpResponse(err.Error(), http.StatusInternalServerError)
-	}
+	code, message := common.HttpStatusFromError(err)
+	return CreateHttpResponse(message, code)
 }
 
 type Routes struct {
@@ -88,14 +61,23 @@ type Routes struct {
 func APIRoutes(tracer utils.Tracer, kvStore store.Store) *Routes {
 
 	routes := Routes{Insecure: []Route{}, Secure: []Route{}}
-	authenticator := NewRouteAuthenticator(tracer)
+	authenticator := NewRouteAuthenticatorWithUsers(tracer, kvStore.UserDatabase())
 
 	routes.Secure = append(routes.Secure, CreateStoreRoute(tracer, kvStore, authenticator))
 	routes.Secure = append(routes.Secure, CreateListRoute(tracer, kvStore, authenticator))
+	routes.Secure = append(routes.Secure, CreateAclRoute(tracer, kvStore, authenticator))
+	routes.Secure = append(routes.Secure, CreateWatchRoute(tracer, kvStore, authenticator))
+	routes.Secure = append(routes.Secure, CreateBatchRoute(tracer, kvStore, authenticator))
 	routes.Secure = append(routes.Secure, CreateShutdownRoute(tracer, kvStore, authenticator))
+	routes.Secure = append(routes.Secure, CreateSnapshotRoute(tracer, kvStore, authenticator))
+	routes.Secure = append(routes.Secure, CreateStatsRoute(tracer, kvStore, authenticator))
+	routes.Secure = append(routes.Secure, CreateUsersRoute(tracer, kvStore.UserDatabase(), authenticator))
+	routes.Secure = append(routes.Secure, CreateGroupsRoute(tracer, kvStore.UserDatabase(), authenticator))
+	routes.Secure = append(routes.Secure, CreatePasswordRoute(tracer, kvStore.UserDatabase(), authenticator))
 
 	routes.Insecure = append(routes.Insecure, CreatePingRoute(tracer, kvStore))
 	routes.Insecure = append(routes.Insecure, CreateLoginRoute(tracer, kvStore.UserDatabase()))
+	routes.Insecure = append(routes.Insecure, CreateSocketRoute(tracer, kvStore, authenticator))
 
 	return &routes
 }
@@ -127,6 +109,28 @@ func CreateListRoute(tracer utils.Tracer, kvStore store.Store, authenticator Aut
 	return &SecureRoute{Path: "/list/", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
 }
 
+func CreateAclRoute(tracer utils.Tracer, kvStore store.Store, authenticator Authenticator) Route {
+	var methods []HttpMethodHandler
+	methods = append(methods, CreateAcl(tracer, kvStore, http.MethodGet))
+	methods = append(methods, CreateAcl(tracer, kvStore, http.MethodPatch))
+
+	return &SecureRoute{Path: "/acl/", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+func CreateWatchRoute(tracer utils.Tracer, kvStore store.Store, authenticator Authenticator) Route {
+	var methods []HttpMethodHandler
+	methods = append(methods, CreateWatch(tracer, kvStore))
+
+	return &SecureRoute{Path: "/watch/", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+func CreateBatchRoute(tracer utils.Tracer, kvStore store.Store, authenticator Authenticator) Route {
+	var methods []HttpMethodHandler
+	methods = append(methods, CreateBatch(tracer, kvStore))
+
+	return &SecureRoute{Path: "/batch", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
 func CreateShutdownRoute(tracer utils.Tracer, kvStore store.Store, authenticator Authenticator) Route {
 	var methods []HttpMethodHandler
 	methods = append(methods, CreateShutdown(tracer, kvStore))
@@ -134,6 +138,56 @@ func CreateShutdownRoute(tracer utils.Tracer, kvStore store.Store, authenticator
 	return &SecureRoute{Path: "/shutdown/", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
 }
 
+func CreateSnapshotRoute(tracer utils.Tracer, kvStore store.Store, authenticator Authenticator) Route {
+	var methods []HttpMethodHandler
+	methods = append(methods, CreateSnapshot(tracer, kvStore))
+
+	return &SecureRoute{Path: "/admin/snapshot", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+func CreateStatsRoute(tracer utils.Tracer, kvStore store.Store, authenticator Authenticator) Route {
+	var methods []HttpMethodHandler
+	methods = append(methods, CreateStats(tracer, kvStore))
+
+	return &SecureRoute{Path: "/admin/stats", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+func CreateUsersRoute(tracer utils.Tracer, users users.UserDatabase, authenticator Authenticator) Route {
+	var methods []HttpMethodHandler
+	methods = append(methods, CreateUsers(tracer, users, http.MethodGet))
+	methods = append(methods, CreateUsers(tracer, users, http.MethodPost))
+	methods = append(methods, CreateUsers(tracer, users, http.MethodPatch))
+	methods = append(methods, CreateUsers(tracer, users, http.MethodDelete))
+
+	return &SecureRoute{Path: "/admin/users/", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+func CreateGroupsRoute(tracer utils.Tracer, users users.UserDatabase, authenticator Authenticator) Route {
+	var methods []HttpMethodHandler
+	methods = append(methods, CreateGroups(tracer, users, http.MethodGet))
+	methods = append(methods, CreateGroups(tracer, users, http.MethodPost))
+	methods = append(methods, CreateGroups(tracer, users, http.MethodPatch))
+	methods = append(methods, CreateGroups(tracer, users, http.MethodDelete))
+
+	return &SecureRoute{Path: "/admin/groups/", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+func CreatePasswordRoute(tracer utils.Tracer, users users.UserDatabase, authenticator Authenticator) Route {
+	var methods []HttpMethodHandler
+	methods = append(methods, CreatePassword(tracer, users))
+
+	return &SecureRoute{Path: "/me/password", Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+// CreateSocketRoute is insecure as far as the router is concerned because the
+// handler authenticates connections itself.
+func CreateSocketRoute(tracer utils.Tracer, kvStore store.Store, authenticator Authenticator) Route {
+	var methods []HttpMethodHandler
+	methods = append(methods, CreateSocket(tracer, kvStore, authenticator))
+
+	return &InsecureRoute{Path: "/ws", Tracer: tracer, MethodHandlers: methods}
+}
+
 func CreateLoginRoute(tracer utils.Tracer, users users.UserDatabase) Route {
 	var methods []HttpMethodHandler
 	methods = append(methods, CreateLogin(tracer, users))
@@ -161,10 +215,46 @@ func CreateDelete(tracer utils.Tracer, kvStore store.Store) *DeleteHandler {
 	return &DeleteHandler{Tracer: tracer, httpMethod: http.MethodDelete, store: kvStore}
 }
 
+func CreateWatch(tracer utils.Tracer, kvStore store.Store) *WatchHandler {
+	return &WatchHandler{Tracer: tracer, httpMethod: http.MethodGet, store: kvStore}
+}
+
+func CreateSocket(tracer utils.Tracer, kvStore store.Store, authenticator Authenticator) *SocketHandler {
+	return &SocketHandler{Tracer: tracer, httpMethod: http.MethodGet, store: kvStore, authenticator: authenticator}
+}
+
+func CreateBatch(tracer utils.Tracer, kvStore store.Store) *BatchHandler {
+	return &BatchHandler{Tracer: tracer, httpMethod: http.MethodPost, store: kvStore}
+}
+
 func CreateShutdown(tracer utils.Tracer, kvStore store.Store) *ShutdownHandler {
 	return &ShutdownHandler{Tracer: tracer, httpMethod: http.MethodGet, store: kvStore}
 }
 
+func CreateSnapshot(tracer utils.Tracer, kvStore store.Store) *SnapshotHandler {
+	return &SnapshotHandler{Tracer: tracer, httpMethod: http.MethodPost, store: kvStore}
+}
+
+func CreateStats(tracer utils.Tracer, kvStore store.Store) *StatsHandler {
+	return &StatsHandler{Tracer: tracer, httpMethod: http.MethodGet, store: kvStore}
+}
+
+func CreateAcl(tracer utils.Tracer, kvStore store.Store, httpMethod string) *AclHandler {
+	return &AclHandler{Tracer: tracer, httpMethod: httpMethod, store: kvStore}
+}
+
+func CreateUsers(tracer utils.Tracer, users users.UserDatabase, httpMethod string) *UsersHandler {
+	return &UsersHandler{Tracer: tracer, httpMethod: httpMethod, users: users}
+}
+
+func CreateGroups(tracer utils.Tracer, users users.UserDatabase, httpMethod string) *GroupsHandler {
+	return &GroupsHandler{Tracer: tracer, httpMethod: httpMethod, users: users}
+}
+
+func CreatePassword(tracer utils.Tracer, users users.UserDatabase) *PasswordHandler {
+	return &PasswordHandler{Tracer: tracer, httpMethod: http.MethodPut, users: users}
+}
+
 func CreateLogin(tracer utils.Tracer, users users.UserDatabase) *LoginHandler {
 	return &LoginHandler{Tracer: tracer, httpMethod: http.MethodGet, Users: users, Tokenizer: utils.NewJwtTokenizer(tracer)}
 }
@@ -174,15 +264,19 @@ func CreateLoginWithTokenizer(tracer utils.Tracer, users users.UserDatabase, tok
 }
 
 func GetBody(req *http.Request) string {
+	return string(GetBodyBytes(req))
+}
+
+func GetBodyBytes(req *http.Request) []byte {
 	if req.Body == nil {
-		return ""
+		return nil
 	}
 
 	body, err := ioutil.ReadAll(req.Body)
 	if err != nil {
 
-		return ""
+		return nil
 	}
 
-	return string(body)
+	return body
 }
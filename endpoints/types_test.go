@@ -1,6 +1,7 @@
 package endpoints_test
 
 import (
+	"context"
 	"demo-store/common"
 	"demo-store/endpoints"
 	"demo-store/store"
@@ -235,3 +236,22 @@ func TestCreateHttpResponseFromErrorErrorValidatingJwtToken(t *testing.T) {
 		t.Errorf("handler returned unexpected value: got %v want %v", resp.Code, expected)
 	}
 }
+
+func TestCreateHttpResponseFromErrorStoreUnavailable(t *testing.T) {
+
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{common.ErrorStoreClosed, http.StatusServiceUnavailable},
+		{context.Canceled, http.StatusServiceUnavailable},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+	}
+
+	for _, test := range tests {
+		resp := endpoints.CreateHttpResponseFromError(test.err)
+		if resp.Code != test.expected {
+			t.Errorf("handler returned unexpected value for %v: got %v want %v", test.err, resp.Code, test.expected)
+		}
+	}
+}
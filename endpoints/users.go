@@ -0,0 +1,165 @@
+package endpoints
+
+import (
+	"demo-store/common"
+	"demo-store/users"
+	"demo-store/utils"
+	"encoding/json"
+	"net/http"
+)
+
+// UserRequest is the JSON body creating or updating a user. An update leaves
+// out what it doesn't change.
+type UserRequest struct {
+	Username string       `json:"username,omitempty"`
+	Password string       `json:"password,omitempty"`
+	Disabled *bool        `json:"disabled,omitempty"`
+	Roles    []users.Role `json:"roles,omitempty"`
+}
+
+// UserResponse is how a user is shown, without its password hash.
+type UserResponse struct {
+	Username string       `json:"username"`
+	Disabled bool         `json:"disabled"`
+	Roles    []users.Role `json:"roles"`
+}
+
+// UsersHandler serves the user API under /admin/users/ to users allowed to
+// manage users, creating users with POST, listing or showing them with GET,
+// resetting passwords, changing roles and disabling or enabling users with
+// PATCH and deleting them with DELETE.
+type UsersHandler struct {
+	Tracer     utils.Tracer
+	httpMethod string
+	users      users.UserDatabase
+}
+
+func (p *UsersHandler) HttpMethod() string {
+	return p.httpMethod
+}
+
+func (p *UsersHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWriter, req *http.Request) HttpResult {
+	return p.handleRequest(args, req, resp)
+}
+
+func (p *UsersHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
+
+	username := args.Get(UsernameParameter)
+	if username == "" {
+		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
+	}
+
+	if !p.users.HasPermission(username, users.PermissionManageUsers) {
+		return CreateHttpResponseFromError(common.ErrorPermissionDenied)
+	}
+
+	name, err := DecodeKeyPath(req, args.Get(PathParameter))
+	if err != nil {
+		return CreateHttpResponseFromError(users.ErrorInvalidUser)
+	}
+
+	switch p.httpMethod {
+	case http.MethodPost:
+		err = p.create(name, req, resp)
+	case http.MethodPatch:
+		err = p.update(name, req, resp)
+	case http.MethodDelete:
+		err = p.users.DeleteUser(name)
+	default:
+		err = p.show(name, resp)
+	}
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+
+	return CreateHttpResponse("Ok", http.StatusOK)
+}
+
+func (p *UsersHandler) show(name string, resp http.ResponseWriter) error {
+
+	if name == "" {
+		list := p.users.ListUsers()
+		responses := make([]UserResponse, 0, len(list))
+		for _, user := range list {
+			responses = append(responses, p.userResponse(&user))
+		}
+		return writeResponse(responses, resp)
+	}
+
+	user, err := p.users.FindUser(name)
+	if err != nil {
+		return err
+	}
+	return writeResponse(p.userResponse(user), resp)
+}
+
+func (p *UsersHandler) create(name string, req *http.Request, resp http.ResponseWriter) error {
+
+	request, err := parseUserRequest(req)
+	if err != nil {
+		return err
+	}
+	if request.Username == "" {
+		request.Username = name
+	}
+	if name != "" && name != request.Username {
+		return users.ErrorInvalidUser
+	}
+
+	roles := request.Roles
+	if roles == nil {
+		roles = users.DefaultRoles(request.Username)
+	}
+	if err := p.users.AddUserWithRoles(request.Username, request.Password, roles); err != nil {
+		return err
+	}
+	if request.Disabled != nil && *request.Disabled {
+		if err := p.users.SetDisabled(request.Username, true); err != nil {
+			return err
+		}
+	}
+	return p.show(request.Username, resp)
+}
+
+func (p *UsersHandler) update(name string, req *http.Request, resp http.ResponseWriter) error {
+
+	request, err := parseUserRequest(req)
+	if err != nil {
+		return err
+	}
+	if name == "" || (request.Password == "" && request.Disabled == nil && request.Roles == nil) {
+		return users.ErrorInvalidUser
+	}
+	if request.Username != "" && request.Username != name {
+		return users.ErrorInvalidUser
+	}
+
+	if request.Password != "" {
+		if err := p.users.SetPassword(name, request.Password); err != nil {
+			return err
+		}
+	}
+	if request.Roles != nil {
+		if err := p.users.SetRoles(name, request.Roles); err != nil {
+			return err
+		}
+	}
+	if request.Disabled != nil {
+		if err := p.users.SetDisabled(name, *request.Disabled); err != nil {
+			return err
+		}
+	}
+	return p.show(name, resp)
+}
+
+func (p *UsersHandler) userResponse(user *users.User) UserResponse {
+	return UserResponse{Username: user.UserName, Disabled: user.Disabled, Roles: user.Roles}
+}
+
+func parseUserRequest(req *http.Request) (*UserRequest, error) {
+	var request UserRequest
+	if err := json.Unmarshal(GetBodyBytes(req), &request); err != nil {
+		return nil, common.ErrorInvalidMessage
+	}
+	return &request, nil
+}
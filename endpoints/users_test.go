@@ -0,0 +1,167 @@
+package endpoints_test
+
+import (
+	"bytes"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"demo-store/users"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func createMockUsersRequest(userDatabase users.UserDatabase, method string, url string, body string, username string) *httptest.ResponseRecorder {
+
+	route := endpoints.CreateUsersRoute(&MockTracer{}, userDatabase, NewMockAuthenticatorWithValue(username))
+	req, err := http.NewRequest(method, "/admin/users/"+url, bytes.NewBufferString(body))
+	if err != nil {
+		return nil
+	}
+
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func createMockGroupsRequest(userDatabase users.UserDatabase, method string, url string, body string, username string) *httptest.ResponseRecorder {
+
+	route := endpoints.CreateGroupsRoute(&MockTracer{}, userDatabase, NewMockAuthenticatorWithValue(username))
+	req, err := http.NewRequest(method, "/admin/groups/"+url, bytes.NewBufferString(body))
+	if err != nil {
+		return nil
+	}
+
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func createMockPasswordRequest(userDatabase users.UserDatabase, body string, username string) *httptest.ResponseRecorder {
+
+	route := endpoints.CreatePasswordRoute(&MockTracer{}, userDatabase, NewMockAuthenticatorWithValue(username))
+	req, err := http.NewRequest(http.MethodPut, "/me/password", bytes.NewBufferString(body))
+	if err != nil {
+		return nil
+	}
+
+	rr := httptest.NewRecorder()
+	route.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestUsersReturnsForbiddenForNonAdminUser(t *testing.T) {
+
+	userDatabase := CreateMockUserDatabase()
+	rr := createMockUsersRequest(userDatabase, http.MethodGet, "", "", input1.Owner)
+
+	AssertErrorHttpCode(common.ErrorUnauthorisedOwner, rr.Code, t)
+}
+
+func TestUsersCanBeManagedByAdmin(t *testing.T) {
+
+	userDatabase := CreateMockUserDatabase()
+	userDatabase.AddUser("admin", "admin")
+
+	rr := createMockUsersRequest(userDatabase, http.MethodPost, "", `{"username": "user1", "password": "1111"}`, "admin")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned unexpected code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	rr = createMockUsersRequest(userDatabase, http.MethodPost, "", `{"username": "user1", "password": "1111"}`, "admin")
+	AssertErrorHttpCode(users.ErrorUserExists, rr.Code, t)
+
+	rr = createMockUsersRequest(userDatabase, http.MethodPatch, "user1", `{"disabled": true}`, "admin")
+	var user endpoints.UserResponse
+	json.Unmarshal(rr.Body.Bytes(), &user)
+	if rr.Code != http.StatusOK || !user.Disabled {
+		t.Errorf("handler returned unexpected user: got %v %+v want %v", rr.Code, user, "disabled")
+	}
+	if err := userDatabase.Authenticate("user1", "1111"); err != users.ErrorUserDisabled {
+		t.Errorf("Returned unexpected error: got %v want %v", err, users.ErrorUserDisabled)
+	}
+
+	rr = createMockUsersRequest(userDatabase, http.MethodGet, "", "", "admin")
+	var list []endpoints.UserResponse
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	expected := []endpoints.UserResponse{
+		{Username: "admin", Roles: []users.Role{users.RoleAdmin}},
+		{Username: "user1", Disabled: true, Roles: []users.Role{users.RoleWriter}},
+	}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("handler returned unexpected users: got %+v want %+v", list, expected)
+	}
+
+	rr = createMockUsersRequest(userDatabase, http.MethodPatch, "user1", `{"password": "2222", "disabled": false}`, "admin")
+	if err := userDatabase.Authenticate("user1", "2222"); rr.Code != http.StatusOK || err != nil {
+		t.Errorf("Returned unexpected error: got %v %v want %v", rr.Code, err, "nil")
+	}
+
+	rr = createMockUsersRequest(userDatabase, http.MethodPatch, "user1", `{"roles": ["reader", "operator"]}`, "admin")
+	if !userDatabase.HasPermission("user1", users.PermissionOperate) || userDatabase.HasPermission("user1", users.PermissionWrite) {
+		t.Errorf("handler returned unexpected roles: got %v want %v", rr.Body.String(), "reader, operator")
+	}
+	rr = createMockUsersRequest(userDatabase, http.MethodPatch, "user1", `{"roles": ["superuser"]}`, "admin")
+	AssertErrorHttpCode(users.ErrorInvalidRole, rr.Code, t)
+
+	rr = createMockUsersRequest(userDatabase, http.MethodDelete, "admin", "", "admin")
+	AssertErrorHttpCode(users.ErrorUserProtected, rr.Code, t)
+
+	rr = createMockUsersRequest(userDatabase, http.MethodDelete, "user1", "", "admin")
+	if _, err := userDatabase.FindUser("user1"); rr.Code != http.StatusOK || err != users.ErrorUserNotFound {
+		t.Errorf("Returned unexpected error: got %v %v want %v", rr.Code, err, users.ErrorUserNotFound)
+	}
+
+	rr = createMockUsersRequest(userDatabase, http.MethodGet, "user1", "", "admin")
+	AssertErrorHttpCode(users.ErrorUserNotFound, rr.Code, t)
+}
+
+func TestPasswordCanBeChangedWithCurrentPassword(t *testing.T) {
+
+	userDatabase := CreateMockUserDatabase()
+	userDatabase.AddUser("user1", "1111")
+
+	rr := createMockPasswordRequest(userDatabase, `{"current_password": "2222", "password": "3333"}`, "user1")
+	AssertErrorHttpCode(users.ErrorUserAuthentication, rr.Code, t)
+
+	rr = createMockPasswordRequest(userDatabase, `{"current_password": "1111", "password": "3333"}`, "user1")
+	if err := userDatabase.Authenticate("user1", "3333"); rr.Code != http.StatusOK || err != nil {
+		t.Errorf("Returned unexpected error: got %v %v want %v", rr.Code, err, "nil")
+	}
+}
+
+func TestGroupsCanBeManagedByAdmin(t *testing.T) {
+
+	userDatabase := CreateMockUserDatabase()
+	userDatabase.AddUser("admin", "admin")
+	userDatabase.AddUser("user1", "1111")
+
+	rr := createMockGroupsRequest(userDatabase, http.MethodPost, "", `{"name": "team"}`, "user1")
+	AssertErrorHttpCode(common.ErrorPermissionDenied, rr.Code, t)
+
+	rr = createMockGroupsRequest(userDatabase, http.MethodPost, "", `{"name": "team", "members": ["unknown"]}`, "admin")
+	AssertErrorHttpCode(users.ErrorUserNotFound, rr.Code, t)
+
+	rr = createMockGroupsRequest(userDatabase, http.MethodPost, "", `{"name": "team", "members": ["user1"]}`, "admin")
+	var group endpoints.GroupResponse
+	json.Unmarshal(rr.Body.Bytes(), &group)
+	expected := endpoints.GroupResponse{Name: "team", Owner: "group:team", Members: []string{"user1"}}
+	if rr.Code != http.StatusOK || !reflect.DeepEqual(group, expected) {
+		t.Errorf("handler returned unexpected group: got %v %+v want %+v", rr.Code, group, expected)
+	}
+
+	rr = createMockGroupsRequest(userDatabase, http.MethodPatch, "team", `{"remove": ["user1"]}`, "admin")
+	if rr.Code != http.StatusOK || userDatabase.Includes("group:team", "user1") {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	rr = createMockGroupsRequest(userDatabase, http.MethodDelete, "team", "", "admin")
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned unexpected code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	rr = createMockGroupsRequest(userDatabase, http.MethodGet, "team", "", "admin")
+	AssertErrorHttpCode(users.ErrorGroupNotFound, rr.Code, t)
+}
@@ -0,0 +1,142 @@
+package endpoints
+
+import (
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	LastEventIdHeader = "Last-Event-ID"
+	AfterParameter    = "after"
+
+	// WatchKeepAliveInterval is how often an idle stream is sent a comment so
+	// proxies and clients don't time it out.
+	WatchKeepAliveInterval = 15 * time.Second
+)
+
+type WatchHandler struct {
+	Tracer     utils.Tracer
+	httpMethod string
+	store      store.Store
+}
+
+func (p *WatchHandler) HttpMethod() string {
+	return p.httpMethod
+}
+
+func (p *WatchHandler) Handle(args *HttpMethodHandlerParams, resp http.ResponseWriter, req *http.Request) HttpResult {
+	return p.handleRequest(args, req, resp)
+}
+
+func (p *WatchHandler) handleRequest(args *HttpMethodHandlerParams, req *http.Request, resp http.ResponseWriter) HttpResult {
+
+	username := args.Get(UsernameParameter)
+	if username == "" {
+		return CreateHttpResponseFromError(common.ErrorAuthorizationHeaderMissing)
+	}
+
+	key, err := DecodeKeyPath(req, args.Get(PathParameter))
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+	query, err := ParseWatchQuery(key, req)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+	query.Reader = username
+
+	flusher, ok := resp.(http.Flusher)
+	if !ok {
+		return CreateHttpResponse("Streaming unsupported", http.StatusInternalServerError)
+	}
+
+	watcher, err := p.store.MakeWatchRequest(req.Context(), query)
+	if err != nil {
+		return CreateHttpResponseFromError(err)
+	}
+	defer p.store.MakeUnwatchRequest(watcher)
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.WriteHeader(http.StatusOK)
+
+	// give new watchers a position to resume from even if no event arrives
+	if query.After == 0 {
+		fmt.Fprintf(resp, "id: %d\n\n", watcher.Sequence)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(WatchKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return CreateHttpResponse("Ok", http.StatusOK)
+
+		case event, ok := <-watcher.Events:
+			// the store stopped or the client fell too far behind, either way
+			// it reconnects and resumes from the last event it saw
+			if !ok {
+				return CreateHttpResponse("Ok", http.StatusOK)
+			}
+			if err := WriteEvent(resp, event); err != nil {
+				return CreateHttpResponse("Ok", http.StatusOK)
+			}
+			flusher.Flush()
+
+		case <-keepAlive.C:
+			fmt.Fprint(resp, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// ParseWatchQuery watches the key, or every key starting with it when the
+// prefix parameter is set or the key is empty. The resume position comes from
+// the Last-Event-ID header a reconnecting EventSource sends, or the after
+// parameter.
+func ParseWatchQuery(key string, req *http.Request) (store.WatchQuery, error) {
+
+	query := store.WatchQuery{Key: key, Prefix: key == ""}
+
+	if value := req.URL.Query().Get(PrefixParameter); value != "" {
+		prefix, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, common.ErrorInvalidQuery
+		}
+		query.Prefix = query.Prefix || prefix
+	}
+
+	after := req.Header.Get(LastEventIdHeader)
+	if after == "" {
+		after = req.URL.Query().Get(AfterParameter)
+	}
+	if after != "" {
+		sequence, err := strconv.ParseUint(after, 10, 64)
+		if err != nil {
+			return query, common.ErrorInvalidQuery
+		}
+		query.After = sequence
+	}
+
+	return query, nil
+}
+
+// WriteEvent writes the event as a Server-Sent Event named after its type.
+func WriteEvent(writer io.Writer, event store.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(writer, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, data)
+	return err
+}
@@ -0,0 +1,151 @@
+package endpoints_test
+
+import (
+	"bufio"
+	"context"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"demo-store/store"
+	"demo-store/utils"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func CreateMockRouteWithWatch(path string, tracer utils.Tracer, kvStore store.Store, authenticator endpoints.Authenticator) endpoints.Route {
+	var methods []endpoints.HttpMethodHandler
+	methods = append(methods, endpoints.CreateWatch(tracer, kvStore))
+
+	return &endpoints.SecureRoute{Path: path, Tracer: tracer, MethodHandlers: methods, Authenticator: authenticator}
+}
+
+// openMockWatchStream starts a watch and returns its response once the stream
+// has started, closing it when the test ends.
+func openMockWatchStream(t *testing.T, kvStore store.Store, url string, lastEventId string) *http.Response {
+
+	path := "/watch/"
+	route := CreateMockRouteWithWatch(path, &MockTracer{}, kvStore, NewMockAuthenticator(input1.Owner))
+	server := httptest.NewServer(route)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		server.Close()
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path+url, nil)
+	if lastEventId != "" {
+		req.Header.Set(endpoints.LastEventIdHeader, lastEventId)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("handler returned unexpected error: got %v want %v", err, "nil")
+	}
+	return resp
+}
+
+// readFrames reads Server-Sent Event frames until count of them carry data.
+func readFrames(t *testing.T, resp *http.Response, count int) []string {
+
+	frames := make(chan string)
+	go func() {
+		defer close(frames)
+		reader := bufio.NewReader(resp.Body)
+		var frame strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if line != "\n" {
+				frame.WriteString(line)
+				continue
+			}
+			if strings.Contains(frame.String(), "data: ") {
+				frames <- frame.String()
+			}
+			frame.Reset()
+		}
+	}()
+
+	var received []string
+	for len(received) < count {
+		select {
+		case frame, ok := <-frames:
+			if !ok {
+				t.Fatalf("handler returned unexpected end of stream after %v events", len(received))
+			}
+			received = append(received, frame)
+		case <-time.After(time.Second):
+			t.Fatalf("handler returned unexpected timeout after %v events", len(received))
+		}
+	}
+	return received
+}
+
+func TestWatchStreamsEvents(t *testing.T) {
+
+	mockStore := NewMockStore()
+	resp := openMockWatchStream(t, mockStore, input1.Key, "")
+
+	expectedStatus := http.StatusOK
+	if resp.StatusCode != expectedStatus {
+		t.Errorf("handler returned unexpected code: got %v want %v", resp.StatusCode, expectedStatus)
+	}
+	expectedContentType := "text/event-stream"
+	if resp.Header.Get("Content-Type") != expectedContentType {
+		t.Errorf("handler returned unexpected content type: got %v want %v", resp.Header.Get("Content-Type"), expectedContentType)
+	}
+
+	mockStore.MakePutRequest(context.Background(), input1.Key, input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), input2.Key, input2.Value, input2.Owner)
+	mockStore.MakeDeleteRequest(context.Background(), input1.Key, input1.Owner)
+
+	frames := readFrames(t, resp, 2)
+	if !strings.HasPrefix(frames[0], "id: 1\nevent: put\ndata: {") || !strings.Contains(frames[0], `"value":"`+base64.StdEncoding.EncodeToString([]byte(input1.Value))+`"`) {
+		t.Errorf("handler returned unexpected event: got %v", frames[0])
+	}
+	if !strings.HasPrefix(frames[1], "id: 3\nevent: delete\n") {
+		t.Errorf("handler returned unexpected event: got %v", frames[1])
+	}
+}
+
+func TestWatchResumesFromLastEventId(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), "a/1", input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), "a/2", input1.Value, input1.Owner)
+	mockStore.MakePutRequest(context.Background(), "b/1", input1.Value, input1.Owner)
+
+	resp := openMockWatchStream(t, mockStore, "a/?prefix=true", "1")
+	mockStore.MakePutRequest(context.Background(), "a/3", input1.Value, input1.Owner)
+
+	frames := readFrames(t, resp, 2)
+	if !strings.HasPrefix(frames[0], "id: 2\n") || !strings.HasPrefix(frames[1], "id: 4\n") {
+		t.Errorf("handler returned unexpected events: got %v", frames)
+	}
+}
+
+func TestWatchReturnsGoneIfPositionLost(t *testing.T) {
+
+	mockStore := NewMockStore()
+	resp := openMockWatchStream(t, mockStore, input1.Key, "10")
+
+	expectedStatus := http.StatusGone
+	if resp.StatusCode != expectedStatus {
+		t.Errorf("handler returned unexpected code: got %v want %v", resp.StatusCode, expectedStatus)
+	}
+}
+
+func TestWatchReturnsErrorIfQueryInvalid(t *testing.T) {
+
+	mockStore := NewMockStore()
+	for _, url := range []string{"?after=soon", "?prefix=maybe"} {
+		resp := openMockWatchStream(t, mockStore, input1.Key+url, "")
+		AssertErrorHttpCode(common.ErrorInvalidQuery, resp.StatusCode, t)
+	}
+}
@@ -4,5 +4,15 @@ go 1.19
 
 require (
 	github.com/golang-jwt/jwt v3.2.2+incompatible
-	golang.org/x/crypto v0.5.0
+	golang.org/x/crypto v0.18.0
+	google.golang.org/grpc v1.62.0
+	google.golang.org/protobuf v1.32.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	golang.org/x/net v0.20.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240123012728-ef4313101c80 // indirect
 )
@@ -2,14 +2,37 @@ package main
 
 import (
 	"demo-store/server"
+	"demo-store/store"
 	"demo-store/utils"
 	"flag"
 	"os"
+	"time"
 )
 
 type Args struct {
-	Port  int
-	Depth int
+	Port          int
+	Depth         int
+	MaxBytes      int64
+	Eviction      store.Eviction
+	DataPath      string
+	Fsync         store.FsyncPolicy
+	FsyncInterval time.Duration
+
+	SnapshotInterval time.Duration
+	RequestTimeout   time.Duration
+
+	Shards         int
+	LimitsPerShard bool
+
+	AllowEmptyValues bool
+	PrivateByDefault bool
+
+	RespPort int
+
+	MemcachedPort int
+	MemcachedUser string
+
+	GrpcPort int
 }
 
 func main() {
@@ -20,7 +43,33 @@ func main() {
 }
 
 func listen(args Args) {
-	err := server.Listen(args.Port, args.Depth)
+	config := server.Config{
+		Port:          args.Port,
+		Depth:         args.Depth,
+		MaxBytes:      args.MaxBytes,
+		Eviction:      args.Eviction,
+		DataPath:      args.DataPath,
+		Fsync:         args.Fsync,
+		FsyncInterval: args.FsyncInterval,
+
+		SnapshotInterval: args.SnapshotInterval,
+		RequestTimeout:   args.RequestTimeout,
+
+		Shards:         args.Shards,
+		LimitsPerShard: args.LimitsPerShard,
+
+		AllowEmptyValues: args.AllowEmptyValues,
+		PrivateByDefault: args.PrivateByDefault,
+
+		RespPort: args.RespPort,
+
+		MemcachedPort: args.MemcachedPort,
+		MemcachedUser: args.MemcachedUser,
+
+		GrpcPort: args.GrpcPort,
+	}
+
+	err := server.Listen(config)
 	if err != nil {
 		utils.ApplicationTracer().LogError(err)
 		os.Exit(-2)
@@ -30,9 +79,39 @@ func listen(args Args) {
 func readArgs() Args {
 	var port int
 	var depth int
+	var maxBytes int64
+	var eviction string
+	var dataPath string
+	var fsync string
+	var fsyncInterval int
+	var snapshotInterval int
+	var requestTimeout int
+	var shards int
+	var limitsPerShard bool
+	var allowEmptyValues bool
+	var privateByDefault bool
+	var respPort int
+	var memcachedPort int
+	var memcachedUser string
+	var grpcPort int
 
 	flag.IntVar(&port, "port", -1, "port to listen on")
 	flag.IntVar(&depth, "depth", 0, "LRU depth")
+	flag.Int64Var(&maxBytes, "max-bytes", 0, "bytes the keys, values and their metadata may take up before keys are evicted (unbounded if 0)")
+	flag.StringVar(&eviction, "eviction", "lru", "eviction policy: lru, lfu, arc or w-tinylfu")
+	flag.StringVar(&dataPath, "data", "", "directory for the write log (persistence disabled if empty)")
+	flag.StringVar(&fsync, "fsync", "interval", "write log fsync policy: always, interval or never")
+	flag.IntVar(&fsyncInterval, "fsync-interval", 1000, "milliseconds between write log fsyncs for the interval policy")
+	flag.IntVar(&snapshotInterval, "snapshot-interval", 0, "seconds between background snapshots (disabled if 0)")
+	flag.IntVar(&requestTimeout, "request-timeout", 0, "milliseconds a request waits for the store before failing (unbounded if 0)")
+	flag.IntVar(&shards, "shards", 1, "number of shards the keys are spread over, each served by its own goroutine")
+	flag.BoolVar(&limitsPerShard, "per-shard-limits", false, "apply --depth and --max-bytes to each shard rather than the whole store")
+	flag.BoolVar(&allowEmptyValues, "allow-empty-values", false, "accept keys written with an empty value")
+	flag.BoolVar(&privateByDefault, "private-by-default", false, "make keys written without a visibility readable only by their owner")
+	flag.IntVar(&respPort, "resp-port", 0, "port to serve the Redis protocol on (disabled if 0)")
+	flag.IntVar(&memcachedPort, "memcached-port", 0, "port to serve the memcached text protocol on (disabled if 0)")
+	flag.StringVar(&memcachedUser, "memcached-user", "memcached", "user owning the keys written over the memcached protocol")
+	flag.IntVar(&grpcPort, "grpc-port", 0, "port to serve gRPC on (disabled if 0)")
 	flag.Parse()
 
 	if port == -1 {
@@ -40,5 +119,56 @@ func readArgs() Args {
 		os.Exit(-1)
 	}
 
-	return Args{Port: port, Depth: depth}
+	if shards < 1 {
+		utils.ApplicationTracer().LogError("Error: shards must be at least 1")
+		os.Exit(-1)
+	}
+
+	if requestTimeout < 0 {
+		utils.ApplicationTracer().LogError("Error: request-timeout must not be negative")
+		os.Exit(-1)
+	}
+
+	if maxBytes < 0 {
+		utils.ApplicationTracer().LogError("Error: max-bytes must not be negative")
+		os.Exit(-1)
+	}
+
+	fsyncPolicy, err := store.ParseFsyncPolicy(fsync)
+	if err != nil {
+		utils.ApplicationTracer().LogError("Error:", err)
+		os.Exit(-1)
+	}
+
+	evictionPolicy, err := store.ParseEviction(eviction)
+	if err != nil {
+		utils.ApplicationTracer().LogError("Error:", err)
+		os.Exit(-1)
+	}
+
+	return Args{
+		Port:          port,
+		Depth:         depth,
+		MaxBytes:      maxBytes,
+		Eviction:      evictionPolicy,
+		DataPath:      dataPath,
+		Fsync:         fsyncPolicy,
+		FsyncInterval: time.Duration(fsyncInterval) * time.Millisecond,
+
+		SnapshotInterval: time.Duration(snapshotInterval) * time.Second,
+		RequestTimeout:   time.Duration(requestTimeout) * time.Millisecond,
+
+		Shards:         shards,
+		LimitsPerShard: limitsPerShard,
+
+		AllowEmptyValues: allowEmptyValues,
+		PrivateByDefault: privateByDefault,
+
+		RespPort: respPort,
+
+		MemcachedPort: memcachedPort,
+		MemcachedUser: memcachedUser,
+
+		GrpcPort: grpcPort,
+	}
 }
@@ -0,0 +1,411 @@
+package memcached
+
+import (
+	"bufio"
+	"demo-store/common"
+	"demo-store/store"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Version is the memcached release whose text protocol is served.
+const Version = "1.6.0"
+
+// maxRelativeExpiry is the largest expiry time taken as seconds from now
+// rather than as a Unix time.
+const maxRelativeExpiry = 60 * 60 * 24 * 30
+
+// expired stands in for an expiry time in the past, which memcached accepts
+// as storing an item that is already gone.
+const expired = time.Nanosecond
+
+const (
+	errorFormat     = "CLIENT_ERROR bad command line format"
+	errorNonNumeric = "CLIENT_ERROR cannot increment or decrement non-numeric value"
+	errorDelta      = "CLIENT_ERROR invalid numeric delta argument"
+	errorTooLarge   = "SERVER_ERROR object too large for cache"
+)
+
+type session struct {
+	server *Server
+	reader *Reader
+	writer *bufio.Writer
+
+	// noreply is set by commands that asked not to be answered
+	noreply bool
+}
+
+func (s *session) serve() {
+	for {
+		args, err := s.reader.ReadLine()
+		if errors.Is(err, ErrorLineTooLong) {
+			s.writer.WriteString("CLIENT_ERROR line too long\r\n")
+			s.writer.Flush()
+			return
+		}
+		if err != nil {
+			return
+		}
+
+		s.noreply = false
+		quit := s.execute(args)
+		if quit {
+			return
+		}
+		if !s.reader.Buffered() {
+			if err := s.writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// execute runs a command, returning true if the connection should close.
+func (s *session) execute(args []string) bool {
+
+	if len(args) == 0 {
+		s.reply("ERROR")
+		return false
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "get":
+		s.get(args, false)
+	case "gets":
+		s.get(args, true)
+	case "set", "add", "replace", "cas":
+		return s.set(command, args)
+	case "delete":
+		s.delete(args)
+	case "incr":
+		s.incr(args, false)
+	case "decr":
+		s.incr(args, true)
+	case "touch":
+		s.touch(args)
+	case "stats":
+		s.stats(args)
+	case "version":
+		s.reply("VERSION " + Version)
+	case "quit":
+		return true
+	default:
+		s.reply("ERROR")
+	}
+	return false
+}
+
+func (s *session) reply(line string) {
+	if !s.noreply {
+		s.writer.WriteString(line + "\r\n")
+	}
+}
+
+// trimNoreply strips a trailing noreply from args, remembering it.
+func (s *session) trimNoreply(args []string, count int) []string {
+	if count >= 0 && len(args) == count+1 && args[count] == "noreply" {
+		s.noreply = true
+		return args[:count]
+	}
+	return args
+}
+
+// replyStoreError answers with the error a store request failed with.
+func (s *session) replyStoreError(err error) {
+	if errors.Is(err, common.ErrorValueTooLarge) {
+		s.reply(errorTooLarge)
+		return
+	}
+	if errors.Is(err, common.ErrorUnauthorisedOwner) || errors.Is(err, common.ErrorPermissionDenied) || errors.Is(err, common.ErrorInvalidKey) {
+		s.reply("CLIENT_ERROR " + err.Error())
+		return
+	}
+	s.reply("SERVER_ERROR " + err.Error())
+}
+
+// get takes get <key>*, answering with the keys found. gets adds the entry
+// version as the cas unique.
+func (s *session) get(keys []string, withCas bool) {
+	if len(keys) == 0 {
+		s.reply("ERROR")
+		return
+	}
+	for _, key := range keys {
+		if !IsValidKey(key) {
+			s.reply(errorFormat)
+			return
+		}
+	}
+
+	for _, key := range keys {
+		entry, err := s.server.store.MakeReadRequest(s.server.ctx, key, s.server.owner)
+		if err != nil {
+			continue
+		}
+
+		line := fmt.Sprintf("VALUE %s %d %d", entry.Key, entry.Flags, len(entry.Value))
+		if withCas {
+			line += fmt.Sprintf(" %d", entry.Version)
+		}
+		s.reply(line)
+		s.reply(string(entry.Value))
+	}
+	s.reply("END")
+}
+
+// set takes <command> <key> <flags> <exptime> <bytes> [<cas unique>]
+// [noreply] followed by the data block, returning true if the connection
+// should close because the data block can't be found.
+func (s *session) set(command string, args []string) bool {
+
+	count := 4
+	if command == "cas" {
+		count = 5
+	}
+	args = s.trimNoreply(args, count)
+	if len(args) != count {
+		s.reply("ERROR")
+		return false
+	}
+
+	length, err := strconv.Atoi(args[3])
+	if err != nil || length < 0 {
+		s.reply(errorFormat)
+		return false
+	}
+
+	flags, flagsErr := strconv.ParseUint(args[1], 10, 32)
+	exptime, exptimeErr := strconv.ParseInt(args[2], 10, 64)
+	var unique uint64
+	var uniqueErr error
+	if command == "cas" {
+		unique, uniqueErr = strconv.ParseUint(args[4], 10, 64)
+	}
+
+	if !IsValidKey(args[0]) || flagsErr != nil || exptimeErr != nil || uniqueErr != nil {
+		s.reply(errorFormat)
+		return s.reader.Discard(length) != nil
+	}
+	if length > MaxValueLength {
+		s.reply(errorTooLarge)
+		return s.reader.Discard(length) != nil
+	}
+
+	value, err := s.reader.ReadData(length)
+	if errors.Is(err, ErrorBadDataChunk) {
+		s.reply("CLIENT_ERROR bad data chunk")
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	options := store.PutOptions{TTL: expiry(exptime, time.Now()), Flags: uint32(flags)}
+	switch command {
+	case "add":
+		options.Precondition.IfNoneMatch = &store.VersionMatch{Any: true}
+	case "replace":
+		options.Precondition.IfMatch = &store.VersionMatch{Any: true}
+	case "cas":
+		options.Precondition.IfMatch = &store.VersionMatch{Versions: []uint64{unique}}
+	}
+
+	err = s.server.store.MakePutRequestWithOptions(s.server.ctx, args[0], []byte(value), s.server.owner, options)
+	switch {
+	case err == nil:
+		s.reply("STORED")
+	case errors.Is(err, common.ErrorPreconditionFailed) && command == "cas":
+		if _, err := s.server.store.MakeListRequest(s.server.ctx, args[0], s.server.owner); errors.Is(err, common.ErrorKeyNotFound) {
+			s.reply("NOT_FOUND")
+		} else {
+			s.reply("EXISTS")
+		}
+	case errors.Is(err, common.ErrorPreconditionFailed):
+		s.reply("NOT_STORED")
+	default:
+		s.replyStoreError(err)
+	}
+	return false
+}
+
+// delete takes delete <key> [0] [noreply], the 0 being a leftover of old
+// clients.
+func (s *session) delete(args []string) {
+	if len(args) == 0 {
+		s.reply(errorFormat)
+		return
+	}
+	// a lone noreply is the key, as there must be one
+	if len(args) > 1 {
+		args = s.trimNoreply(args, len(args)-1)
+	}
+	if len(args) == 2 && args[1] == "0" {
+		args = args[:1]
+	}
+	if len(args) != 1 {
+		s.reply(errorFormat)
+		return
+	}
+
+	err := s.server.store.MakeDeleteRequest(s.server.ctx, args[0], s.server.owner)
+	switch {
+	case err == nil:
+		s.reply("DELETED")
+	case errors.Is(err, common.ErrorKeyNotFound):
+		s.reply("NOT_FOUND")
+	default:
+		s.replyStoreError(err)
+	}
+}
+
+// incr takes incr|decr <key> <delta> [noreply]. Values are unsigned 64 bit
+// integers that wrap on increment and stop at 0 on decrement, and the write
+// is retried if the key changes in between reading and writing it.
+func (s *session) incr(args []string, decrement bool) {
+	args = s.trimNoreply(args, 2)
+	if len(args) != 2 {
+		s.reply("ERROR")
+		return
+	}
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		s.reply(errorDelta)
+		return
+	}
+
+	for {
+		entry, err := s.server.store.MakeListRequest(s.server.ctx, args[0], s.server.owner)
+		if errors.Is(err, common.ErrorKeyNotFound) {
+			s.reply("NOT_FOUND")
+			return
+		}
+		if err != nil {
+			s.replyStoreError(err)
+			return
+		}
+
+		value, err := strconv.ParseUint(string(entry.Value), 10, 64)
+		if err != nil {
+			s.reply(errorNonNumeric)
+			return
+		}
+		switch {
+		case !decrement:
+			value += delta
+		case delta > value:
+			value = 0
+		default:
+			value -= delta
+		}
+
+		// keep the flags and expiry time
+		options := store.PutOptions{Flags: entry.Flags}
+		options.Precondition.IfMatch = &store.VersionMatch{Versions: []uint64{entry.Version}}
+		if !entry.Expires.IsZero() {
+			if options.TTL = time.Until(entry.Expires); options.TTL <= 0 {
+				options.TTL = expired
+			}
+		}
+
+		result := strconv.FormatUint(value, 10)
+		err = s.server.store.MakePutRequestWithOptions(s.server.ctx, args[0], []byte(result), s.server.owner, options)
+		if errors.Is(err, common.ErrorPreconditionFailed) {
+			continue
+		}
+		if err != nil {
+			s.replyStoreError(err)
+			return
+		}
+
+		s.reply(result)
+		return
+	}
+}
+
+// touch takes touch <key> <exptime> [noreply].
+func (s *session) touch(args []string) {
+	args = s.trimNoreply(args, 2)
+	if len(args) != 2 {
+		s.reply("ERROR")
+		return
+	}
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		s.reply(errorFormat)
+		return
+	}
+
+	err = s.server.store.MakeExpireRequest(s.server.ctx, args[0], s.server.owner, expiry(exptime, time.Now()))
+	switch {
+	case err == nil:
+		s.reply("TOUCHED")
+	case errors.Is(err, common.ErrorKeyNotFound):
+		s.reply("NOT_FOUND")
+	default:
+		s.replyStoreError(err)
+	}
+}
+
+// stats answers with the general statistics, named as memcached names them
+// where the store keeps an equivalent.
+func (s *session) stats(args []string) {
+	if len(args) != 0 {
+		s.reply("CLIENT_ERROR unsupported stats group")
+		return
+	}
+
+	stats, err := s.server.store.MakeStatsRequest(s.server.ctx)
+	if err != nil {
+		s.replyStoreError(err)
+		return
+	}
+	open, total := s.server.connectionCounts()
+	now := time.Now()
+
+	lines := []struct {
+		name  string
+		value any
+	}{
+		{"pid", os.Getpid()},
+		{"uptime", int64(now.Sub(stats.Started).Seconds())},
+		{"time", now.Unix()},
+		{"version", Version},
+		{"curr_connections", open},
+		{"total_connections", total},
+		{"cmd_get", stats.Gets},
+		{"cmd_set", stats.Puts},
+		{"get_hits", stats.Hits},
+		{"get_misses", stats.Misses},
+		{"delete_hits", stats.Deletes},
+		{"evictions", stats.Evictions},
+		{"expirations", stats.Expirations},
+		{"curr_items", stats.Keys},
+		{"bytes", stats.Bytes},
+		{"limit_maxbytes", stats.MaxBytes},
+	}
+	for _, line := range lines {
+		s.reply(fmt.Sprintf("STAT %s %v", line.name, line.value))
+	}
+	s.reply("END")
+}
+
+// expiry converts a memcached expiry time, either seconds from now or a Unix
+// time, to a TTL.
+func expiry(exptime int64, now time.Time) time.Duration {
+	switch {
+	case exptime == 0:
+		return 0
+	case exptime < 0:
+		return expired
+	case exptime <= maxRelativeExpiry:
+		return time.Duration(exptime) * time.Second
+	}
+
+	if ttl := time.Unix(exptime, 0).Sub(now); ttl > 0 {
+		return ttl
+	}
+	return expired
+}
@@ -0,0 +1,83 @@
+// Package memcached serves the store over the memcached text protocol so
+// existing memcached clients can use it unchanged.
+package memcached
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// MaxKeyLength is the longest key memcached accepts.
+const MaxKeyLength = 250
+
+// MaxValueLength limits a stored value to memcached's default item size.
+const MaxValueLength = 1 << 20
+
+var ErrorLineTooLong = errors.New("line too long")
+var ErrorBadDataChunk = errors.New("bad data chunk")
+
+// Reader reads command lines and the data blocks that follow storage commands.
+type Reader struct {
+	reader *bufio.Reader
+}
+
+func NewReader(reader io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReader(reader)}
+}
+
+// Buffered reports whether another command may already be waiting, so
+// replies to pipelined commands can be flushed together.
+func (r *Reader) Buffered() bool {
+	return r.reader.Buffered() > 0
+}
+
+// ReadLine returns the space separated words of the next command line, which
+// must fit in the read buffer.
+func (r *Reader) ReadLine() ([]string, error) {
+	line, err := r.reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, ErrorLineTooLong
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(line)), nil
+}
+
+// ReadData reads a data block of length bytes and the \r\n ending it. A
+// block of the wrong length is skipped up to the end of its line.
+func (r *Reader) ReadData(length int) (string, error) {
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		return "", err
+	}
+	if data[length] != '\r' || data[length+1] != '\n' {
+		if data[length+1] != '\n' {
+			r.reader.ReadSlice('\n')
+		}
+		return "", ErrorBadDataChunk
+	}
+	return string(data[:length]), nil
+}
+
+// Discard skips a data block that won't be stored.
+func (r *Reader) Discard(length int) error {
+	_, err := r.reader.Discard(length + 2)
+	return err
+}
+
+// IsValidKey reports whether key is short enough and free of control
+// characters, which would break the line based protocol.
+func IsValidKey(key string) bool {
+	if len(key) == 0 || len(key) > MaxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package memcached_test
+
+import (
+	"demo-store/memcached"
+	"strings"
+	"testing"
+)
+
+func TestIsValidKey(t *testing.T) {
+
+	tests := []struct {
+		key      string
+		expected bool
+	}{
+		{"key1", true},
+		{"users/alice", true},
+		{"", false},
+		{"with space", false},
+		{"tab\tkey", false},
+		{strings.Repeat("k", memcached.MaxKeyLength), true},
+		{strings.Repeat("k", memcached.MaxKeyLength+1), false},
+	}
+
+	for _, test := range tests {
+		if valid := memcached.IsValidKey(test.key); valid != test.expected {
+			t.Errorf("Returned unexpected validity of %q: got %v want %v", test.key, valid, test.expected)
+		}
+	}
+}
+
+func TestReaderReadsLinesAndData(t *testing.T) {
+
+	reader := memcached.NewReader(strings.NewReader("set key1 0 0 6\r\nvalue1\r\nget  key1\r\n"))
+
+	args, err := reader.ReadLine()
+	if err != nil || strings.Join(args, ",") != "set,key1,0,0,6" {
+		t.Errorf("Returned unexpected line: got %v, %v want %v", args, err, "set,key1,0,0,6")
+	}
+	data, err := reader.ReadData(6)
+	if err != nil || data != "value1" {
+		t.Errorf("Returned unexpected data: got %v, %v want %v", data, err, "value1")
+	}
+	args, err = reader.ReadLine()
+	if err != nil || strings.Join(args, ",") != "get,key1" {
+		t.Errorf("Returned unexpected line: got %v, %v want %v", args, err, "get,key1")
+	}
+}
+
+func TestReaderRejectsBadDataChunk(t *testing.T) {
+
+	reader := memcached.NewReader(strings.NewReader("value1\r\nget key1\r\n"))
+
+	if _, err := reader.ReadData(3); err != memcached.ErrorBadDataChunk {
+		t.Errorf("Returned unexpected error: got %v want %v", err, memcached.ErrorBadDataChunk)
+	}
+	args, _ := reader.ReadLine()
+	if strings.Join(args, ",") != "get,key1" {
+		t.Errorf("Returned unexpected line: got %v want %v", args, "get,key1")
+	}
+}
+
+func TestReaderRejectsLongLines(t *testing.T) {
+
+	reader := memcached.NewReader(strings.NewReader("get " + strings.Repeat("k", 8192) + "\r\n"))
+
+	if _, err := reader.ReadLine(); err != memcached.ErrorLineTooLong {
+		t.Errorf("Returned unexpected error: got %v want %v", err, memcached.ErrorLineTooLong)
+	}
+}
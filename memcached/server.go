@@ -0,0 +1,129 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Server accepts memcached connections until it is closed. The protocol has
+// no authentication, so every key is read and written as a single user.
+type Server struct {
+	Tracer   utils.Tracer
+	store    store.Store
+	owner    string
+	listener net.Listener
+
+	mutex            sync.Mutex
+	connections      map[net.Conn]bool
+	totalConnections uint64
+	closed           bool
+	group            sync.WaitGroup
+
+	// ctx is cancelled on Close so sessions stop waiting for the store
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ErrorPrivilegedOwner is returned by Listen for an owner allowed to override
+// other users, which would let anyone who can reach the port do the same.
+var ErrorPrivilegedOwner = errors.New("memcached user may not override other users")
+
+// Listen starts serving the store on address, such as ":11211", writing keys
+// as owner.
+func Listen(tracer utils.Tracer, kvStore store.Store, address string, owner string) (*Server, error) {
+
+	if kvStore.UserDatabase().HasPermission(owner, users.PermissionOverride) {
+		return nil, ErrorPrivilegedOwner
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{Tracer: tracer, store: kvStore, owner: owner, listener: listener, connections: make(map[net.Conn]bool)}
+	server.ctx, server.cancel = context.WithCancel(context.Background())
+	tracer.LogInfo("Memcached Server Listening ", listener.Addr())
+
+	server.group.Add(1)
+	go server.accept()
+
+	return server, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops accepting connections, closes the open ones and waits for
+// their sessions to finish.
+func (s *Server) Close() error {
+
+	s.mutex.Lock()
+	s.closed = true
+	err := s.listener.Close()
+	for conn := range s.connections {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+	s.cancel()
+
+	s.group.Wait()
+	return err
+}
+
+// connectionCounts returns the open and the total number of connections.
+func (s *Server) connectionCounts() (int, uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.connections), s.totalConnections
+}
+
+func (s *Server) accept() {
+	defer s.group.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.Tracer.LogError("Memcached accept error: ", err)
+			}
+			return
+		}
+
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		s.connections[conn] = true
+		s.totalConnections++
+		s.group.Add(1)
+		s.mutex.Unlock()
+
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		s.mutex.Lock()
+		delete(s.connections, conn)
+		s.mutex.Unlock()
+
+		conn.Close()
+		s.group.Done()
+	}()
+
+	s.Tracer.LogInfo("Memcached connection from ", conn.RemoteAddr())
+	session := &session{server: s, reader: NewReader(conn), writer: bufio.NewWriter(conn)}
+	session.serve()
+}
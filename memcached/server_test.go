@@ -0,0 +1,264 @@
+package memcached_test
+
+import (
+	"bufio"
+	"context"
+	"demo-store/memcached"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockTracer struct {
+}
+
+func (h *MockTracer) LogInfo(message ...any) {
+}
+
+func (h *MockTracer) LogError(message ...any) {
+}
+
+func (h *MockTracer) LogWarning(message ...any) {
+}
+
+func (h *MockTracer) Close() {
+}
+
+func CreateMockTracer() utils.Tracer {
+	return &MockTracer{}
+}
+
+const owner = "memcached"
+
+type mockClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// startMockServer serves a new store and returns a client connected to it.
+func startMockServer(t *testing.T) (*mockClient, *store.KvStore) {
+
+	kvStore := store.CreateKvStore(CreateMockTracer(), users.CreateUserDatabase(), 0)
+	server, err := memcached.Listen(CreateMockTracer(), kvStore, "127.0.0.1:0", owner)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	t.Cleanup(func() { server.Close() })
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &mockClient{conn: conn, reader: bufio.NewReader(conn)}, kvStore
+}
+
+// do sends request and reads lines back until one of them starts with a word
+// that ends a reply, returning them joined with |.
+func (c *mockClient) do(t *testing.T, request string) string {
+	c.conn.SetDeadline(time.Now().Add(time.Second))
+	if _, err := c.conn.Write([]byte(request)); err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	var lines []string
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		line = strings.TrimSuffix(line, "\r\n")
+		lines = append(lines, line)
+
+		if !strings.HasPrefix(line, "VALUE ") && !strings.HasPrefix(line, "STAT ") &&
+			(len(lines) < 2 || !strings.HasPrefix(lines[len(lines)-2], "VALUE ")) {
+			return strings.Join(lines, "|")
+		}
+	}
+}
+
+type exchange struct {
+	request  string
+	expected string
+}
+
+func runExchanges(t *testing.T, client *mockClient, exchanges []exchange) {
+	for _, e := range exchanges {
+		if reply := client.do(t, e.request); reply != e.expected {
+			t.Errorf("Returned unexpected reply to %q: got %v want %v", e.request, reply, e.expected)
+		}
+	}
+}
+
+func TestStorageCommands(t *testing.T) {
+
+	client, _ := startMockServer(t)
+
+	runExchanges(t, client, []exchange{
+		{"set key1 5 0 6\r\nvalue1\r\n", "STORED"},
+		{"get key1\r\n", "VALUE key1 5 6|value1|END"},
+		{"add key1 0 0 6\r\nvalue2\r\n", "NOT_STORED"},
+		{"replace key2 0 0 6\r\nvalue2\r\n", "NOT_STORED"},
+		{"add key2 0 0 6\r\nvalue2\r\n", "STORED"},
+		{"replace key2 7 0 3\r\nnew\r\n", "STORED"},
+		{"get key1 key3 key2\r\n", "VALUE key1 5 6|value1|VALUE key2 7 3|new|END"},
+		{"set key1 0 0 6 noreply\r\nvalue3\r\nget key1\r\n", "VALUE key1 0 6|value3|END"},
+		{"set key1 0 0 2\r\nvalue\r\n", "CLIENT_ERROR bad data chunk"},
+		{"set key1 x 0 2\r\nab\r\n", "CLIENT_ERROR bad command line format"},
+		{"set key1 0 0\r\n", "ERROR"},
+		{"flush_all\r\n", "ERROR"},
+	})
+}
+
+func TestCasUsesEntryVersion(t *testing.T) {
+
+	client, kvStore := startMockServer(t)
+	client.do(t, "set key1 0 0 6\r\nvalue1\r\n")
+	entry, _ := kvStore.MakeListRequest(context.Background(), "key1", owner)
+	version := entry.Version
+
+	runExchanges(t, client, []exchange{
+		{"gets key1\r\n", "VALUE key1 0 6 " + uitoa(version) + "|value1|END"},
+		{"cas key1 0 0 6 " + uitoa(version+1) + "\r\nvalue2\r\n", "EXISTS"},
+		{"cas key1 0 0 6 " + uitoa(version) + "\r\nvalue2\r\n", "STORED"},
+		{"cas key1 0 0 6 " + uitoa(version) + "\r\nvalue3\r\n", "EXISTS"},
+		{"cas key2 0 0 6 1\r\nvalue2\r\n", "NOT_FOUND"},
+		{"get key1\r\n", "VALUE key1 0 6|value2|END"},
+	})
+}
+
+func TestDeleteAndOwnership(t *testing.T) {
+
+	client, kvStore := startMockServer(t)
+	kvStore.MakePutRequest(context.Background(), "key2", "value2", "user2")
+
+	runExchanges(t, client, []exchange{
+		{"set key1 0 0 6\r\nvalue1\r\n", "STORED"},
+		{"delete key1\r\n", "DELETED"},
+		{"delete key1\r\n", "NOT_FOUND"},
+		{"delete key2\r\n", "CLIENT_ERROR Owner not authorised to update value"},
+		{"delete\r\n", "CLIENT_ERROR bad command line format"},
+		{"delete noreply\r\n", "NOT_FOUND"},
+		{"set key2 0 0 6\r\nvalue1\r\n", "CLIENT_ERROR Owner not authorised to update value"},
+	})
+
+	entry, _ := kvStore.MakeListRequest(context.Background(), "key2", owner)
+	if entry == nil || string(entry.Value) != "value2" {
+		t.Errorf("Returned unexpected entry: got %v want %v", entry, "value2")
+	}
+}
+
+func TestIncrAndDecr(t *testing.T) {
+
+	client, kvStore := startMockServer(t)
+
+	runExchanges(t, client, []exchange{
+		{"incr counter 1\r\n", "NOT_FOUND"},
+		{"set counter 3 100 2\r\n10\r\n", "STORED"},
+		{"incr counter 5\r\n", "15"},
+		{"decr counter 20\r\n", "0"},
+		{"set counter 0 0 20\r\n18446744073709551615\r\n", "STORED"},
+		{"incr counter 2\r\n", "1"},
+		{"incr counter x\r\n", "CLIENT_ERROR invalid numeric delta argument"},
+		{"set word 0 0 4\r\nword\r\n", "STORED"},
+		{"incr word 1\r\n", "CLIENT_ERROR cannot increment or decrement non-numeric value"},
+	})
+
+	client.do(t, "set counter 3 100 2\r\n10\r\n")
+	client.do(t, "incr counter 1\r\n")
+	entry, _ := kvStore.MakeListRequest(context.Background(), "counter", owner)
+	if entry.Flags != 3 || entry.Expires.IsZero() {
+		t.Errorf("Returned unexpected flags and expiry: got %v/%v want %v/%v", entry.Flags, entry.Expires, 3, "set")
+	}
+}
+
+func TestExpiryTimes(t *testing.T) {
+
+	client, kvStore := startMockServer(t)
+	future := uitoa(uint64(time.Now().Add(time.Hour).Unix()))
+
+	runExchanges(t, client, []exchange{
+		{"set key1 0 -1 6\r\nvalue1\r\n", "STORED"},
+		{"get key1\r\n", "END"},
+		{"set key1 0 " + future + " 6\r\nvalue1\r\n", "STORED"},
+		{"set key2 0 0 6\r\nvalue2\r\n", "STORED"},
+		{"touch key2 100\r\n", "TOUCHED"},
+		{"touch key3 100\r\n", "NOT_FOUND"},
+	})
+
+	for _, key := range []string{"key1", "key2"} {
+		entry, _ := kvStore.MakeListRequest(context.Background(), key, owner)
+		if entry == nil || entry.TTL <= 0 || entry.TTL > int64(time.Hour/time.Millisecond) {
+			t.Errorf("Returned unexpected entry: got %v want a TTL", entry)
+		}
+	}
+
+	client.do(t, "touch key2 -1\r\n")
+	if reply := client.do(t, "get key2\r\n"); reply != "END" {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "END")
+	}
+}
+
+func TestStatsReportStoreCounters(t *testing.T) {
+
+	client, _ := startMockServer(t)
+	client.do(t, "set key1 0 0 6\r\nvalue1\r\n")
+	client.do(t, "get key1 key2\r\n")
+
+	stats := map[string]string{}
+	for _, line := range strings.Split(client.do(t, "stats\r\n"), "|") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 {
+			stats[fields[1]] = fields[2]
+		}
+	}
+
+	size := (&store.Entry{Key: "key1", Value: []byte("value1"), Owner: owner}).Size()
+	expected := map[string]string{
+		"cmd_get": "2", "cmd_set": "1", "get_hits": "1", "get_misses": "1",
+		"curr_items": "1", "bytes": strconv.FormatInt(size, 10), "limit_maxbytes": "0",
+		"curr_connections": "1", "version": memcached.Version,
+	}
+	for name, value := range expected {
+		if stats[name] != value {
+			t.Errorf("Returned unexpected %v: got %v want %v", name, stats[name], value)
+		}
+	}
+}
+
+func TestQuitClosesConnection(t *testing.T) {
+
+	client, _ := startMockServer(t)
+	if reply := client.do(t, "version\r\n"); reply != "VERSION "+memcached.Version {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "VERSION "+memcached.Version)
+	}
+
+	client.conn.Write([]byte("quit\r\n"))
+	if _, err := client.reader.ReadByte(); err == nil {
+		t.Errorf("Returned unexpected open connection after quit")
+	}
+}
+
+func uitoa(value uint64) string {
+	return strconv.FormatUint(value, 10)
+}
+
+func TestListenRefusesPrivilegedOwner(t *testing.T) {
+
+	users.PasswordCost = bcrypt.MinCost
+	userDatabase := users.CreateUserDatabase()
+	userDatabase.AddUser("admin", "admin")
+	kvStore := store.CreateKvStore(CreateMockTracer(), userDatabase, 0)
+
+	if _, err := memcached.Listen(CreateMockTracer(), kvStore, "127.0.0.1:0", "admin"); err != memcached.ErrorPrivilegedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, memcached.ErrorPrivilegedOwner)
+	}
+}
@@ -0,0 +1,413 @@
+package resp
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultScanCount is how many keys SCAN looks at when no COUNT is given.
+const DefaultScanCount = 10
+
+// maxCursors limits the SCAN cursors a connection keeps. Redis clients expect
+// numeric cursors, so each session maps them to the store's opaque ones.
+const maxCursors = 1024
+
+const (
+	errorNoAuth    = "NOAUTH Authentication required."
+	errorWrongPass = "WRONGPASS invalid username-password pair or user is disabled."
+	errorSyntax    = "ERR syntax error"
+	errorInteger   = "ERR value is not an integer or out of range"
+	errorCursor    = "ERR invalid cursor"
+)
+
+type session struct {
+	ctx      context.Context
+	store    store.Store
+	reader   *Reader
+	writer   *Writer
+	username string
+
+	cursors    map[uint64]string
+	nextCursor uint64
+}
+
+func newSession(ctx context.Context, kvStore store.Store, reader *Reader, writer *Writer) *session {
+	return &session{ctx: ctx, store: kvStore, reader: reader, writer: writer, cursors: make(map[uint64]string)}
+}
+
+func (s *session) serve() {
+	for {
+		args, err := s.reader.ReadCommand()
+		if err != nil {
+			if errors.Is(err, ErrorProtocol) {
+				s.writer.WriteError("ERR Protocol error")
+				s.writer.Flush()
+			}
+			return
+		}
+
+		quit := s.execute(args)
+		if !s.reader.Buffered() || quit {
+			if err := s.writer.Flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// execute runs a command, returning true if the connection should close.
+func (s *session) execute(args []string) bool {
+
+	name := strings.ToUpper(args[0])
+	args = args[1:]
+
+	switch name {
+	case "PING":
+		if len(args) > 0 {
+			s.writer.WriteBulkString(args[0])
+		} else {
+			s.writer.WriteSimpleString("PONG")
+		}
+		return false
+
+	case "QUIT":
+		s.writer.WriteSimpleString("OK")
+		return true
+
+	case "AUTH":
+		s.auth(args)
+		return false
+	}
+
+	if s.username == "" {
+		s.writer.WriteError(errorNoAuth)
+		return false
+	}
+
+	switch name {
+	case "SELECT":
+		// there is only the one database
+		if len(args) != 1 || args[0] != "0" {
+			s.writer.WriteError("ERR DB index is out of range")
+		} else {
+			s.writer.WriteSimpleString("OK")
+		}
+	case "GET":
+		s.get(args)
+	case "SET":
+		s.set(args)
+	case "DEL":
+		s.del(args)
+	case "EXISTS":
+		s.exists(args)
+	case "KEYS":
+		s.keys(args)
+	case "SCAN":
+		s.scan(args)
+	case "EXPIRE":
+		s.expire(args)
+	case "TTL":
+		s.ttl(args)
+	default:
+		s.writer.WriteError(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name)))
+	}
+	return false
+}
+
+func (s *session) wrongArguments(command string) {
+	s.writer.WriteError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", command))
+}
+
+// writeStoreError replies with the error a store request failed with.
+func (s *session) writeStoreError(err error) {
+	if errors.Is(err, common.ErrorUnauthorisedOwner) || errors.Is(err, common.ErrorPermissionDenied) {
+		s.writer.WriteError("NOPERM " + err.Error())
+		return
+	}
+	s.writer.WriteError("ERR " + err.Error())
+}
+
+// auth takes AUTH username password. The single argument form, which logs in
+// as Redis' default user, isn't supported since every key has an owner.
+func (s *session) auth(args []string) {
+	if len(args) != 2 {
+		s.wrongArguments("auth")
+		return
+	}
+
+	if err := s.store.UserDatabase().Authenticate(args[0], args[1]); err != nil {
+		s.writer.WriteError(errorWrongPass)
+		return
+	}
+
+	s.username = args[0]
+	s.writer.WriteSimpleString("OK")
+}
+
+func (s *session) get(args []string) {
+	if len(args) != 1 {
+		s.wrongArguments("get")
+		return
+	}
+
+	value, err := s.store.MakeGetRequest(s.ctx, args[0], s.username)
+	switch {
+	case errors.Is(err, common.ErrorKeyNotFound):
+		s.writer.WriteNull()
+	case err != nil:
+		s.writeStoreError(err)
+	default:
+		s.writer.WriteBulkString(value)
+	}
+}
+
+// set takes SET key value [EX seconds | PX milliseconds] [NX | XX], replying
+// with a null when NX or XX stop the write as Redis does.
+func (s *session) set(args []string) {
+	if len(args) < 2 {
+		s.wrongArguments("set")
+		return
+	}
+	key, value := args[0], args[1]
+
+	var options store.PutOptions
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			if options.Precondition.IfMatch != nil {
+				s.writer.WriteError(errorSyntax)
+				return
+			}
+			options.Precondition.IfNoneMatch = &store.VersionMatch{Any: true}
+		case "XX":
+			if options.Precondition.IfNoneMatch != nil {
+				s.writer.WriteError(errorSyntax)
+				return
+			}
+			options.Precondition.IfMatch = &store.VersionMatch{Any: true}
+		case "EX", "PX":
+			if options.TTL != 0 || i+1 == len(args) {
+				s.writer.WriteError(errorSyntax)
+				return
+			}
+			amount, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				s.writer.WriteError(errorInteger)
+				return
+			}
+			if amount <= 0 {
+				s.writer.WriteError("ERR invalid expire time in 'set' command")
+				return
+			}
+			unit := time.Second
+			if strings.ToUpper(args[i]) == "PX" {
+				unit = time.Millisecond
+			}
+			options.TTL = time.Duration(amount) * unit
+			i++
+		default:
+			s.writer.WriteError(errorSyntax)
+			return
+		}
+	}
+
+	err := s.store.MakePutRequestWithOptions(s.ctx, key, []byte(value), s.username, options)
+	switch {
+	case errors.Is(err, common.ErrorPreconditionFailed):
+		s.writer.WriteNull()
+	case err != nil:
+		s.writeStoreError(err)
+	default:
+		s.writer.WriteSimpleString("OK")
+	}
+}
+
+// del deletes the keys in order, stopping at the first the user doesn't own.
+func (s *session) del(args []string) {
+	if len(args) == 0 {
+		s.wrongArguments("del")
+		return
+	}
+
+	deleted := int64(0)
+	for _, key := range args {
+		err := s.store.MakeDeleteRequest(s.ctx, key, s.username)
+		switch {
+		case errors.Is(err, common.ErrorKeyNotFound):
+		case err != nil:
+			s.writeStoreError(err)
+			return
+		default:
+			deleted++
+		}
+	}
+	s.writer.WriteInteger(deleted)
+}
+
+func (s *session) exists(args []string) {
+	if len(args) == 0 {
+		s.wrongArguments("exists")
+		return
+	}
+
+	found := int64(0)
+	for _, key := range args {
+		if _, err := s.store.MakeListRequest(s.ctx, key, s.username); err == nil {
+			found++
+		}
+	}
+	s.writer.WriteInteger(found)
+}
+
+func (s *session) keys(args []string) {
+	if len(args) != 1 {
+		s.wrongArguments("keys")
+		return
+	}
+
+	result, err := s.store.MakeScanRequest(s.ctx, store.ScanQuery{Prefix: literalPrefix(args[0]), Reader: s.username})
+	if err != nil {
+		s.writeStoreError(err)
+		return
+	}
+	s.writer.WriteBulkStrings(matchingKeys(result.Entries, args[0]))
+}
+
+// scan takes SCAN cursor [MATCH pattern] [COUNT count]. Like Redis it may
+// return fewer than count keys, or none, before the cursor reaches 0.
+func (s *session) scan(args []string) {
+	if len(args) == 0 {
+		s.wrongArguments("scan")
+		return
+	}
+
+	query := store.ScanQuery{Limit: DefaultScanCount}
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		s.writer.WriteError(errorCursor)
+		return
+	}
+	if cursor != 0 {
+		storeCursor, ok := s.cursors[cursor]
+		if !ok {
+			s.writer.WriteError(errorCursor)
+			return
+		}
+		query.Cursor = storeCursor
+	}
+
+	pattern := "*"
+	for i := 1; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			s.writer.WriteError(errorSyntax)
+			return
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, err := strconv.Atoi(args[i+1])
+			if err != nil || count < 1 {
+				s.writer.WriteError(errorInteger)
+				return
+			}
+			query.Limit = count
+		default:
+			s.writer.WriteError(errorSyntax)
+			return
+		}
+	}
+	query.Prefix = literalPrefix(pattern)
+	query.Reader = s.username
+
+	result, err := s.store.MakeScanRequest(s.ctx, query)
+	if err != nil {
+		s.writeStoreError(err)
+		return
+	}
+
+	delete(s.cursors, cursor)
+	next := "0"
+	if result.NextCursor != "" {
+		next = strconv.FormatUint(s.saveCursor(result.NextCursor), 10)
+	}
+	s.writer.WriteArray(2)
+	s.writer.WriteBulkString(next)
+	s.writer.WriteBulkStrings(matchingKeys(result.Entries, pattern))
+}
+
+func (s *session) saveCursor(storeCursor string) uint64 {
+	if len(s.cursors) >= maxCursors {
+		// abandoned scans, start afresh
+		s.cursors = make(map[uint64]string)
+	}
+	s.nextCursor++
+	s.cursors[s.nextCursor] = storeCursor
+	return s.nextCursor
+}
+
+func matchingKeys(entries []*store.Entry, pattern string) []string {
+	keys := []string{}
+	for _, entry := range entries {
+		if MatchPattern(pattern, entry.Key) {
+			keys = append(keys, entry.Key)
+		}
+	}
+	return keys
+}
+
+// expire takes EXPIRE key seconds, deleting the key if seconds isn't
+// positive as Redis does.
+func (s *session) expire(args []string) {
+	if len(args) != 2 {
+		s.wrongArguments("expire")
+		return
+	}
+
+	seconds, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		s.writer.WriteError(errorInteger)
+		return
+	}
+
+	if seconds <= 0 {
+		err = s.store.MakeDeleteRequest(s.ctx, args[0], s.username)
+	} else {
+		err = s.store.MakeExpireRequest(s.ctx, args[0], s.username, time.Duration(seconds)*time.Second)
+	}
+	switch {
+	case errors.Is(err, common.ErrorKeyNotFound):
+		s.writer.WriteInteger(0)
+	case err != nil:
+		s.writeStoreError(err)
+	default:
+		s.writer.WriteInteger(1)
+	}
+}
+
+// ttl replies with the seconds left, -1 for a key that never expires and -2
+// for a missing key.
+func (s *session) ttl(args []string) {
+	if len(args) != 1 {
+		s.wrongArguments("ttl")
+		return
+	}
+
+	entry, err := s.store.MakeListRequest(s.ctx, args[0], s.username)
+	switch {
+	case errors.Is(err, common.ErrorKeyNotFound):
+		s.writer.WriteInteger(-2)
+	case err != nil:
+		s.writeStoreError(err)
+	case entry.Expires.IsZero():
+		s.writer.WriteInteger(-1)
+	default:
+		s.writer.WriteInteger((entry.TTL + 500) / 1000)
+	}
+}
@@ -0,0 +1,104 @@
+package resp
+
+import "strings"
+
+// MatchPattern reports whether key matches a Redis glob pattern: * matches any
+// run of characters, ? any single character, [abc], [^abc] and [a-z] a
+// character class and \ escapes the character after it.
+func MatchPattern(pattern string, key string) bool {
+
+	// on a mismatch after a *, retry with the * consuming one more character
+	starPattern, starKey := -1, -1
+	p, k := 0, 0
+	for k < len(key) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				starPattern, starKey = p, k
+				p++
+				continue
+
+			case '?':
+				p++
+				k++
+				continue
+
+			case '[':
+				if next, ok := matchClass(pattern, p, key[k]); ok {
+					p = next
+					k++
+					continue
+				}
+
+			case '\\':
+				if p+1 < len(pattern) && pattern[p+1] == key[k] {
+					p += 2
+					k++
+					continue
+				}
+
+			default:
+				if pattern[p] == key[k] {
+					p++
+					k++
+					continue
+				}
+			}
+		}
+
+		if starPattern < 0 {
+			return false
+		}
+		starKey++
+		p, k = starPattern+1, starKey
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches c against the class starting at pattern[start], returning
+// the index after the class.
+func matchClass(pattern string, start int, c byte) (int, bool) {
+
+	i := start + 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+
+	matched := false
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			matched = matched || pattern[i] == c
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			low, high := pattern[i], pattern[i+2]
+			if low > high {
+				low, high = high, low
+			}
+			matched = matched || (c >= low && c <= high)
+			i += 2
+		default:
+			matched = matched || pattern[i] == c
+		}
+	}
+	if i == len(pattern) {
+		// an unterminated class never matches
+		return 0, false
+	}
+
+	return i + 1, matched != negate
+}
+
+// literalPrefix is the part of pattern before its first special character,
+// which every matching key starts with.
+func literalPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?[\\"); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
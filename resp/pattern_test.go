@@ -0,0 +1,40 @@
+package resp_test
+
+import (
+	"demo-store/resp"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+
+	tests := []struct {
+		pattern string
+		key     string
+		matches bool
+	}{
+		{"*", "", true},
+		{"*", "users/alice", true},
+		{"users/*", "users/alice", true},
+		{"users/*", "groups/admin", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h*llo", "hello world", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"*a*b", "xaybzb", true},
+		{"h[llo", "hllo", false},
+	}
+
+	for _, test := range tests {
+		if matches := resp.MatchPattern(test.pattern, test.key); matches != test.matches {
+			t.Errorf("Returned unexpected match for %v and %v: got %v want %v", test.pattern, test.key, matches, test.matches)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package resp serves the store over version 2 of the Redis serialization
+// protocol so redis-cli and Redis client libraries can talk to it.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxBulkLength limits a single argument of a command.
+const MaxBulkLength = 64 << 20
+
+// MaxArguments limits the number of arguments of a command.
+const MaxArguments = 1 << 16
+
+var ErrorProtocol = errors.New("Protocol error")
+
+// Reader reads commands sent as arrays of bulk strings or, as typed into a
+// telnet session, inline on a single line.
+type Reader struct {
+	reader *bufio.Reader
+}
+
+func NewReader(reader io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReader(reader)}
+}
+
+// Buffered reports whether another command may already be waiting, so
+// replies to pipelined commands can be flushed together.
+func (r *Reader) Buffered() bool {
+	return r.reader.Buffered() > 0
+}
+
+func (r *Reader) ReadCommand() ([]string, error) {
+
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			continue
+		}
+
+		if line[0] != '*' {
+			// like empty lines, lines of only spaces are skipped
+			if args := strings.Fields(line); len(args) > 0 {
+				return args, nil
+			}
+			continue
+		}
+
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count > MaxArguments {
+			return nil, ErrorProtocol
+		}
+		if count <= 0 {
+			continue
+		}
+
+		args := make([]string, count)
+		for i := range args {
+			if args[i], err = r.readBulkString(); err != nil {
+				return nil, err
+			}
+		}
+		return args, nil
+	}
+}
+
+func (r *Reader) readBulkString() (string, error) {
+
+	line, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return "", ErrorProtocol
+	}
+
+	length, err := strconv.Atoi(line[1:])
+	if err != nil || length < 0 || length > MaxBulkLength {
+		return "", ErrorProtocol
+	}
+
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		return "", err
+	}
+	if data[length] != '\r' || data[length+1] != '\n' {
+		return "", ErrorProtocol
+	}
+	return string(data[:length]), nil
+}
+
+func (r *Reader) readLine() (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// Writer buffers replies until Flush.
+type Writer struct {
+	writer *bufio.Writer
+}
+
+func NewWriter(writer io.Writer) *Writer {
+	return &Writer{writer: bufio.NewWriter(writer)}
+}
+
+func (w *Writer) WriteSimpleString(value string) {
+	w.writer.WriteString("+" + value + "\r\n")
+}
+
+// WriteError writes an error reply. By convention message starts with an
+// upper case error code such as ERR or NOAUTH.
+func (w *Writer) WriteError(message string) {
+	w.writer.WriteString("-" + strings.NewReplacer("\r", " ", "\n", " ").Replace(message) + "\r\n")
+}
+
+func (w *Writer) WriteInteger(value int64) {
+	w.writer.WriteString(":" + strconv.FormatInt(value, 10) + "\r\n")
+}
+
+func (w *Writer) WriteBulkString(value string) {
+	w.writer.WriteString("$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n")
+}
+
+// WriteNull writes the null bulk string Redis uses for missing values.
+func (w *Writer) WriteNull() {
+	w.writer.WriteString("$-1\r\n")
+}
+
+// WriteArray starts an array whose length elements must be written next.
+func (w *Writer) WriteArray(length int) {
+	w.writer.WriteString("*" + strconv.Itoa(length) + "\r\n")
+}
+
+func (w *Writer) WriteBulkStrings(values []string) {
+	w.WriteArray(len(values))
+	for _, value := range values {
+		w.WriteBulkString(value)
+	}
+}
+
+func (w *Writer) Flush() error {
+	return w.writer.Flush()
+}
@@ -0,0 +1,56 @@
+package resp_test
+
+import (
+	"bytes"
+	"demo-store/resp"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadCommand(t *testing.T) {
+
+	input := "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$12\r\nhello\r\nworld\r\n" + "   \r\nPING now\r\n"
+	reader := resp.NewReader(strings.NewReader(input))
+
+	args, err := reader.ReadCommand()
+	if err != nil || fmt.Sprintf("%q", args) != `["SET" "key" "hello\r\nworld"]` {
+		t.Errorf("Returned unexpected command: got %q %v", args, err)
+	}
+
+	args, err = reader.ReadCommand()
+	if err != nil || fmt.Sprint(args) != "[PING now]" {
+		t.Errorf("Returned unexpected inline command: got %q %v", args, err)
+	}
+}
+
+func TestReadCommandRejectsMalformedInput(t *testing.T) {
+
+	inputs := []string{"*1\r\n:3\r\n", "*1\r\n$3\r\nabcd\r\n", "*x\r\n", "*1\r\n$-2\r\n"}
+	for _, input := range inputs {
+		_, err := resp.NewReader(strings.NewReader(input)).ReadCommand()
+		if !errors.Is(err, resp.ErrorProtocol) {
+			t.Errorf("Returned unexpected error for %q: got %v want %v", input, err, resp.ErrorProtocol)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+
+	var buffer bytes.Buffer
+	writer := resp.NewWriter(&buffer)
+
+	writer.WriteSimpleString("OK")
+	writer.WriteError("ERR bad\r\nthing")
+	writer.WriteInteger(-2)
+	writer.WriteBulkString("hi")
+	writer.WriteNull()
+	writer.WriteBulkStrings([]string{"a", ""})
+	writer.Flush()
+
+	expected := "+OK\r\n-ERR bad  thing\r\n:-2\r\n$2\r\nhi\r\n$-1\r\n*2\r\n$1\r\na\r\n$0\r\n\r\n"
+	if buffer.String() != expected {
+		t.Errorf("Returned unexpected output: got %q want %q", buffer.String(), expected)
+	}
+}
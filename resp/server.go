@@ -0,0 +1,106 @@
+package resp
+
+import (
+	"context"
+	"demo-store/store"
+	"demo-store/utils"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Server accepts RESP connections until it is closed.
+type Server struct {
+	Tracer   utils.Tracer
+	store    store.Store
+	listener net.Listener
+
+	mutex       sync.Mutex
+	connections map[net.Conn]bool
+	closed      bool
+	group       sync.WaitGroup
+
+	// ctx is cancelled on Close so sessions stop waiting for the store
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Listen starts serving the store on address, such as ":6379".
+func Listen(tracer utils.Tracer, kvStore store.Store, address string) (*Server, error) {
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{Tracer: tracer, store: kvStore, listener: listener, connections: make(map[net.Conn]bool)}
+	server.ctx, server.cancel = context.WithCancel(context.Background())
+	tracer.LogInfo("RESP Server Listening ", listener.Addr())
+
+	server.group.Add(1)
+	go server.accept()
+
+	return server, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops accepting connections, closes the open ones and waits for
+// their sessions to finish.
+func (s *Server) Close() error {
+
+	s.mutex.Lock()
+	s.closed = true
+	err := s.listener.Close()
+	for conn := range s.connections {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+	s.cancel()
+
+	s.group.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.group.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.Tracer.LogError("RESP accept error: ", err)
+			}
+			return
+		}
+
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		s.connections[conn] = true
+		s.group.Add(1)
+		s.mutex.Unlock()
+
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() {
+		s.mutex.Lock()
+		delete(s.connections, conn)
+		s.mutex.Unlock()
+
+		conn.Close()
+		s.group.Done()
+	}()
+
+	s.Tracer.LogInfo("RESP connection from ", conn.RemoteAddr())
+	session := newSession(s.ctx, s.store, NewReader(conn), NewWriter(conn))
+	session.serve()
+}
@@ -0,0 +1,269 @@
+package resp_test
+
+import (
+	"bufio"
+	"context"
+	"demo-store/resp"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockTracer struct {
+}
+
+func (h *MockTracer) LogInfo(message ...any) {
+}
+
+func (h *MockTracer) LogError(message ...any) {
+}
+
+func (h *MockTracer) LogWarning(message ...any) {
+}
+
+func (h *MockTracer) Close() {
+}
+
+func CreateMockTracer() utils.Tracer {
+	return &MockTracer{}
+}
+
+// NewMockUserDatabase returns the users the tests log in as, with passwords
+// hashed at the lowest cost so logging in stays quick.
+func NewMockUserDatabase() users.UserDatabase {
+	users.PasswordCost = bcrypt.MinCost
+	userDatabase := users.CreateUserDatabase()
+	userDatabase.AddUser("user_a", "pass_a")
+	userDatabase.AddUser("user_b", "pass_b")
+	return userDatabase
+}
+
+type mockClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// startMockServer serves a new store and returns a client connected to it.
+func startMockServer(t *testing.T) (*mockClient, *store.KvStore) {
+
+	kvStore := store.CreateKvStore(CreateMockTracer(), NewMockUserDatabase(), 0)
+	server, err := resp.Listen(CreateMockTracer(), kvStore, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	t.Cleanup(func() { server.Close() })
+
+	return dialMockServer(t, server), kvStore
+}
+
+func dialMockServer(t *testing.T, server *resp.Server) *mockClient {
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &mockClient{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// do sends a command and returns its reply flattened to a string, with
+// arrays as [a b] and nulls as (nil).
+func (c *mockClient) do(t *testing.T, args ...string) string {
+	command := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		command += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	c.conn.SetDeadline(time.Now().Add(time.Second))
+	if _, err := c.conn.Write([]byte(command)); err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	return c.readReply(t)
+}
+
+func (c *mockClient) readReply(t *testing.T) string {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+
+	switch line[0] {
+	case '$':
+		if line == "$-1" {
+			return "(nil)"
+		}
+		var length int
+		fmt.Sscanf(line[1:], "%d", &length)
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		return string(data[:length])
+	case '*':
+		var length int
+		fmt.Sscanf(line[1:], "%d", &length)
+		elements := []string{}
+		for i := 0; i < length; i++ {
+			elements = append(elements, c.readReply(t))
+		}
+		return fmt.Sprint(elements)
+	default:
+		return line
+	}
+}
+
+func TestCommandsRequireAuth(t *testing.T) {
+
+	client, _ := startMockServer(t)
+
+	if reply := client.do(t, "PING"); reply != "+PONG" {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "+PONG")
+	}
+	if reply := client.do(t, "GET", "key1"); !strings.HasPrefix(reply, "-NOAUTH") {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "-NOAUTH")
+	}
+	if reply := client.do(t, "AUTH", "user_a", "wrong"); !strings.HasPrefix(reply, "-WRONGPASS") {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "-WRONGPASS")
+	}
+	if reply := client.do(t, "AUTH", "user_a", "pass_a"); reply != "+OK" {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "+OK")
+	}
+	if reply := client.do(t, "GET", "key1"); reply != "(nil)" {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "(nil)")
+	}
+}
+
+func TestSetGetDelete(t *testing.T) {
+
+	client, kvStore := startMockServer(t)
+	client.do(t, "AUTH", "user_a", "pass_a")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"SET", "key1", "value1"}, "+OK"},
+		{[]string{"GET", "key1"}, "value1"},
+		{[]string{"SET", "key1", "value2", "NX"}, "(nil)"},
+		{[]string{"SET", "key2", "value2", "XX"}, "(nil)"},
+		{[]string{"SET", "key2", "value2", "NX", "EX", "100"}, "+OK"},
+		{[]string{"SET", "key2", "value2", "EX", "0"}, "-ERR invalid expire time in 'set' command"},
+		{[]string{"SET", "key2", "value2", "FOREVER"}, "-ERR syntax error"},
+		{[]string{"EXISTS", "key1", "key2", "key3"}, ":2"},
+		{[]string{"DEL", "key1", "key3"}, ":1"},
+		{[]string{"GET", "key1"}, "(nil)"},
+		{[]string{"get"}, "-ERR wrong number of arguments for 'get' command"},
+		{[]string{"FLUSHALL"}, "-ERR unknown command 'flushall'"},
+	}
+
+	for _, test := range tests {
+		if reply := client.do(t, test.args...); reply != test.expected {
+			t.Errorf("Returned unexpected reply to %v: got %v want %v", test.args, reply, test.expected)
+		}
+	}
+
+	entry, _ := kvStore.MakeListRequest(context.Background(), "key2", "user_a")
+	if entry.Owner != "user_a" {
+		t.Errorf("Returned unexpected owner: got %v want %v", entry.Owner, "user_a")
+	}
+}
+
+func TestOwnershipApplies(t *testing.T) {
+
+	client, kvStore := startMockServer(t)
+	kvStore.MakePutRequest(context.Background(), "key1", "value1", "user_b")
+	client.do(t, "AUTH", "user_a", "pass_a")
+
+	for _, args := range [][]string{{"SET", "key1", "mine"}, {"DEL", "key1"}, {"EXPIRE", "key1", "10"}} {
+		if reply := client.do(t, args...); !strings.HasPrefix(reply, "-NOPERM") {
+			t.Errorf("Returned unexpected reply to %v: got %v want %v", args, reply, "-NOPERM")
+		}
+	}
+	if reply := client.do(t, "GET", "key1"); reply != "value1" {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "value1")
+	}
+}
+
+func TestExpireAndTtl(t *testing.T) {
+
+	client, _ := startMockServer(t)
+	client.do(t, "AUTH", "user_a", "pass_a")
+	client.do(t, "SET", "key1", "value1")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"TTL", "key1"}, ":-1"},
+		{[]string{"TTL", "key2"}, ":-2"},
+		{[]string{"EXPIRE", "key1", "100"}, ":1"},
+		{[]string{"TTL", "key1"}, ":100"},
+		{[]string{"EXPIRE", "key2", "100"}, ":0"},
+		{[]string{"EXPIRE", "key1", "soon"}, "-ERR value is not an integer or out of range"},
+		{[]string{"EXPIRE", "key1", "0"}, ":1"},
+		{[]string{"EXISTS", "key1"}, ":0"},
+	}
+
+	for _, test := range tests {
+		if reply := client.do(t, test.args...); reply != test.expected {
+			t.Errorf("Returned unexpected reply to %v: got %v want %v", test.args, reply, test.expected)
+		}
+	}
+}
+
+func TestKeysAndScan(t *testing.T) {
+
+	client, kvStore := startMockServer(t)
+	for _, key := range []string{"a/1", "a/2", "a/3", "b/1", "ab"} {
+		kvStore.MakePutRequest(context.Background(), key, "value", "user_a")
+	}
+	client.do(t, "AUTH", "user_a", "pass_a")
+
+	if reply := client.do(t, "KEYS", "a*"); reply != "[a/1 a/2 a/3 ab]" {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "[a/1 a/2 a/3 ab]")
+	}
+	if reply := client.do(t, "KEYS", "?/1"); reply != "[a/1 b/1]" {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "[a/1 b/1]")
+	}
+
+	var keys []string
+	cursor := "0"
+	for {
+		reply := client.do(t, "SCAN", cursor, "MATCH", "a/*", "COUNT", "2")
+		fields := strings.Fields(strings.NewReplacer("[", " ", "]", " ").Replace(reply))
+		cursor = fields[0]
+		keys = append(keys, fields[1:]...)
+		if cursor == "0" {
+			break
+		}
+	}
+	if fmt.Sprint(keys) != "[a/1 a/2 a/3]" {
+		t.Errorf("Returned unexpected keys: got %v want %v", keys, "[a/1 a/2 a/3]")
+	}
+
+	if reply := client.do(t, "SCAN", "99"); reply != "-ERR invalid cursor" {
+		t.Errorf("Returned unexpected reply: got %v want %v", reply, "-ERR invalid cursor")
+	}
+}
+
+func TestPipelinedAndInlineCommands(t *testing.T) {
+
+	client, _ := startMockServer(t)
+	client.conn.Write([]byte("   \r\nAUTH user_a pass_a\r\nSET key1 value1\r\nGET key1\r\nQUIT\r\n"))
+
+	for _, expected := range []string{"+OK", "+OK", "value1", "+OK"} {
+		if reply := client.readReply(t); reply != expected {
+			t.Errorf("Returned unexpected reply: got %v want %v", reply, expected)
+		}
+	}
+	if _, err := client.reader.ReadByte(); err == nil {
+		t.Errorf("Returned unexpected open connection after QUIT")
+	}
+}
@@ -0,0 +1,63 @@
+// Package rpc serves the store over gRPC, implementing the service defined in
+// storepb/store.proto.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative storepb/store.proto
+
+import (
+	"demo-store/endpoints"
+	"demo-store/rpc/storepb"
+	"demo-store/store"
+	"demo-store/utils"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// Server serves the Store gRPC service until it is closed.
+type Server struct {
+	storepb.UnimplementedStoreServer
+
+	Tracer        utils.Tracer
+	store         store.Store
+	authenticator endpoints.Authenticator
+	tokenizer     utils.Tokenizer
+	grpcServer    *grpc.Server
+	listener      net.Listener
+}
+
+// Listen starts serving the store on address, such as ":9090".
+func Listen(tracer utils.Tracer, kvStore store.Store, authenticator endpoints.Authenticator, address string) (*Server, error) {
+	return ListenWithTokenizer(tracer, kvStore, authenticator, utils.NewJwtTokenizer(tracer), address)
+}
+
+func ListenWithTokenizer(tracer utils.Tracer, kvStore store.Store, authenticator endpoints.Authenticator, tokenizer utils.Tokenizer, address string) (*Server, error) {
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{Tracer: tracer, store: kvStore, authenticator: authenticator, tokenizer: tokenizer, listener: listener}
+	server.grpcServer = grpc.NewServer()
+	storepb.RegisterStoreServer(server.grpcServer, server)
+	tracer.LogInfo("gRPC Server Listening ", listener.Addr())
+
+	go func() {
+		if err := server.grpcServer.Serve(listener); err != nil {
+			tracer.LogError("gRPC serve error: ", err)
+		}
+	}()
+
+	return server, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops the server, ending open calls and watches.
+func (s *Server) Close() error {
+	s.grpcServer.Stop()
+	return nil
+}
@@ -0,0 +1,234 @@
+package rpc
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/endpoints"
+	"demo-store/rpc/storepb"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	AuthorizationMetadata = "authorization"
+	WatchSequenceMetadata = "watch-sequence"
+)
+
+// username authenticates the call from its authorization metadata, which
+// carries the same value as the REST Authorization header.
+func (s *Server) username(ctx context.Context) (string, error) {
+	token := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(AuthorizationMetadata); len(values) > 0 {
+			token = values[0]
+		}
+	}
+
+	username, err := s.authenticator.GetUsername(token)
+	if err != nil {
+		return "", StatusFromError(err)
+	}
+	return username, nil
+}
+
+func (s *Server) Login(ctx context.Context, req *storepb.LoginRequest) (*storepb.LoginResponse, error) {
+	if req.Username == "" || req.Password == "" {
+		return nil, StatusFromError(common.ErrorAuthorizationHeaderMissing)
+	}
+
+	if err := s.store.UserDatabase().Authenticate(req.Username, req.Password); err != nil {
+		return nil, StatusFromError(common.ErrorAuthorizationFailed)
+	}
+
+	user, err := s.store.UserDatabase().FindUser(req.Username)
+	if err != nil {
+		return nil, StatusFromError(common.ErrorAuthorizationFailed)
+	}
+
+	token, err := s.tokenizer.CreateToken(req.Username, users.RoleNames(user.Roles))
+	if err != nil {
+		return nil, StatusFromError(common.ErrorAuthorizationFailed)
+	}
+
+	return &storepb.LoginResponse{Token: utils.BearerTokenHeader + token}, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *storepb.GetRequest) (*storepb.GetResponse, error) {
+	username, err := s.username(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Key == "" {
+		return nil, StatusFromError(common.ErrorKeyNotSet)
+	}
+
+	entry, err := s.store.MakeReadRequest(ctx, req.Key, username)
+	if err != nil {
+		return nil, StatusFromError(err)
+	}
+	return &storepb.GetResponse{
+		Value:           entry.Value,
+		Version:         entry.Version,
+		ContentType:     entry.ContentType,
+		ContentEncoding: entry.ContentEncoding,
+	}, nil
+}
+
+func (s *Server) Put(ctx context.Context, req *storepb.PutRequest) (*storepb.PutResponse, error) {
+	username, err := s.username(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Key == "" {
+		return nil, StatusFromError(common.ErrorKeyNotSet)
+	}
+	visibility, err := store.ParseVisibility(req.Visibility)
+	if err != nil {
+		return nil, StatusFromError(err)
+	}
+
+	options := store.PutOptions{
+		ContentType:     req.ContentType,
+		ContentEncoding: req.ContentEncoding,
+		Visibility:      visibility,
+		Precondition:    precondition(req.IfMatch, req.IfNoneMatch),
+	}
+	if req.Ttl != nil {
+		if !req.Ttl.IsValid() || req.Ttl.AsDuration() < 0 {
+			return nil, StatusFromError(common.ErrorInvalidTtl)
+		}
+		options.TTL = req.Ttl.AsDuration()
+	}
+
+	if err := s.store.MakePutRequestWithOptions(ctx, req.Key, req.Value, username, options); err != nil {
+		return nil, StatusFromError(err)
+	}
+	return &storepb.PutResponse{}, nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *storepb.DeleteRequest) (*storepb.DeleteResponse, error) {
+	username, err := s.username(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Key == "" {
+		return nil, StatusFromError(common.ErrorKeyNotSet)
+	}
+
+	options := store.DeleteOptions{Precondition: precondition(req.IfMatch, req.IfNoneMatch)}
+	if err := s.store.MakeDeleteRequestWithOptions(ctx, req.Key, username, options); err != nil {
+		return nil, StatusFromError(err)
+	}
+	return &storepb.DeleteResponse{}, nil
+}
+
+func (s *Server) List(ctx context.Context, req *storepb.ListRequest) (*storepb.Entry, error) {
+	username, err := s.username(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Key == "" {
+		return nil, StatusFromError(common.ErrorKeyNotSet)
+	}
+
+	entry, err := s.store.MakeListRequest(ctx, req.Key, username)
+	if err != nil {
+		return nil, StatusFromError(err)
+	}
+	return entryMessage(entry), nil
+}
+
+func (s *Server) ListAll(ctx context.Context, req *storepb.ListAllRequest) (*storepb.ListAllResponse, error) {
+	username, err := s.username(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.store.MakeListAllRequest(ctx, username)
+	if err != nil {
+		return nil, StatusFromError(err)
+	}
+
+	response := &storepb.ListAllResponse{Entries: make([]*storepb.Entry, 0, len(entries))}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, entryMessage(entry))
+	}
+	return response, nil
+}
+
+// Watch sends events until the client cancels the call, or the watch ends on
+// the store's side, when the client can resume from the last event received.
+func (s *Server) Watch(req *storepb.WatchRequest, stream storepb.Store_WatchServer) error {
+	username, err := s.username(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	query := store.WatchQuery{Key: req.Key, Prefix: req.Prefix || req.Key == "", After: req.After, Reader: username}
+	watcher, err := s.store.MakeWatchRequest(stream.Context(), query)
+	if err != nil {
+		return StatusFromError(err)
+	}
+	defer s.store.MakeUnwatchRequest(watcher)
+
+	header := metadata.Pairs(WatchSequenceMetadata, strconv.FormatUint(watcher.Sequence, 10))
+	if err := grpc.SendHeader(stream.Context(), header); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(eventMessage(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func precondition(ifMatch string, ifNoneMatch string) store.Precondition {
+	return store.Precondition{
+		IfMatch:     endpoints.ParseVersionMatch(ifMatch),
+		IfNoneMatch: endpoints.ParseVersionMatch(ifNoneMatch),
+	}
+}
+
+func entryMessage(entry *store.Entry) *storepb.Entry {
+	return &storepb.Entry{
+		Key:     entry.Key,
+		Owner:   entry.Owner,
+		Reads:   int64(entry.Reads),
+		Writes:  int64(entry.Writes),
+		AgeMs:   entry.Age,
+		TtlMs:   entry.TTL,
+		Version: entry.Version,
+
+		ContentType:     entry.ContentType,
+		ContentEncoding: entry.ContentEncoding,
+		Private:         entry.Private,
+	}
+}
+
+func eventMessage(event store.Event) *storepb.Event {
+	return &storepb.Event{
+		Sequence:  event.Sequence,
+		Type:      event.Type,
+		Key:       event.Key,
+		Value:     event.Value,
+		Owner:     event.Owner,
+		Version:   event.Version,
+		Timestamp: timestamppb.New(event.Timestamp),
+	}
+}
@@ -0,0 +1,237 @@
+package rpc_test
+
+import (
+	"context"
+	"demo-store/endpoints"
+	"demo-store/rpc"
+	"demo-store/rpc/storepb"
+	"demo-store/store"
+	"demo-store/users"
+	"demo-store/utils"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type MockTracer struct {
+}
+
+func (h *MockTracer) LogInfo(message ...any) {
+}
+
+func (h *MockTracer) LogError(message ...any) {
+}
+
+func (h *MockTracer) LogWarning(message ...any) {
+}
+
+func (h *MockTracer) Close() {
+}
+
+func CreateMockTracer() utils.Tracer {
+	return &MockTracer{}
+}
+
+// NewMockUserDatabase returns the users the tests log in as, with passwords
+// hashed at the lowest cost so logging in stays quick.
+func NewMockUserDatabase() users.UserDatabase {
+	users.PasswordCost = bcrypt.MinCost
+	userDatabase := users.CreateUserDatabase()
+	userDatabase.AddUser("user_a", "pass_a")
+	return userDatabase
+}
+
+// startMockServer serves a new store and returns a client connected to it.
+func startMockServer(t *testing.T) (storepb.StoreClient, *store.KvStore) {
+
+	kvStore := store.CreateKvStore(CreateMockTracer(), NewMockUserDatabase(), 0)
+	authenticator := endpoints.NewRouteAuthenticator(CreateMockTracer())
+	server, err := rpc.Listen(CreateMockTracer(), kvStore, authenticator, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	t.Cleanup(func() { server.Close() })
+
+	conn, err := grpc.Dial(server.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return storepb.NewStoreClient(conn), kvStore
+}
+
+func asUser(username string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return metadata.AppendToOutgoingContext(ctx, rpc.AuthorizationMetadata, username), cancel
+}
+
+func assertCode(t *testing.T, err error, expected codes.Code) {
+	t.Helper()
+	if code := status.Code(err); code != expected {
+		t.Errorf("Returned unexpected code: got %v want %v (%v)", code, expected, err)
+	}
+}
+
+func TestLoginReturnsUsableToken(t *testing.T) {
+
+	client, _ := startMockServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.Login(ctx, &storepb.LoginRequest{Username: "user_a", Password: "wrong"})
+	assertCode(t, err, codes.Unauthenticated)
+
+	_, err = client.Login(ctx, &storepb.LoginRequest{Username: "user_a"})
+	assertCode(t, err, codes.PermissionDenied)
+
+	login, err := client.Login(ctx, &storepb.LoginRequest{Username: "user_a", Password: "pass_a"})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	authorized, cancel := asUser(login.Token)
+	defer cancel()
+	if _, err := client.Put(authorized, &storepb.PutRequest{Key: "key1", Value: []byte("value1")}); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	invalid, cancel := asUser("Bearer invalid")
+	defer cancel()
+	_, err = client.Get(invalid, &storepb.GetRequest{Key: "key1"})
+	assertCode(t, err, codes.Unauthenticated)
+}
+
+func TestCallsRequireAuthorization(t *testing.T) {
+
+	client, _ := startMockServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.Get(ctx, &storepb.GetRequest{Key: "key1"})
+	assertCode(t, err, codes.PermissionDenied)
+
+	_, err = client.ListAll(ctx, &storepb.ListAllRequest{})
+	assertCode(t, err, codes.PermissionDenied)
+}
+
+func TestPutGetDelete(t *testing.T) {
+
+	client, kvStore := startMockServer(t)
+	kvStore.MakePutRequest(context.Background(), "key2", "value2", "user_b")
+	ctx, cancel := asUser("user_a")
+	defer cancel()
+
+	_, err := client.Put(ctx, &storepb.PutRequest{Key: "key1", Value: []byte("value1"), Ttl: durationpb.New(time.Minute)})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	got, err := client.Get(ctx, &storepb.GetRequest{Key: "key1"})
+	if err != nil || string(got.Value) != "value1" {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", got, err, "value1")
+	}
+
+	entry, err := client.List(ctx, &storepb.ListRequest{Key: "key1"})
+	if err != nil || entry.Owner != "user_a" || entry.TtlMs <= 0 || entry.Version != got.Version {
+		t.Errorf("Returned unexpected entry: got %v, %v", entry, err)
+	}
+
+	_, err = client.Put(ctx, &storepb.PutRequest{Key: "key1", Value: []byte("value2"), IfNoneMatch: "*"})
+	assertCode(t, err, codes.FailedPrecondition)
+
+	_, err = client.Put(ctx, &storepb.PutRequest{Key: "key1"})
+	assertCode(t, err, codes.InvalidArgument)
+
+	_, err = client.Put(ctx, &storepb.PutRequest{Key: "key1", Value: []byte("value2"), Ttl: durationpb.New(-time.Second)})
+	assertCode(t, err, codes.InvalidArgument)
+
+	_, err = client.Delete(ctx, &storepb.DeleteRequest{Key: "key2"})
+	assertCode(t, err, codes.PermissionDenied)
+
+	_, err = client.Delete(ctx, &storepb.DeleteRequest{Key: "key1"})
+	if err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	_, err = client.Get(ctx, &storepb.GetRequest{Key: "key1"})
+	assertCode(t, err, codes.NotFound)
+
+	all, err := client.ListAll(ctx, &storepb.ListAllRequest{})
+	if err != nil || len(all.Entries) != 1 || all.Entries[0].Key != "key2" {
+		t.Errorf("Returned unexpected entries: got %v, %v want %v", all, err, "key2")
+	}
+}
+
+func TestPutSetsVisibility(t *testing.T) {
+
+	client, _ := startMockServer(t)
+	ctx, cancel := asUser("user_a")
+	defer cancel()
+	otherCtx, otherCancel := asUser("user_b")
+	defer otherCancel()
+
+	_, err := client.Put(ctx, &storepb.PutRequest{Key: "key1", Value: []byte("value1"), Visibility: "private"})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	entry, err := client.List(ctx, &storepb.ListRequest{Key: "key1"})
+	if err != nil || !entry.Private {
+		t.Errorf("Returned unexpected entry: got %v, %v want %v", entry, err, "private")
+	}
+
+	_, err = client.Get(otherCtx, &storepb.GetRequest{Key: "key1"})
+	assertCode(t, err, codes.PermissionDenied)
+
+	_, err = client.Put(ctx, &storepb.PutRequest{Key: "key2", Value: []byte("value2"), Visibility: "secret"})
+	assertCode(t, err, codes.InvalidArgument)
+}
+
+func TestWatchStreamsEvents(t *testing.T) {
+
+	client, kvStore := startMockServer(t)
+	kvStore.MakePutRequest(context.Background(), "users/a", "value1", "user_a")
+	ctx, cancel := asUser("user_a")
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &storepb.WatchRequest{Key: "users/", Prefix: true})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	header, err := stream.Header()
+	if err != nil || len(header.Get(rpc.WatchSequenceMetadata)) != 1 || header.Get(rpc.WatchSequenceMetadata)[0] != "1" {
+		t.Errorf("Returned unexpected header: got %v, %v want %v", header, err, "1")
+	}
+
+	kvStore.MakePutRequest(context.Background(), "other", "value", "user_a")
+	kvStore.MakePutRequest(context.Background(), "users/b", "value2", "user_a")
+	kvStore.MakeDeleteRequest(context.Background(), "users/a", "user_a")
+
+	for _, expected := range []string{"put users/b", "delete users/a"} {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		if got := event.Type + " " + event.Key; got != expected {
+			t.Errorf("Returned unexpected event: got %v want %v", got, expected)
+		}
+	}
+
+	// resume from the first event
+	resumed, err := client.Watch(ctx, &storepb.WatchRequest{Prefix: true, After: 1})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	event, err := resumed.Recv()
+	if err != nil || event.Key != "other" || event.Sequence != 2 {
+		t.Errorf("Returned unexpected event: got %v, %v want %v", event, err, "other")
+	}
+}
@@ -0,0 +1,50 @@
+package rpc
+
+import (
+	"demo-store/endpoints"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StatusFromError converts err into the gRPC status matching the HTTP status
+// the REST API answers the same error with, so both APIs agree on what went
+// wrong.
+func StatusFromError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	result := endpoints.CreateHttpResponseFromError(err)
+	return status.Error(CodeFromHttpStatus(result.Code), result.Message)
+}
+
+func CodeFromHttpStatus(code int) codes.Code {
+	switch code {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests, http.StatusRequestEntityTooLarge:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
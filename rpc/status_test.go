@@ -0,0 +1,46 @@
+package rpc_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/rpc"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusFromErrorFollowsHttpStatus(t *testing.T) {
+
+	tests := []struct {
+		err      error
+		expected codes.Code
+	}{
+		{common.ErrorKeyNotFound, codes.NotFound},
+		{common.ErrorUnauthorisedOwner, codes.PermissionDenied},
+		{common.ErrorPermissionDenied, codes.PermissionDenied},
+		{common.ErrorAuthorizationHeaderMissing, codes.PermissionDenied},
+		{common.ErrorAuthorizationFailed, codes.Unauthenticated},
+		{common.ErrorValidatingJwtToken, codes.Unauthenticated},
+		{common.ErrorKeyNotSet, codes.InvalidArgument},
+		{common.ErrorInvalidTtl, codes.InvalidArgument},
+		{common.ErrorPreconditionFailed, codes.FailedPrecondition},
+		{common.ErrorWatchPositionLost, codes.NotFound},
+		{common.ErrorPersistenceDisabled, codes.Unimplemented},
+		{common.ErrorStoreClosed, codes.Unavailable},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("some error"), codes.Internal},
+	}
+
+	for _, test := range tests {
+		code := status.Code(rpc.StatusFromError(test.err))
+		if code != test.expected {
+			t.Errorf("Returned unexpected code for %v: got %v want %v", test.err, code, test.expected)
+		}
+	}
+
+	if err := rpc.StatusFromError(nil); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+}
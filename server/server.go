@@ -1,37 +1,190 @@
 package server
 
 import (
+	"context"
 	"demo-store/endpoints"
+	"demo-store/memcached"
+	"demo-store/resp"
+	"demo-store/rpc"
 	"demo-store/store"
 	"demo-store/users"
 	"demo-store/utils"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 )
 
-func Listen(port int, depth int) error {
+type Config struct {
+	Port          int
+	Depth         int
+	MaxBytes      int64
+	Eviction      store.Eviction
+	DataPath      string
+	Fsync         store.FsyncPolicy
+	FsyncInterval time.Duration
+
+	SnapshotInterval time.Duration
+
+	// RequestTimeout bounds how long each request waits for the store, so a
+	// stalled store answers 504 Gateway Timeout rather than hanging.
+	RequestTimeout time.Duration
+
+	// Shards spreads the keys over that many stores, each with its own
+	// goroutine, if above one. LimitsPerShard applies Depth and MaxBytes to
+	// each of them rather than to the store as a whole.
+	Shards         int
+	LimitsPerShard bool
+
+	// AllowEmptyValues accepts keys written with an empty value.
+	AllowEmptyValues bool
+
+	// PrivateByDefault makes keys written without a visibility private.
+	PrivateByDefault bool
+
+	// RespPort serves the store over the Redis protocol as well, if set.
+	RespPort int
+
+	// MemcachedPort serves the store over the memcached text protocol as well,
+	// if set, reading and writing keys as MemcachedUser.
+	MemcachedPort int
+	MemcachedUser string
+
+	// GrpcPort serves the store over gRPC as well, if set.
+	GrpcPort int
+}
+
+func Listen(config Config) error {
 
 	userDatabase := users.Load("cache")
-	kvStore := store.CreateKvStore(utils.ApplicationTracer(), userDatabase, depth)
+	kvStore, err := createStore(config, userDatabase)
+	if err != nil {
+		return err
+	}
+
+	// shutting the store down closes its write log, so nothing written before
+	// a failed start is lost
+	listeners, err := startListeners(config, kvStore)
+	if err != nil {
+		kvStore.MakeShutdownRequest(context.Background())
+		return err
+	}
+
 	shutdownListener := store.CreateShutdownListener()
 	kvStore.RegisterShutdownListener(shutdownListener)
+	handler := NewHandler(utils.HttpTracer(), kvStore)
 
-	register(kvStore)
+	if err := start(config.Port, handler, *shutdownListener, listeners); err != nil {
+		// the shutdown listener then closes the other listeners
+		kvStore.MakeShutdownRequest(context.Background())
+		return err
+	}
+	return nil
+}
 
-	return start(port, *shutdownListener)
+// startListeners starts the servers for protocols other than HTTP, returning
+// them so they can be closed on shutdown.
+func startListeners(config Config, kvStore store.Store) ([]io.Closer, error) {
+
+	var listeners []io.Closer
+	if config.RespPort > 0 {
+		respServer, err := resp.Listen(utils.ApplicationTracer(), kvStore, fmt.Sprintf(":%d", config.RespPort))
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, respServer)
+	}
+	if config.MemcachedPort > 0 {
+		memcachedServer, err := memcached.Listen(utils.ApplicationTracer(), kvStore, fmt.Sprintf(":%d", config.MemcachedPort), config.MemcachedUser)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, memcachedServer)
+	}
+	if config.GrpcPort > 0 {
+		authenticator := endpoints.NewRouteAuthenticatorWithUsers(utils.ApplicationTracer(), kvStore.UserDatabase())
+		grpcServer, err := rpc.Listen(utils.ApplicationTracer(), kvStore, authenticator, fmt.Sprintf(":%d", config.GrpcPort))
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, grpcServer)
+	}
+
+	return listeners, nil
+}
+
+func closeListeners(listeners []io.Closer) {
+	for _, listener := range listeners {
+		listener.Close()
+	}
+}
+
+func createStore(config Config, userDatabase users.UserDatabase) (store.Store, error) {
+
+	storeConfig := store.Config{Depth: config.Depth, MaxBytes: config.MaxBytes, Eviction: config.Eviction, SnapshotInterval: config.SnapshotInterval, RequestTimeout: config.RequestTimeout, AllowEmptyValues: config.AllowEmptyValues, PrivateByDefault: config.PrivateByDefault}
+	if config.Shards > 1 {
+		return createShardedStore(config, storeConfig, userDatabase)
+	}
+
+	if config.DataPath != "" {
+		writeLog, err := store.OpenWriteLog(utils.ApplicationTracer(), config.DataPath, config.Fsync, config.FsyncInterval)
+		if err != nil {
+			return nil, err
+		}
+		storeConfig.WriteLog = writeLog
+	}
+
+	kvStore, err := store.CreateKvStoreWithConfig(utils.ApplicationTracer(), userDatabase, storeConfig)
+	if err != nil {
+		closeWriteLogs(storeConfig.WriteLog)
+		return nil, err
+	}
+	return kvStore, nil
+}
+
+func createShardedStore(config Config, storeConfig store.Config, userDatabase users.UserDatabase) (store.Store, error) {
+
+	shardConfig := store.ShardConfig{Shards: config.Shards, LimitsPerShard: config.LimitsPerShard}
+	if config.DataPath != "" {
+		writeLogs, err := store.OpenShardWriteLogs(utils.ApplicationTracer(), config.DataPath, config.Shards, config.Fsync, config.FsyncInterval)
+		if err != nil {
+			return nil, err
+		}
+		shardConfig.WriteLogs = writeLogs
+	}
+
+	shardedStore, err := store.CreateShardedStore(utils.ApplicationTracer(), userDatabase, storeConfig, shardConfig)
+	if err != nil {
+		closeWriteLogs(shardConfig.WriteLogs...)
+		return nil, err
+	}
+	return shardedStore, nil
+}
+
+// closeWriteLogs closes the write logs of a store that failed to start.
+func closeWriteLogs(writeLogs ...*store.WriteLog) {
+	for _, writeLog := range writeLogs {
+		if writeLog != nil {
+			writeLog.Close()
+		}
+	}
 }
 
-func start(port int, shutdownListener store.ShutdownListener) error {
+func start(port int, handler http.Handler, shutdownListener store.ShutdownListener, listeners []io.Closer) error {
 
 	httpServer := &http.Server{
-		Addr: fmt.Sprintf(":%d", port),
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: handler,
 	}
 	go func() {
 
 		utils.ApplicationTracer().LogInfo("HTTP Server Listening ", httpServer.Addr)
 		resp := <-shutdownListener.Listener
 		if resp {
+			closeListeners(listeners)
 			shutdown(httpServer)
 		}
 	}()
@@ -51,18 +204,23 @@ func shutdown(httpServer *http.Server) {
 	}
 }
 
-func register(store store.Store) {
+// NewHandler routes requests to the API endpoints for the store. It is what
+// Listen serves, and lets tests and embedding programs serve the API
+// in-process, for example with httptest.
+func NewHandler(tracer utils.Tracer, store store.Store) *http.ServeMux {
 
-	routes := endpoints.APIRoutes(utils.HttpTracer(), store)
+	mux := http.NewServeMux()
+	routes := endpoints.APIRoutes(tracer, store)
 	for _, route := range routes.Secure {
-		registerRoute(route)
+		registerRoute(tracer, mux, route)
 	}
 	for _, route := range routes.Insecure {
-		registerRoute(route)
+		registerRoute(tracer, mux, route)
 	}
+	return mux
 }
 
-func registerRoute(route endpoints.Route) {
-	utils.ApplicationTracer().LogInfo("Register route: ", route.RootPath())
-	http.Handle(route.RootPath(), route)
+func registerRoute(tracer utils.Tracer, mux *http.ServeMux, route endpoints.Route) {
+	tracer.LogInfo("Register route: ", route.RootPath())
+	mux.Handle(route.RootPath(), route)
 }
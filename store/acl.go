@@ -0,0 +1,247 @@
+package store
+
+import (
+	"demo-store/common"
+	"demo-store/users"
+	"sort"
+)
+
+// Access is what a grant allows a user to do with a key.
+type Access string
+
+const (
+	AccessRead   Access = "read"
+	AccessWrite  Access = "write"
+	AccessDelete Access = "delete"
+)
+
+// Visibility says who may read a key. Private keys may only be read by their
+// owner, users granted read and users allowed to override, and are left out
+// of lists for anyone else.
+type Visibility string
+
+const (
+	VisibilityPublic  Visibility = "public"
+	VisibilityPrivate Visibility = "private"
+)
+
+// ParseVisibility accepts public, private or "" for the default.
+func ParseVisibility(value string) (Visibility, error) {
+	switch visibility := Visibility(value); visibility {
+	case "", VisibilityPublic, VisibilityPrivate:
+		return visibility, nil
+	}
+	return "", common.ErrorInvalidVisibility
+}
+
+// ACL grants users access to a key besides its owner, by username or by group
+// as users.GroupOwner names it. Grants only ever add access: a public key is
+// readable by everyone whatever its ACL, and granting read matters only once
+// the key is private.
+type ACL map[string][]Access
+
+// AclChange changes who may use a key. Revoking an empty list removes all of
+// a user's access, and Owner, if set, transfers the key to a new owner.
+type AclChange struct {
+	Grant  ACL
+	Revoke ACL
+	Owner  string
+}
+
+// Allows reports whether the ACL grants user access.
+func (a ACL) Allows(user string, access Access) bool {
+	return containsAccess(a[user], access)
+}
+
+func containsAccess(accesses []Access, access Access) bool {
+	for _, granted := range accesses {
+		if granted == access {
+			return true
+		}
+	}
+	return false
+}
+
+func (a ACL) Clone() ACL {
+	if len(a) == 0 {
+		return nil
+	}
+	clone := make(ACL, len(a))
+	for user, accesses := range a {
+		clone[user] = append([]Access(nil), accesses...)
+	}
+	return clone
+}
+
+// size is the number of bytes the ACL is accounted for in Entry.Size.
+func (a ACL) size() int {
+	size := 0
+	for user, accesses := range a {
+		size += len(user)
+		for _, access := range accesses {
+			size += len(access)
+		}
+	}
+	return size
+}
+
+// applied returns a copy of the ACL with the change's grants and revokes
+// made, keeping each user's access in order and dropping users left with
+// none.
+func (a ACL) applied(change AclChange) ACL {
+	acl := a.Clone()
+	if acl == nil {
+		acl = make(ACL)
+	}
+
+	for user, accesses := range change.Grant {
+		for _, access := range accesses {
+			if !acl.Allows(user, access) {
+				acl[user] = append(acl[user], access)
+			}
+		}
+		sort.Slice(acl[user], func(i, j int) bool { return acl[user][i] < acl[user][j] })
+	}
+	for user, accesses := range change.Revoke {
+		if len(accesses) == 0 {
+			delete(acl, user)
+			continue
+		}
+		var kept []Access
+		for _, access := range acl[user] {
+			if !containsAccess(accesses, access) {
+				kept = append(kept, access)
+			}
+		}
+		acl[user] = kept
+	}
+
+	for user, accesses := range acl {
+		if len(accesses) == 0 {
+			delete(acl, user)
+		}
+	}
+	if len(acl) == 0 {
+		return nil
+	}
+	return acl
+}
+
+// ValidateAclChange rejects unknown access and grants to nobody.
+func ValidateAclChange(change AclChange) error {
+	for _, acl := range []ACL{change.Grant, change.Revoke} {
+		for user, accesses := range acl {
+			if user == "" {
+				return common.ErrorInvalidAccess
+			}
+			for _, access := range accesses {
+				if access != AccessRead && access != AccessWrite && access != AccessDelete {
+					return common.ErrorInvalidAccess
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// authorise checks that user may have access to entry, if there is one, and
+// that user may write at all unless reading. Owners, including members of a
+// group owning the entry, have every access, and users allowed to override
+// have it to keys owned by others.
+func (s *KvStore) authorise(user string, entry *Entry, access Access) error {
+	if access != AccessRead && !s.userDatabase.HasPermission(user, users.PermissionWrite) {
+		return common.ErrorPermissionDenied
+	}
+	if entry == nil || s.owns(user, entry) || s.granted(user, entry.ACL, access) {
+		return nil
+	}
+	if access == AccessRead && !entry.Private {
+		return nil
+	}
+	if s.userDatabase.HasPermission(user, users.PermissionOverride) {
+		return nil
+	}
+
+	if access == AccessRead {
+		return common.ErrorPermissionDenied
+	}
+	return common.ErrorUnauthorisedOwner
+}
+
+// owns reports whether user owns the entry, themselves or as a member of the
+// group owning it.
+func (s *KvStore) owns(user string, entry *Entry) bool {
+	return s.userDatabase.Includes(entry.Owner, user)
+}
+
+// granted reports whether the ACL grants user access, themselves or through a
+// group they're a member of.
+func (s *KvStore) granted(user string, acl ACL, access Access) bool {
+	for grantee, accesses := range acl {
+		if containsAccess(accesses, access) && s.userDatabase.Includes(grantee, user) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGroups fails with users.ErrorGroupNotFound if the change names a group
+// that doesn't exist, which nobody could then use the key through.
+func (s *KvStore) checkGroups(change AclChange) error {
+	names := []string{change.Owner}
+	for grantee := range change.Grant {
+		names = append(names, grantee)
+	}
+	for _, name := range names {
+		if group, ok := users.ParseGroupOwner(name); ok {
+			if _, err := s.userDatabase.FindGroup(group); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readable reports whether user may read the entry, leaving it out of lists
+// if not.
+func (s *KvStore) readable(user string, entry *Entry) bool {
+	return s.authorise(user, entry, AccessRead) == nil
+}
+
+// ChangeAcl grants and revokes access to the key, or transfers it to a new
+// owner, returning the entry as changed. Only the owner, members of the group
+// owning it or users allowed to override may change it. Like Expire, it
+// doesn't count as a write or change the entry's version.
+func (s *KvStore) ChangeAcl(key string, owner string, change AclChange) (*Entry, error) {
+
+	if err := ValidateAclChange(change); err != nil {
+		return nil, err
+	}
+	if err := s.checkGroups(change); err != nil {
+		return nil, err
+	}
+	entry, err := s.findLiveEntry(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.owns(owner, entry) && !s.userDatabase.HasPermission(owner, users.PermissionOverride) {
+		s.Tracer.LogError("User", owner, " cannot change access to key.")
+		return nil, common.ErrorUnauthorisedOwner
+	}
+
+	changed := entry.Clone()
+	changed.ACL = entry.ACL.applied(change)
+	if change.Owner != "" {
+		changed.Owner = change.Owner
+	}
+	if err := s.recordEntry(LogOpPut, changed); err != nil {
+		return nil, err
+	}
+
+	size := entry.Size()
+	entry.ACL = changed.ACL
+	entry.Owner = changed.Owner
+	s.entries.Resize(key, size)
+	return entry.Clone(), nil
+}
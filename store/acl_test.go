@@ -0,0 +1,226 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/users"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadGrantsOnlyAddReaders(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.UserDatabase().AddUser("admin", "111")
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+
+	change := store.AclChange{Grant: store.ACL{"reader1": {store.AccessRead}}}
+	if _, err := mockStore.MakeAclRequest(context.Background(), key1, owner1, change); err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if _, err := mockStore.MakeGetRequest(context.Background(), key1, owner2); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	private := store.PutOptions{Visibility: store.VisibilityPrivate}
+	if err := mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, private); err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	readers := map[string]error{owner1: nil, "reader1": nil, "admin": nil, owner2: common.ErrorPermissionDenied}
+	for reader, expected := range readers {
+		if _, err := mockStore.MakeGetRequest(context.Background(), key1, reader); err != expected {
+			t.Errorf("Returned unexpected error for %v: got %v want %v", reader, err, expected)
+		}
+	}
+
+	_, err := mockStore.MakeBatchRequest(context.Background(), []store.BatchOperation{{Op: store.BatchOpGet, Key: key1}}, owner2)
+	if !errors.Is(err, common.ErrorPermissionDenied) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPermissionDenied)
+	}
+}
+
+func TestAclGrantsAndRevokesAccess(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+
+	grant := store.AclChange{Grant: store.ACL{owner2: {store.AccessWrite, store.AccessDelete}}}
+	if _, err := mockStore.MakeAclRequest(context.Background(), key1, owner2, grant); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+	mockStore.MakeAclRequest(context.Background(), key1, owner1, grant)
+
+	batch := []store.BatchOperation{{Op: store.BatchOpPut, Key: key1, Value: []byte(value2)}}
+	if _, err := mockStore.MakeBatchRequest(context.Background(), batch, owner2); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	revoke := store.AclChange{Revoke: store.ACL{owner2: {store.AccessDelete}}}
+	entry, _ := mockStore.MakeAclRequest(context.Background(), key1, owner1, revoke)
+	if expected := (store.ACL{owner2: {store.AccessWrite}}); !reflect.DeepEqual(entry.ACL, expected) {
+		t.Errorf("Returned unexpected acl: got %v want %v", entry.ACL, expected)
+	}
+	if err := mockStore.MakeDeleteRequest(context.Background(), key1, owner2); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+
+	entry, _ = mockStore.MakeListRequest(context.Background(), key1, owner1)
+	if entry.Owner != owner1 || entry.Version != 2 || !entry.ACL.Allows(owner2, store.AccessWrite) {
+		t.Errorf("Returned unexpected entry: got %+v", entry)
+	}
+}
+
+func TestAclIsRestoredFromWriteLog(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakeAclRequest(context.Background(), key1, owner1, store.AclChange{Grant: store.ACL{"reader1": {store.AccessRead}}, Owner: owner2})
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+	entry, err := kvStore.MakeListRequest(context.Background(), key1, owner2)
+	if err != nil || entry.Owner != owner2 || !entry.ACL.Allows("reader1", store.AccessRead) {
+		t.Errorf("Returned unexpected entry: got %+v, %v", entry, err)
+	}
+}
+
+func TestPrivateKeysAreHiddenFromOtherUsers(t *testing.T) {
+
+	mockStore := NewMockStore()
+	private := store.PutOptions{Visibility: store.VisibilityPrivate}
+	mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, private)
+	mockStore.MakePutRequest(context.Background(), key2, value2, owner1)
+
+	if _, err := mockStore.MakeGetRequest(context.Background(), key1, owner2); err != common.ErrorPermissionDenied {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPermissionDenied)
+	}
+	if _, err := mockStore.MakeListRequest(context.Background(), key1, owner2); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+	if entries, _ := mockStore.MakeListAllRequest(context.Background(), owner2); len(entries) != 1 || entries[0].Key != key2 {
+		t.Errorf("Returned unexpected entries: got %v want only %v", entries, key2)
+	}
+	if result, _ := mockStore.MakeScanRequest(context.Background(), store.ScanQuery{Reader: owner2}); len(result.Entries) != 1 {
+		t.Errorf("Returned unexpected entries: got %v want only %v", result.Entries, key2)
+	}
+
+	if entries, _ := mockStore.MakeListAllRequest(context.Background(), owner1); len(entries) != 2 {
+		t.Errorf("Returned unexpected entries: got %v want %v", len(entries), 2)
+	}
+	if value, err := mockStore.MakeGetRequest(context.Background(), key1, owner1); err != nil || value != value1 {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, value1)
+	}
+}
+
+func TestOnlyOwnersCanChangeVisibility(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	mockStore.MakeAclRequest(context.Background(), key1, owner1, store.AclChange{Grant: store.ACL{owner2: {store.AccessWrite}}})
+
+	public := store.PutOptions{Visibility: store.VisibilityPublic}
+	if err := mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value2), owner2, public); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+	if err := mockStore.MakePutRequest(context.Background(), key1, value2, owner2); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	invalid := store.PutOptions{Visibility: "hidden"}
+	if err := mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, invalid); err != common.ErrorInvalidVisibility {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorInvalidVisibility)
+	}
+}
+
+func TestStoreCanMakeKeysPrivateByDefault(t *testing.T) {
+
+	config := store.Config{PrivateByDefault: true}
+	kvStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), config)
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	public := store.PutOptions{Visibility: store.VisibilityPublic}
+	kvStore.MakePutRequestWithOptions(context.Background(), key2, []byte(value2), owner1, public)
+
+	// updates keep the visibility the key was created with
+	kvStore.MakePutRequest(context.Background(), key2, value1, owner1)
+
+	if entry, _ := kvStore.MakeListRequest(context.Background(), key1, owner1); !entry.Private {
+		t.Errorf("Returned unexpected visibility: got %v want %v", entry.Private, true)
+	}
+	if entry, _ := kvStore.MakeListRequest(context.Background(), key2, owner1); entry.Private {
+		t.Errorf("Returned unexpected visibility: got %v want %v", entry.Private, false)
+	}
+}
+
+func TestPrivateKeysAreRestoredFromWriteLog(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	private := store.PutOptions{Visibility: store.VisibilityPrivate}
+	kvStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, private)
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+	if _, err := kvStore.MakeGetRequest(context.Background(), key1, owner2); err != common.ErrorPermissionDenied {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPermissionDenied)
+	}
+}
+
+func TestGroupOwnedKeysCanBeWrittenByMembers(t *testing.T) {
+
+	mockStore := NewMockStore()
+	userDatabase := mockStore.UserDatabase()
+	userDatabase.AddUser(owner2, "22222")
+	userDatabase.AddGroup("team")
+	userDatabase.AddGroupMember("team", owner2)
+	team := users.GroupOwner("team")
+
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	mockStore.MakePutRequest(context.Background(), key2, value1, owner1)
+	missing := store.AclChange{Owner: users.GroupOwner("missing")}
+	if _, err := mockStore.MakeAclRequest(context.Background(), key1, owner1, missing); err != users.ErrorGroupNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, users.ErrorGroupNotFound)
+	}
+	mockStore.MakeAclRequest(context.Background(), key1, owner1, store.AclChange{Owner: team})
+	mockStore.MakeAclRequest(context.Background(), key2, owner1, store.AclChange{Grant: store.ACL{team: {store.AccessWrite}}})
+
+	if err := mockStore.MakePutRequest(context.Background(), key1, value2, owner1); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+	for _, key := range []string{key1, key2} {
+		if err := mockStore.MakePutRequest(context.Background(), key, value2, owner2); err != nil {
+			t.Errorf("Returned unexpected error for %v: got %v want %v", key, err, "nil")
+		}
+	}
+
+	userDatabase.RemoveGroupMember("team", owner2)
+	if err := mockStore.MakeDeleteRequest(context.Background(), key1, owner2); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+	userDatabase.AddGroupMember("team", owner2)
+	if err := mockStore.MakeDeleteRequest(context.Background(), key1, owner2); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+}
+
+func TestGroupNamesAreNotUsers(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.UserDatabase().AddGroup("team")
+	team := users.GroupOwner("team")
+
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	mockStore.MakePutRequest(context.Background(), key2, value1, owner1)
+	mockStore.MakeAclRequest(context.Background(), key1, owner1, store.AclChange{Owner: team})
+	mockStore.MakeAclRequest(context.Background(), key2, owner1, store.AclChange{Grant: store.ACL{team: {store.AccessWrite}}})
+
+	for _, key := range []string{key1, key2} {
+		if err := mockStore.MakePutRequest(context.Background(), key, value2, team); err != common.ErrorUnauthorisedOwner {
+			t.Errorf("Returned unexpected error for %v: got %v want %v", key, err, common.ErrorUnauthorisedOwner)
+		}
+	}
+	if _, err := mockStore.MakeAclRequest(context.Background(), key1, team, store.AclChange{Owner: owner2}); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+}
@@ -0,0 +1,107 @@
+package store
+
+// ArcPolicy is the Adaptive Replacement Cache. Keys used once are kept apart
+// from keys used again, so a scan can only push out other keys used once, and
+// the keys recently evicted from each are remembered to adapt how much room
+// each gets: a write to a key evicted too soon grows the side it came from.
+//
+// ARC is defined for a cache of fixed size; as the list is bounded by bytes as
+// well as keys, the number of keys held stands in for it.
+type ArcPolicy struct {
+	recent         *keyList
+	frequent       *keyList
+	recentGhosts   *keyList
+	frequentGhosts *keyList
+
+	// target is how many of the keys held should be recent ones
+	target int
+}
+
+func NewArcPolicy() *ArcPolicy {
+	return &ArcPolicy{recent: newKeyList(), frequent: newKeyList(), recentGhosts: newKeyList(), frequentGhosts: newKeyList()}
+}
+
+func (p *ArcPolicy) Add(key string) {
+
+	size := p.recent.Len() + p.frequent.Len() + 1
+	switch {
+	case p.recentGhosts.Contains(key):
+		delta := 1
+		if p.frequentGhosts.Len() > p.recentGhosts.Len() {
+			delta = p.frequentGhosts.Len() / p.recentGhosts.Len()
+		}
+		p.target += delta
+		if p.target > size {
+			p.target = size
+		}
+		p.recentGhosts.Remove(key)
+		p.frequent.PushFront(key)
+
+	case p.frequentGhosts.Contains(key):
+		delta := 1
+		if p.recentGhosts.Len() > p.frequentGhosts.Len() {
+			delta = p.recentGhosts.Len() / p.frequentGhosts.Len()
+		}
+		p.target -= delta
+		if p.target < 0 {
+			p.target = 0
+		}
+		p.frequentGhosts.Remove(key)
+		p.frequent.PushFront(key)
+
+	default:
+		p.recent.PushFront(key)
+	}
+}
+
+func (p *ArcPolicy) Touch(key string) {
+	if p.recent.Remove(key) {
+		p.frequent.PushFront(key)
+	} else if p.frequent.Contains(key) {
+		p.frequent.MoveToFront(key)
+	}
+}
+
+func (p *ArcPolicy) Remove(key string) {
+	if !p.recent.Remove(key) {
+		p.frequent.Remove(key)
+	}
+}
+
+func (p *ArcPolicy) Evict(keep string) (string, bool) {
+
+	key, ok := "", false
+	if p.recent.Len() > p.target || p.frequent.Len() == 0 {
+		key, ok = p.evictFrom(p.recent, p.recentGhosts, keep)
+	}
+	if !ok {
+		key, ok = p.evictFrom(p.frequent, p.frequentGhosts, keep)
+	}
+	if !ok {
+		key, ok = p.evictFrom(p.recent, p.recentGhosts, keep)
+	}
+
+	p.trimGhosts()
+	return key, ok
+}
+
+func (p *ArcPolicy) evictFrom(keys *keyList, ghosts *keyList, keep string) (string, bool) {
+	key, ok := keys.PopBack(keep)
+	if ok {
+		ghosts.PushFront(key)
+	}
+	return key, ok
+}
+
+// trimGhosts remembers no more evicted keys than there are keys held.
+func (p *ArcPolicy) trimGhosts() {
+
+	size := p.recent.Len() + p.frequent.Len()
+	for p.recentGhosts.Len()+p.frequentGhosts.Len() > size {
+		if p.recentGhosts.Len() > 0 && (p.recent.Len()+p.recentGhosts.Len() > size || p.frequentGhosts.Len() == 0) {
+			p.recentGhosts.PopBack("")
+		} else {
+			p.frequentGhosts.PopBack("")
+		}
+	}
+}
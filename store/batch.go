@@ -0,0 +1,179 @@
+package store
+
+import (
+	"demo-store/common"
+	"fmt"
+	"time"
+)
+
+const (
+	BatchOpPut    = "put"
+	BatchOpGet    = "get"
+	BatchOpDelete = "delete"
+)
+
+type BatchOperation struct {
+	Op           string
+	Key          string
+	Value        []byte
+	TTL          time.Duration
+	Precondition Precondition
+}
+
+type BatchResult struct {
+	Op      string `json:"op"`
+	Key     string `json:"key"`
+	Value   string `json:"value,omitempty"`
+	Version uint64 `json:"version,omitempty"`
+}
+
+// BatchError reports the operation that stopped a batch from being applied.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch applies every operation in order as the given owner, or none of them
+// if any would fail. Operations are first checked against a staged view of the
+// keys so later operations see the effect of earlier ones in the same batch.
+func (s *KvStore) Batch(operations []BatchOperation, owner string) ([]BatchResult, error) {
+
+	staged, err := s.validateBatch(operations, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	// the batch is recorded whole, before any of it is applied, so a crash
+	// can't replay half of it and a failed append leaves the store as it was
+	records := []LogRecord{}
+	for i, operation := range operations {
+		switch operation.Op {
+		case BatchOpPut:
+			records = append(records, CreateLogRecord(LogOpPut, staged[i]))
+		case BatchOpDelete:
+			records = append(records, CreateLogRecord(LogOpDelete, staged[i]))
+		}
+	}
+	if len(records) > 0 {
+		if err := s.recordEntries(records); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]BatchResult, 0, len(operations))
+	for i, operation := range operations {
+		result := BatchResult{Op: operation.Op, Key: operation.Key}
+
+		switch operation.Op {
+		case BatchOpPut:
+			s.applyPut(staged[i])
+			result.Version = staged[i].Version
+
+		case BatchOpDelete:
+			// an earlier put in the batch may already have evicted the key
+			if _, ok := s.entries.data[operation.Key]; ok {
+				s.applyDelete(staged[i])
+			}
+
+		case BatchOpGet:
+			result.Value = string(staged[i].Value)
+			result.Version = staged[i].Version
+			if _, ok := s.entries.data[operation.Key]; ok {
+				s.Read(operation.Key, owner)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// validateBatch returns, for each operation, the state of its key at that
+// point in the batch, or the first operation that would fail.
+func (s *KvStore) validateBatch(operations []BatchOperation, owner string) ([]*Entry, error) {
+
+	if len(operations) == 0 {
+		return nil, common.ErrorInvalidBatch
+	}
+
+	keys := make(map[string]*Entry)
+	lookup := func(key string) *Entry {
+		if entry, ok := keys[key]; ok {
+			return entry
+		}
+		entry, _ := s.findLiveEntry(key)
+		return entry
+	}
+
+	version := s.version
+	staged := make([]*Entry, len(operations))
+	for i, operation := range operations {
+		entry := lookup(operation.Key)
+
+		switch operation.Op {
+		case BatchOpPut:
+			if err := ValidateKey(operation.Key); err != nil {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+			if len(operation.Value) == 0 && !s.allowEmptyValues {
+				return nil, &BatchError{Index: i, Err: common.ErrorStoreValueNotSet}
+			}
+			if err := s.authorise(owner, entry, AccessWrite); err != nil {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+			if err := operation.Precondition.Check(entry); err != nil {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+
+			version++
+			written := s.staged(operation.Key, operation.Value, owner, entry, version, PutOptions{TTL: operation.TTL})
+			if !s.entries.Fits(written) {
+				return nil, &BatchError{Index: i, Err: common.ErrorValueTooLarge}
+			}
+			keys[operation.Key] = written
+			staged[i] = written
+
+		case BatchOpDelete:
+			if entry == nil {
+				if err := operation.Precondition.Check(nil); err != nil {
+					return nil, &BatchError{Index: i, Err: err}
+				}
+				return nil, &BatchError{Index: i, Err: common.ErrorKeyNotFound}
+			}
+			if err := s.authorise(owner, entry, AccessDelete); err != nil {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+			if err := operation.Precondition.Check(entry); err != nil {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+			keys[operation.Key] = nil
+			staged[i] = entry
+
+		case BatchOpGet:
+			if err := s.authorise(owner, entry, AccessRead); err != nil {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+			if err := operation.Precondition.Check(entry); err != nil {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+			if entry == nil {
+				return nil, &BatchError{Index: i, Err: common.ErrorKeyNotFound}
+			}
+			staged[i] = entry
+
+		default:
+			return nil, &BatchError{Index: i, Err: common.ErrorInvalidBatch}
+		}
+	}
+
+	return staged, nil
+}
@@ -0,0 +1,114 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"errors"
+	"testing"
+)
+
+func TestBatchAppliesAllOperations(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key2, value2, owner1)
+
+	operations := []store.BatchOperation{
+		{Op: store.BatchOpPut, Key: key1, Value: []byte(value1)},
+		{Op: store.BatchOpGet, Key: key1},
+		{Op: store.BatchOpDelete, Key: key2},
+	}
+	results, err := mockStore.MakeBatchRequest(context.Background(), operations, owner1)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	if results[1].Value != value1 || results[1].Version != results[0].Version {
+		t.Errorf("Returned unexpected result: got %v want %v", results[1], results[0])
+	}
+	if value, _ := mockStore.MakeGetRequest(context.Background(), key1, owner1); value != value1 {
+		t.Errorf("Returned unexpected value: got %v want %v", value, value1)
+	}
+	if _, err := mockStore.MakeGetRequest(context.Background(), key2, owner1); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+}
+
+func TestBatchAppliesNothingIfAnOperationFails(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key2, value2, owner2)
+
+	operations := []store.BatchOperation{
+		{Op: store.BatchOpPut, Key: key1, Value: []byte(value1)},
+		{Op: store.BatchOpPut, Key: key2, Value: []byte(value1)},
+	}
+	_, err := mockStore.MakeBatchRequest(context.Background(), operations, owner1)
+
+	var batchErr *store.BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, common.ErrorUnauthorisedOwner) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+
+	if _, err := mockStore.MakeGetRequest(context.Background(), key1, owner1); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+}
+
+func TestBatchAdminCanOverrideOwner(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.UserDatabase().AddUser("admin", "111")
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+
+	operations := []store.BatchOperation{
+		{Op: store.BatchOpPut, Key: key1, Value: []byte(value2)},
+		{Op: store.BatchOpDelete, Key: key1},
+	}
+	if _, err := mockStore.MakeBatchRequest(context.Background(), operations, "admin"); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+}
+
+func TestBatchPreconditionsSeeEarlierOperations(t *testing.T) {
+
+	mockStore := NewMockStore()
+
+	operations := []store.BatchOperation{
+		{Op: store.BatchOpPut, Key: key1, Value: []byte(value1), Precondition: createOnly},
+		{Op: store.BatchOpPut, Key: key1, Value: []byte(value2), Precondition: createOnly},
+	}
+	_, err := mockStore.MakeBatchRequest(context.Background(), operations, owner1)
+	if !errors.Is(err, common.ErrorPreconditionFailed) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPreconditionFailed)
+	}
+
+	operations = []store.BatchOperation{
+		{Op: store.BatchOpPut, Key: key1, Value: []byte(value1)},
+		{Op: store.BatchOpDelete, Key: key1},
+		{Op: store.BatchOpGet, Key: key1},
+	}
+	_, err = mockStore.MakeBatchRequest(context.Background(), operations, owner1)
+	if !errors.Is(err, common.ErrorKeyNotFound) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+}
+
+func TestBatchIsReplayedFromWriteLog(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+
+	operations := []store.BatchOperation{
+		{Op: store.BatchOpPut, Key: key1, Value: []byte(value1)},
+		{Op: store.BatchOpPut, Key: key2, Value: []byte(value2)},
+	}
+	kvStore.MakeBatchRequest(context.Background(), operations, owner1)
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+
+	entries, _ := kvStore.MakeListAllRequest(context.Background(), owner1)
+	if len(entries) != 2 {
+		t.Errorf("Returned unexpected entry count: got %v want %v", len(entries), 2)
+	}
+}
@@ -7,16 +7,33 @@ import (
 
 type Entry struct {
 	Key    string `json:"key"`
-	Value  string `json:"-"`
+	Value  []byte `json:"-"`
 	Owner  string `json:"owner"`
 	Reads  int    `json:"reads"`
 	Writes int    `json:"writes"`
 	Age    int64  `json:"age"`
+	TTL    int64  `json:"ttl,omitempty"`
+
+	Version uint64 `json:"version"`
+
+	// Flags is opaque to the store, kept for clients that tag values with how
+	// they were encoded, as memcached clients do.
+	Flags uint32 `json:"flags,omitempty"`
+
+	// ContentType and ContentEncoding are the headers the value was written
+	// with over HTTP, replayed when it is read.
+	ContentType     string `json:"content_type,omitempty"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+
+	// ACL grants other users access to the key.
+	ACL     ACL  `json:"acl,omitempty"`
+	Private bool `json:"private,omitempty"`
 
 	Timestamp time.Time `json:"-"`
+	Expires   time.Time `json:"-"`
 }
 
-func NewEntry(key string, value string, owner string) *Entry {
+func NewEntry(key string, value []byte, owner string) *Entry {
 	entry := Entry{
 		Key:   key,
 		Value: value,
@@ -36,19 +53,55 @@ func (e *Entry) Clone() *Entry {
 		Reads:     e.Reads,
 		Writes:    e.Writes,
 		Timestamp: e.Timestamp,
+		Expires:   e.Expires,
+		Version:   e.Version,
+		Flags:     e.Flags,
+
+		ContentType:     e.ContentType,
+		ContentEncoding: e.ContentEncoding,
+
+		ACL:     e.ACL.Clone(),
+		Private: e.Private,
 	}
 
 	newEntry.Age = time.Since(e.Timestamp).Milliseconds()
+	if !e.Expires.IsZero() {
+		newEntry.TTL = time.Until(e.Expires).Milliseconds()
+	}
 	return &newEntry
 }
 
-func (e *Entry) ReadValue() string {
+// SetExpiry makes the entry expire ttl from now, or never if ttl is zero.
+func (e *Entry) SetExpiry(ttl time.Duration) {
+	if ttl == 0 {
+		e.Expires = time.Time{}
+		return
+	}
+	e.Expires = time.Now().Add(ttl)
+}
+
+// EntryOverhead approximates the memory an entry takes up besides its key,
+// value and metadata strings: the entry itself and its slots in the LRU list,
+// map and key index.
+const EntryOverhead = 256
+
+// Size is the number of bytes the entry is accounted for against the store's
+// memory budget.
+func (e *Entry) Size() int64 {
+	return int64(len(e.Key)+len(e.Value)+len(e.Owner)+len(e.ContentType)+len(e.ContentEncoding)+e.ACL.size()) + EntryOverhead
+}
+
+func (e *Entry) IsExpired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+func (e *Entry) ReadValue() []byte {
 	e.Reads++
 	e.Timestamp = time.Now()
 	return e.Value
 }
 
-func (e *Entry) WriteValue(value string) {
+func (e *Entry) WriteValue(value []byte) {
 	e.Value = value
 	e.Writes++
 	e.Timestamp = time.Now()
@@ -0,0 +1,231 @@
+package store
+
+import (
+	"container/list"
+	"demo-store/common"
+	"demo-store/utils"
+)
+
+// EntryList holds the store's entries in least recently used order, which is
+// the order they are listed and snapshotted in, and asks its eviction policy
+// which to drop when it is over its depth or size.
+type EntryList struct {
+	data        map[string]*list.Element
+	orderedData *list.List
+	index       *KeyIndex
+	policy      EvictionPolicy
+	tracer      utils.Tracer
+	depth       int
+	maxBytes    int64
+	bytes       int64
+	onEvict     func(entry *Entry)
+}
+
+func NewEntryList(tracer utils.Tracer, depth int, policy EvictionPolicy) *EntryList {
+
+	return &EntryList{data: make(map[string]*list.Element), orderedData: list.New(), index: NewKeyIndex(), policy: policy, tracer: tracer, depth: depth}
+}
+
+// NewLruEntryList creates a list evicting the least recently used entries.
+func NewLruEntryList(tracer utils.Tracer, depth int) *EntryList {
+	return NewEntryList(tracer, depth, NewLruPolicy())
+}
+
+func (s *EntryList) AddEntry(key string, value []byte, owner string) {
+
+	entry := NewEntry(key, value, owner)
+	elem := s.orderedData.PushFront(entry)
+	s.data[entry.Key] = elem
+	s.index.Insert(entry.Key)
+	s.bytes += entry.Size()
+	s.policy.Add(entry.Key)
+
+	s.tracer.LogInfo("Key", entry.Key, "added")
+
+	s.evict(entry.Key)
+}
+
+// SetMaxBytes bounds the total size of the entries, evicting once it is
+// exceeded. Zero leaves the size unbounded.
+func (s *EntryList) SetMaxBytes(maxBytes int64) {
+	s.maxBytes = maxBytes
+	s.evict("")
+}
+
+// Fits reports whether entry could be stored without evicting itself.
+func (s *EntryList) Fits(entry *Entry) bool {
+	return s.maxBytes == 0 || entry.Size() <= s.maxBytes
+}
+
+// SetEvictionHandler registers a callback invoked for every entry dropped
+// because the list is over its depth or size.
+func (s *EntryList) SetEvictionHandler(handler func(entry *Entry)) {
+	s.onEvict = handler
+}
+
+// RestoreEntry places an already populated entry at the front of the list,
+// replacing any entry with the same key, without touching its counters.
+func (s *EntryList) RestoreEntry(entry *Entry) {
+
+	if elem, ok := s.data[entry.Key]; ok {
+		s.bytes += entry.Size() - elem.Value.(*Entry).Size()
+		elem.Value = entry
+		s.orderedData.MoveToFront(elem)
+		s.policy.Touch(entry.Key)
+		s.evict(entry.Key)
+		return
+	}
+
+	s.data[entry.Key] = s.orderedData.PushFront(entry)
+	s.index.Insert(entry.Key)
+	s.bytes += entry.Size()
+	s.policy.Add(entry.Key)
+	s.evict(entry.Key)
+}
+
+// Resize accounts for a change to the metadata of the entry under key, which
+// was previousSize bytes before, evicting others if it no longer fits.
+func (s *EntryList) Resize(key string, previousSize int64) error {
+	entry, err := s.FindEntry(key)
+	if err != nil {
+		return err
+	}
+
+	s.bytes += entry.Size() - previousSize
+	s.evict(key)
+	return nil
+}
+
+func (s *EntryList) full() bool {
+	return (s.depth > 0 && len(s.data) > s.depth) || (s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+// evict drops the entries the policy chooses until the list is within its
+// limits, never dropping keep, the key being written.
+func (s *EntryList) evict(keep string) {
+
+	for s.full() {
+		key, ok := s.policy.Evict(keep)
+		if !ok {
+			return
+		}
+		elem := s.data[key]
+		remove := elem.Value.(*Entry)
+
+		s.tracer.LogInfo("Key", remove.Key, "dropped")
+
+		s.orderedData.Remove(elem)
+		delete(s.data, remove.Key)
+		s.index.Delete(remove.Key)
+		s.bytes -= remove.Size()
+
+		if s.onEvict != nil {
+			s.onEvict(remove)
+		}
+	}
+}
+
+func (s *EntryList) UpdateEntry(key string, value []byte) error {
+	entry, err := s.FindEntry(key)
+	if err != nil {
+		return err
+	}
+
+	size := entry.Size()
+	entry.WriteValue(value)
+	s.bytes += entry.Size() - size
+
+	// push to top as its been written
+	elem := s.data[entry.Key]
+	s.orderedData.MoveToFront(elem)
+	s.policy.Touch(entry.Key)
+
+	s.tracer.LogInfo("Key", entry.Key, "updated")
+
+	s.evict(entry.Key)
+	return nil
+}
+
+func (s *EntryList) ReadEntry(key string) ([]byte, error) {
+
+	entry, err := s.FindEntry(key)
+	if err != nil {
+		return nil, err
+	}
+
+	valeue := entry.ReadValue()
+
+	// push to top as its been read
+	elem := s.data[entry.Key]
+	s.orderedData.MoveToFront(elem)
+	s.policy.Touch(entry.Key)
+
+	s.tracer.LogInfo("Key", entry.Key, "accessed")
+	return valeue, nil
+}
+
+func (s *EntryList) DeleteEntry(key string) error {
+
+	entry, err := s.FindEntry(key)
+	if err != nil {
+		return err
+	}
+
+	//remove from ordered list
+	elem := s.data[key]
+	s.orderedData.Remove(elem)
+
+	// remove from dictionary and key index
+	delete(s.data, entry.Key)
+	s.index.Delete(entry.Key)
+	s.bytes -= entry.Size()
+	s.policy.Remove(entry.Key)
+
+	s.tracer.LogInfo("Key", entry.Key, " deleted")
+
+	return nil
+}
+
+func (s *EntryList) Len() int {
+	return len(s.data)
+}
+
+// Bytes is the size of every entry in the list.
+func (s *EntryList) Bytes() int64 {
+	return s.bytes
+}
+
+func (s *EntryList) FindEntry(key string) (*Entry, error) {
+
+	elem, ok := s.data[key]
+	if !ok {
+		s.tracer.LogError("Key", key, " not found")
+		return nil, common.ErrorKeyNotFound
+	}
+
+	entry := elem.Value.(*Entry)
+	return entry, nil
+}
+
+func (s *EntryList) ListAll() []*Entry {
+
+	// copy them in correct order as the dictionary can be accessed in random order
+	entryList := make([]*Entry, 0, len(s.data))
+	if len(s.data) > 0 {
+		front := s.orderedData.Back()
+		entry := front.Value.(*Entry)
+		entryList = append(entryList, entry.Clone())
+
+		for {
+
+			front = front.Prev()
+			if front == nil {
+				break
+			}
+			entry := front.Value.(*Entry)
+			entryList = append(entryList, entry.Clone())
+		}
+	}
+
+	return entryList
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// EvictionPolicy chooses which key a full EntryList drops. The list tells it
+// about every key added, used or removed, and asks it for a key to evict for
+// as long as it is over its depth or size.
+type EvictionPolicy interface {
+	// Add starts tracking a key written for the first time.
+	Add(key string)
+
+	// Touch records a read or write of a tracked key.
+	Touch(key string)
+
+	// Remove stops tracking a key that was deleted or expired.
+	Remove(key string)
+
+	// Evict stops tracking and returns the key to drop next, which is never
+	// keep, or false if there is nothing else to drop.
+	Evict(keep string) (string, bool)
+}
+
+type Eviction int
+
+const (
+	EvictionLru Eviction = iota
+	EvictionLfu
+	EvictionArc
+	EvictionTinyLfu
+)
+
+func ParseEviction(value string) (Eviction, error) {
+	switch value {
+	case "lru":
+		return EvictionLru, nil
+	case "lfu":
+		return EvictionLfu, nil
+	case "arc":
+		return EvictionArc, nil
+	case "w-tinylfu":
+		return EvictionTinyLfu, nil
+	}
+
+	return EvictionLru, fmt.Errorf("unknown eviction policy %q", value)
+}
+
+func (e Eviction) String() string {
+	switch e {
+	case EvictionLfu:
+		return "lfu"
+	case EvictionArc:
+		return "arc"
+	case EvictionTinyLfu:
+		return "w-tinylfu"
+	default:
+		return "lru"
+	}
+}
+
+func NewEvictionPolicy(eviction Eviction) EvictionPolicy {
+	switch eviction {
+	case EvictionLfu:
+		return NewLfuPolicy()
+	case EvictionArc:
+		return NewArcPolicy()
+	case EvictionTinyLfu:
+		return NewTinyLfuPolicy()
+	default:
+		return NewLruPolicy()
+	}
+}
+
+// keyList is a list of keys in the order they were last used, most recent at
+// the front, that can find any of them in constant time.
+type keyList struct {
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newKeyList() *keyList {
+	return &keyList{order: list.New(), elements: make(map[string]*list.Element)}
+}
+
+func (l *keyList) Len() int {
+	return l.order.Len()
+}
+
+func (l *keyList) Contains(key string) bool {
+	_, ok := l.elements[key]
+	return ok
+}
+
+func (l *keyList) PushFront(key string) {
+	l.elements[key] = l.order.PushFront(key)
+}
+
+func (l *keyList) MoveToFront(key string) {
+	l.order.MoveToFront(l.elements[key])
+}
+
+func (l *keyList) Remove(key string) bool {
+	elem, ok := l.elements[key]
+	if ok {
+		l.order.Remove(elem)
+		delete(l.elements, key)
+	}
+	return ok
+}
+
+// Back returns the least recently used key other than keep.
+func (l *keyList) Back(keep string) (string, bool) {
+	for elem := l.order.Back(); elem != nil; elem = elem.Prev() {
+		if key := elem.Value.(string); key != keep {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// PopBack removes and returns the least recently used key other than keep.
+func (l *keyList) PopBack(keep string) (string, bool) {
+	key, ok := l.Back(keep)
+	if ok {
+		l.Remove(key)
+	}
+	return key, ok
+}
+
+// LruPolicy evicts the least recently used key.
+type LruPolicy struct {
+	keys *keyList
+}
+
+func NewLruPolicy() *LruPolicy {
+	return &LruPolicy{keys: newKeyList()}
+}
+
+func (p *LruPolicy) Add(key string) {
+	p.keys.PushFront(key)
+}
+
+func (p *LruPolicy) Touch(key string) {
+	p.keys.MoveToFront(key)
+}
+
+func (p *LruPolicy) Remove(key string) {
+	p.keys.Remove(key)
+}
+
+func (p *LruPolicy) Evict(keep string) (string, bool) {
+	return p.keys.PopBack(keep)
+}
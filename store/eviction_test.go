@@ -0,0 +1,227 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/users"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+var evictions = []store.Eviction{store.EvictionLru, store.EvictionLfu, store.EvictionArc, store.EvictionTinyLfu}
+
+const traceCapacity = 500
+
+// zipfTrace uses keys with the skewed popularity typical of caches.
+func zipfTrace(length int, keys int, seed int64) []string {
+	random := rand.New(rand.NewSource(seed))
+	zipf := rand.NewZipf(random, 1.1, 1, uint64(keys-1))
+
+	trace := make([]string, length)
+	for i := range trace {
+		trace[i] = fmt.Sprint("key", zipf.Uint64())
+	}
+	return trace
+}
+
+// scanTrace interrupts a zipf trace with batch jobs touching keys only once.
+func scanTrace(length int, keys int, seed int64) []string {
+	trace := make([]string, 0, length)
+	for i, key := range zipfTrace(length, keys, seed) {
+		trace = append(trace, key)
+		if i%5000 == 4999 {
+			for j := 0; j < 2*traceCapacity; j++ {
+				trace = append(trace, fmt.Sprint("scan", i, "-", j))
+			}
+		}
+	}
+	return trace
+}
+
+// loopTrace cycles through more keys than fit, which defeats recency.
+func loopTrace(length int, keys int) []string {
+	trace := make([]string, length)
+	for i := range trace {
+		trace[i] = fmt.Sprint("key", i%keys)
+	}
+	return trace
+}
+
+// hitRatio replays trace against a list holding up to traceCapacity keys,
+// writing every key that misses.
+func hitRatio(eviction store.Eviction, trace []string) float64 {
+	list := store.NewEntryList(CreateMockTracer(), traceCapacity, store.NewEvictionPolicy(eviction))
+
+	hits := 0
+	for _, key := range trace {
+		if _, err := list.ReadEntry(key); err == nil {
+			hits++
+		} else {
+			list.AddEntry(key, []byte(value1), owner1)
+		}
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+func BenchmarkEvictionHitRatio(b *testing.B) {
+
+	traces := []struct {
+		name  string
+		trace []string
+	}{
+		{"zipf", zipfTrace(100000, 10000, 1)},
+		{"scan", scanTrace(100000, 10000, 1)},
+		{"loop", loopTrace(100000, 2*traceCapacity)},
+	}
+
+	for _, trace := range traces {
+		for _, eviction := range evictions {
+			trace, eviction := trace, eviction
+			b.Run(trace.name+"/"+eviction.String(), func(b *testing.B) {
+				ratio := 0.0
+				for i := 0; i < b.N; i++ {
+					ratio = hitRatio(eviction, trace.trace)
+				}
+				b.ReportMetric(ratio, "hit-ratio")
+			})
+		}
+	}
+}
+
+func TestScanResistantPoliciesBeatLruOnScans(t *testing.T) {
+
+	trace := scanTrace(50000, 10000, 2)
+	lru := hitRatio(store.EvictionLru, trace)
+
+	for _, eviction := range []store.Eviction{store.EvictionArc, store.EvictionTinyLfu} {
+		if ratio := hitRatio(eviction, trace); ratio <= lru {
+			t.Errorf("Returned unexpected %v hit ratio: got %v want more than lru's %v", eviction, ratio, lru)
+		}
+	}
+}
+
+func TestEvictionPoliciesKeepWithinDepth(t *testing.T) {
+
+	for _, eviction := range evictions {
+		list := store.NewEntryList(CreateMockTracer(), traceCapacity, store.NewEvictionPolicy(eviction))
+		for i, key := range scanTrace(20000, 2000, 3) {
+			if _, err := list.ReadEntry(key); err != nil {
+				list.AddEntry(key, []byte(value1), owner1)
+			}
+			if i%7 == 0 {
+				list.DeleteEntry(key)
+			}
+			if list.Len() > traceCapacity {
+				t.Fatalf("Returned unexpected %v length: got %v want at most %v", eviction, list.Len(), traceCapacity)
+			}
+		}
+	}
+}
+
+func TestEvictionPoliciesNeverEvictKeptKey(t *testing.T) {
+
+	for _, eviction := range evictions {
+		policy := store.NewEvictionPolicy(eviction)
+		policy.Add(key1)
+		policy.Add(key2)
+		policy.Touch(key2)
+		policy.Touch(key2)
+
+		if key, ok := policy.Evict(key1); !ok || key != key2 {
+			t.Errorf("Returned unexpected %v eviction: got %v, %v want %v", eviction, key, ok, key2)
+		}
+		if key, ok := policy.Evict(key1); ok {
+			t.Errorf("Returned unexpected %v eviction: got %v want none", eviction, key)
+		}
+	}
+}
+
+func TestLfuPolicyEvictsLeastFrequentlyUsed(t *testing.T) {
+
+	policy := store.NewLfuPolicy()
+	for _, key := range []string{"a", "b", "c"} {
+		policy.Add(key)
+	}
+	policy.Touch("a")
+	policy.Touch("a")
+	policy.Touch("c")
+
+	for _, expected := range []string{"b", "c", "a"} {
+		if key, _ := policy.Evict(""); key != expected {
+			t.Errorf("Returned unexpected key: got %v want %v", key, expected)
+		}
+	}
+}
+
+func TestArcPolicyGrowsRecentSideOnGhostHit(t *testing.T) {
+
+	policy := store.NewArcPolicy()
+	policy.Add("a")
+	policy.Add("b")
+	policy.Touch("b")
+
+	if key, _ := policy.Evict(""); key != "a" {
+		t.Errorf("Returned unexpected key: got %v want %v", key, "a")
+	}
+
+	// a was evicted from the keys used once too soon, so they get more room
+	policy.Add("a")
+	policy.Add("c")
+	if key, _ := policy.Evict(""); key != "b" {
+		t.Errorf("Returned unexpected key: got %v want %v", key, "b")
+	}
+}
+
+func TestTinyLfuPolicyRejectsRarelyUsedKeys(t *testing.T) {
+
+	policy := store.NewTinyLfuPolicy()
+	policy.Add("a")
+	policy.Add("popular")
+	for i := 0; i < 5; i++ {
+		policy.Touch("popular")
+	}
+	policy.Add("b")
+
+	if key, _ := policy.Evict("b"); key != "a" {
+		t.Errorf("Returned unexpected key: got %v want %v", key, "a")
+	}
+
+	// once full, b leaving the window has to be used more than popular
+	policy.Add("rare")
+	if key, _ := policy.Evict("rare"); key != "b" {
+		t.Errorf("Returned unexpected key: got %v want %v", key, "b")
+	}
+}
+
+func TestParseEviction(t *testing.T) {
+
+	for _, eviction := range evictions {
+		parsed, err := store.ParseEviction(eviction.String())
+		if err != nil || parsed != eviction {
+			t.Errorf("Returned unexpected eviction: got %v, %v want %v", parsed, err, eviction)
+		}
+	}
+	if _, err := store.ParseEviction("fifo"); err == nil {
+		t.Errorf("Returned unexpected error: got %v want an error", err)
+	}
+}
+
+func TestKvStoreEvictsWithConfiguredPolicy(t *testing.T) {
+
+	config := store.Config{Depth: 2, Eviction: store.EvictionLfu}
+	kvStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), config)
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakeGetRequest(context.Background(), key1, owner1)
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+	kvStore.MakePutRequest(context.Background(), "key3", "value3", owner1)
+
+	// lru would have evicted key1, read before key2 was written
+	if _, err := kvStore.MakeGetRequest(context.Background(), key2, owner1); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+	if value, err := kvStore.MakeGetRequest(context.Background(), key1, owner1); err != nil || value != value1 {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, value1)
+	}
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"container/heap"
+	"demo-store/common"
+	"time"
+)
+
+const DefaultExpiryInterval = time.Second
+
+type expiryItem struct {
+	key     string
+	expires time.Time
+}
+
+// expiryQueue is a min-heap of expiry times. Items are never updated in place:
+// a key whose expiry changes is pushed again and the stale item is skipped
+// when it reaches the top.
+type expiryQueue []expiryItem
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].expires.Before(q[j].expires) }
+func (q expiryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(item any)     { *q = append(*q, item.(expiryItem)) }
+func (q *expiryQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+func (s *KvStore) scheduleExpiry(entry *Entry) {
+	if entry.Expires.IsZero() {
+		return
+	}
+	heap.Push(&s.expiries, expiryItem{key: entry.Key, expires: entry.Expires})
+}
+
+// expireDue removes every entry whose expiry has passed.
+func (s *KvStore) expireDue() {
+
+	now := time.Now()
+	for len(s.expiries) > 0 && !now.Before(s.expiries[0].expires) {
+		item := heap.Pop(&s.expiries).(expiryItem)
+
+		entry, ok := s.entries.data[item.key]
+		if !ok {
+			continue
+		}
+		if current := entry.Value.(*Entry); current.Expires.Equal(item.expires) {
+			s.expire(current)
+		}
+	}
+}
+
+// Expire changes when an existing entry expires, without counting as a write
+// or changing its version. A zero ttl makes the entry permanent.
+func (s *KvStore) Expire(key string, owner string, ttl time.Duration) error {
+
+	entry, err := s.findLiveEntry(key)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authorise(owner, entry, AccessWrite); err != nil {
+		s.Tracer.LogError("User", owner, " cannot change expiry of key.")
+		return err
+	}
+	if ttl < 0 {
+		return common.ErrorInvalidTtl
+	}
+
+	changed := entry.Clone()
+	changed.SetExpiry(ttl)
+	if err := s.recordEntry(LogOpPut, changed); err != nil {
+		return err
+	}
+
+	entry.Expires = changed.Expires
+	s.scheduleExpiry(entry)
+	return nil
+}
+
+func (s *KvStore) expire(entry *Entry) {
+	s.entries.DeleteEntry(entry.Key)
+	s.Tracer.LogInfo("Key", entry.Key, "expired")
+	s.stats.Expirations++
+	s.publish(EventExpire, entry)
+	s.recordEntry(LogOpExpire, &Entry{Key: entry.Key, Version: entry.Version, Timestamp: time.Now()})
+}
+
+// findLiveEntry behaves like FindEntry but treats expired entries as missing,
+// removing them on the way.
+func (s *KvStore) findLiveEntry(key string) (*Entry, error) {
+
+	entry, err := s.entries.FindEntry(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.IsExpired(time.Now()) {
+		s.expire(entry)
+		return nil, common.ErrorKeyNotFound
+	}
+	return entry, nil
+}
@@ -0,0 +1,166 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/users"
+	"testing"
+	"time"
+)
+
+var shortTtl = store.PutOptions{TTL: 50 * time.Millisecond}
+
+func TestExpiredEntryIsNotFound(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, shortTtl)
+	mockStore.MakePutRequest(context.Background(), key2, value2, owner2)
+
+	time.Sleep(2 * shortTtl.TTL)
+
+	if _, err := mockStore.MakeGetRequest(context.Background(), key1, owner1); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+	if _, err := mockStore.MakeListRequest(context.Background(), key1, owner1); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+
+	entries, _ := mockStore.MakeListAllRequest(context.Background(), owner1)
+	if len(entries) != 1 || entries[0].Key != key2 {
+		t.Errorf("Returned unexpected entries: got %v want %v", entries, key2)
+	}
+}
+
+func TestExpiredEntryCanBeClaimedByAnotherOwner(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, shortTtl)
+
+	time.Sleep(2 * shortTtl.TTL)
+
+	if err := mockStore.MakePutRequest(context.Background(), key1, value2, owner2); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+}
+
+func TestPutWithoutTtlClearsExpiry(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, shortTtl)
+	mockStore.MakePutRequest(context.Background(), key1, value2, owner1)
+
+	time.Sleep(2 * shortTtl.TTL)
+
+	value, err := mockStore.MakeGetRequest(context.Background(), key1, owner1)
+	if err != nil || value != value2 {
+		t.Errorf("Returned unexpected value: got %v want %v", value, value2)
+	}
+}
+
+func TestListReturnsRemainingTtl(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, store.PutOptions{TTL: time.Minute})
+	mockStore.MakePutRequest(context.Background(), key2, value2, owner2)
+
+	entry, _ := mockStore.MakeListRequest(context.Background(), key1, owner1)
+	if entry.TTL <= 0 || entry.TTL > time.Minute.Milliseconds() {
+		t.Errorf("Returned unexpected ttl: got %v want %v", entry.TTL, time.Minute.Milliseconds())
+	}
+
+	entry, _ = mockStore.MakeListRequest(context.Background(), key2, owner1)
+	if entry.TTL != 0 {
+		t.Errorf("Returned unexpected ttl: got %v want %v", entry.TTL, 0)
+	}
+}
+
+func TestExpirySweepRemovesEntriesWithoutReads(t *testing.T) {
+
+	dir := t.TempDir()
+	writeLog, _ := store.OpenWriteLog(CreateMockTracer(), dir, store.FsyncAlways, 0)
+	config := store.Config{WriteLog: writeLog, ExpiryInterval: 10 * time.Millisecond}
+	kvStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), config)
+
+	kvStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, shortTtl)
+	time.Sleep(4 * shortTtl.TTL)
+	kvStore.MakeShutdownRequest(context.Background())
+
+	writeLog, _ = store.OpenWriteLog(CreateMockTracer(), dir, store.FsyncAlways, 0)
+	defer writeLog.Close()
+
+	var ops []string
+	writeLog.Replay(0, func(record store.LogRecord) error {
+		ops = append(ops, record.Op)
+		return nil
+	})
+
+	expected := []string{store.LogOpPut, store.LogOpExpire}
+	if len(ops) != len(expected) || ops[1] != expected[1] {
+		t.Errorf("Returned unexpected operations: got %v want %v", ops, expected)
+	}
+}
+
+func TestPersistentStoreRestoresExpiry(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	kvStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, shortTtl)
+	kvStore.MakePutRequestWithOptions(context.Background(), key2, []byte(value2), owner2, store.PutOptions{TTL: time.Minute})
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+	time.Sleep(2 * shortTtl.TTL)
+
+	if _, err := kvStore.MakeGetRequest(context.Background(), key1, owner1); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+	if entry, _ := kvStore.MakeListRequest(context.Background(), key2, owner1); entry == nil || entry.TTL <= 0 {
+		t.Errorf("Returned unexpected entry: got %v want %v", entry, key2)
+	}
+}
+
+func TestExpireChangesExpiryOnly(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	before, _ := mockStore.MakeListRequest(context.Background(), key1, owner1)
+
+	if err := mockStore.MakeExpireRequest(context.Background(), key1, owner1, shortTtl.TTL); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	after, _ := mockStore.MakeListRequest(context.Background(), key1, owner1)
+	if after.TTL <= 0 || after.Version != before.Version || after.Writes != before.Writes {
+		t.Errorf("Returned unexpected entry: got %+v want ttl set on %+v", after, before)
+	}
+
+	time.Sleep(2 * shortTtl.TTL)
+	if _, err := mockStore.MakeGetRequest(context.Background(), key1, owner1); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+}
+
+func TestExpireWithZeroTtlMakesEntryPermanent(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, shortTtl)
+	mockStore.MakeExpireRequest(context.Background(), key1, owner1, 0)
+
+	time.Sleep(2 * shortTtl.TTL)
+	if _, err := mockStore.MakeGetRequest(context.Background(), key1, owner1); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+}
+
+func TestExpireChecksKeyAndOwner(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+
+	if err := mockStore.MakeExpireRequest(context.Background(), key2, owner1, time.Second); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+	if err := mockStore.MakeExpireRequest(context.Background(), key1, owner2, time.Second); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+}
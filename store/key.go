@@ -0,0 +1,27 @@
+package store
+
+import "demo-store/common"
+
+// MaxKeyLength limits the length of a key in bytes.
+const MaxKeyLength = common.MaxKeyLength
+
+// KeySeparator splits hierarchical keys such as team/service/config.
+const KeySeparator = common.KeySeparator
+
+// ValidateKey checks key as common.ValidateKey does, which clients share.
+func ValidateKey(key string) error {
+	return common.ValidateKey(key)
+}
+
+// prefixEnd returns the smallest string greater than every string starting
+// with prefix, or "" if there is none.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+
+	tests := []struct {
+		key      string
+		expected error
+	}{
+		{"key1", nil},
+		{"team/service/config", nil},
+		{"report", nil},
+		{"with space?&%#", nil},
+		{"ключ/値", nil},
+		{strings.Repeat("k", store.MaxKeyLength), nil},
+		{"", common.ErrorKeyNotSet},
+		{strings.Repeat("k", store.MaxKeyLength+1), common.ErrorInvalidKey},
+		{"/team", common.ErrorInvalidKey},
+		{"team/", common.ErrorInvalidKey},
+		{"team//service", common.ErrorInvalidKey},
+		{"team/./service", common.ErrorInvalidKey},
+		{"team/..", common.ErrorInvalidKey},
+		{"line\nbreak", common.ErrorInvalidKey},
+		{"delete\x7f", common.ErrorInvalidKey},
+		{"invalid\xffutf8", common.ErrorInvalidKey},
+	}
+
+	for _, test := range tests {
+		if err := store.ValidateKey(test.key); err != test.expected {
+			t.Errorf("Returned unexpected error for %q: got %v want %v", test.key, err, test.expected)
+		}
+	}
+}
+
+func TestPutRejectsInvalidKey(t *testing.T) {
+
+	kvStore := NewMockStore()
+
+	if err := kvStore.MakePutRequest(context.Background(), "team//service", value1, owner1); err != common.ErrorInvalidKey {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorInvalidKey)
+	}
+
+	operations := []store.BatchOperation{{Op: store.BatchOpPut, Key: "team/", Value: []byte(value1)}}
+	if _, err := kvStore.MakeBatchRequest(context.Background(), operations, owner1); !errors.Is(err, common.ErrorInvalidKey) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorInvalidKey)
+	}
+}
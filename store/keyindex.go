@@ -0,0 +1,116 @@
+package store
+
+import "math/rand"
+
+const keyIndexMaxLevel = 32
+
+type keyIndexNode struct {
+	key  string
+	next []*keyIndexNode
+}
+
+// KeyIndex keeps the store's keys sorted in a skip list so range scans don't
+// need to sort the whole key set on every request.
+type KeyIndex struct {
+	head   *keyIndexNode
+	level  int
+	length int
+	random *rand.Rand
+}
+
+func NewKeyIndex() *KeyIndex {
+	return &KeyIndex{
+		head:   &keyIndexNode{next: make([]*keyIndexNode, keyIndexMaxLevel)},
+		level:  1,
+		random: rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+func (i *KeyIndex) Len() int {
+	return i.length
+}
+
+// Insert adds the key, returning false if it was already present.
+func (i *KeyIndex) Insert(key string) bool {
+
+	var update [keyIndexMaxLevel]*keyIndexNode
+	node := i.head
+	for level := i.level - 1; level >= 0; level-- {
+		for node.next[level] != nil && node.next[level].key < key {
+			node = node.next[level]
+		}
+		update[level] = node
+	}
+
+	if next := node.next[0]; next != nil && next.key == key {
+		return false
+	}
+
+	level := i.randomLevel()
+	for ; i.level < level; i.level++ {
+		update[i.level] = i.head
+	}
+
+	inserted := &keyIndexNode{key: key, next: make([]*keyIndexNode, level)}
+	for l := 0; l < level; l++ {
+		inserted.next[l] = update[l].next[l]
+		update[l].next[l] = inserted
+	}
+
+	i.length++
+	return true
+}
+
+// Delete removes the key, returning false if it was not present.
+func (i *KeyIndex) Delete(key string) bool {
+
+	var update [keyIndexMaxLevel]*keyIndexNode
+	node := i.head
+	for level := i.level - 1; level >= 0; level-- {
+		for node.next[level] != nil && node.next[level].key < key {
+			node = node.next[level]
+		}
+		update[level] = node
+	}
+
+	deleted := node.next[0]
+	if deleted == nil || deleted.key != key {
+		return false
+	}
+
+	for l := 0; l < len(deleted.next); l++ {
+		update[l].next[l] = deleted.next[l]
+	}
+	for i.level > 1 && i.head.next[i.level-1] == nil {
+		i.level--
+	}
+
+	i.length--
+	return true
+}
+
+// Ascend calls visit for every key greater than or equal to from in ascending
+// order until visit returns false.
+func (i *KeyIndex) Ascend(from string, visit func(key string) bool) {
+
+	node := i.head
+	for level := i.level - 1; level >= 0; level-- {
+		for node.next[level] != nil && node.next[level].key < from {
+			node = node.next[level]
+		}
+	}
+
+	for node = node.next[0]; node != nil; node = node.next[0] {
+		if !visit(node.key) {
+			return
+		}
+	}
+}
+
+func (i *KeyIndex) randomLevel() int {
+	level := 1
+	for level < keyIndexMaxLevel && i.random.Intn(4) == 0 {
+		level++
+	}
+	return level
+}
@@ -0,0 +1,76 @@
+package store_test
+
+import (
+	"demo-store/store"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func ascendAll(index *store.KeyIndex, from string) []string {
+	keys := []string{}
+	index.Ascend(from, func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestKeyIndexMatchesSortedKeys(t *testing.T) {
+
+	index := store.NewKeyIndex()
+	expected := make(map[string]bool)
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("key%d", random.Intn(500))
+		if random.Intn(3) == 0 {
+			if index.Delete(key) != expected[key] {
+				t.Fatalf("Returned unexpected delete result for %v", key)
+			}
+			delete(expected, key)
+		} else {
+			if index.Insert(key) == expected[key] {
+				t.Fatalf("Returned unexpected insert result for %v", key)
+			}
+			expected[key] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(expected))
+	for key := range expected {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	if index.Len() != len(sorted) {
+		t.Errorf("Returned unexpected length: got %v want %v", index.Len(), len(sorted))
+	}
+	keys := ascendAll(index, "")
+	if fmt.Sprint(keys) != fmt.Sprint(sorted) {
+		t.Errorf("Returned unexpected keys: got %v want %v", keys, sorted)
+	}
+}
+
+func TestKeyIndexAscendStartsAtKey(t *testing.T) {
+
+	index := store.NewKeyIndex()
+	for _, key := range []string{"b", "d", "a", "c"} {
+		index.Insert(key)
+	}
+
+	keys := ascendAll(index, "bb")
+	if fmt.Sprint(keys) != "[c d]" {
+		t.Errorf("Returned unexpected keys: got %v want %v", keys, "[c d]")
+	}
+
+	var visited []string
+	index.Ascend("", func(key string) bool {
+		visited = append(visited, key)
+		return key != "b"
+	})
+	if fmt.Sprint(visited) != "[a b]" {
+		t.Errorf("Returned unexpected keys: got %v want %v", visited, "[a b]")
+	}
+}
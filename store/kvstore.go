@@ -1,53 +1,344 @@
 package store
 
 import (
+	"context"
 	"demo-store/common"
 	"demo-store/users"
 	"demo-store/utils"
 	"time"
 )
 
-func (s *KvStore) MakePutRequest(key string, value string, owner string) error {
-	req := CreatePutRequest(key, value, owner)
+// MakePutRequest writes a text value, see MakePutRequestWithOptions for
+// values of any kind.
+func (s *KvStore) MakePutRequest(ctx context.Context, key string, value string, owner string) error {
+	return s.MakePutRequestWithOptions(ctx, key, []byte(value), owner, PutOptions{})
+}
+
+func (s *KvStore) MakePutRequestWithOptions(ctx context.Context, key string, value []byte, owner string, options PutOptions) error {
+	_, err := s.MakeWriteRequest(ctx, key, value, owner, options)
+	return err
+}
+
+// MakeWriteRequest puts a value like MakePutRequestWithOptions, returning a
+// copy of the entry written so callers can learn its version.
+func (s *KvStore) MakeWriteRequest(ctx context.Context, key string, value []byte, owner string, options PutOptions) (*Entry, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreatePutRequest(key, value, owner, options)
+	select {
+	case s.putChannel <- req:
+	case <-s.closed:
+		return nil, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case resp := <-req.Response:
+		return resp.Entry, resp.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *KvStore) MakeGetRequest(ctx context.Context, key string, user string) (string, error) {
+	entry, err := s.MakeReadRequest(ctx, key, user)
+	if err != nil {
+		return "", err
+	}
+	return string(entry.Value), nil
+}
+
+func (s *KvStore) MakeReadRequest(ctx context.Context, key string, user string) (*Entry, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateGetRequest(key, user)
+	select {
+	case s.getChannel <- req:
+	case <-s.closed:
+		return nil, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case resp := <-req.Response:
+		return resp.Entry, resp.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *KvStore) MakeListAllRequest(ctx context.Context, user string) ([]*Entry, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateListAllRequest(user)
+	select {
+	case s.listAllChannel <- req:
+	case <-s.closed:
+		return nil, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case entries := <-req.Response:
+		return entries, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *KvStore) MakeListRequest(ctx context.Context, key string, user string) (*Entry, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateListRequest(key, user)
+	select {
+	case s.listChannel <- req:
+	case <-s.closed:
+		return nil, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 
-	s.putChannel <- req
-	return <-req.Response
+	select {
+	case resp := <-req.Response:
+		return resp.Entry, resp.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-func (s *KvStore) MakeGetRequest(key string) (string, error) {
-	req := CreateGetRequest(key)
-	s.getChannel <- req
+func (s *KvStore) MakeScanRequest(ctx context.Context, query ScanQuery) (*ScanResult, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateScanRequest(query)
+	select {
+	case s.scanChannel <- req:
+	case <-s.closed:
+		return nil, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 
-	resp := <-req.Response
-	return resp.Value, resp.Error
+	select {
+	case resp := <-req.Response:
+		return resp.Result, resp.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-func (s *KvStore) MakeListAllRequest() []*Entry {
-	req := CreateListAllRequest()
-	s.listAllChannel <- req
+func (s *KvStore) MakeDeleteRequest(ctx context.Context, key string, owner string) error {
+	return s.MakeDeleteRequestWithOptions(ctx, key, owner, DeleteOptions{})
+}
 
-	return <-req.Response
+func (s *KvStore) MakeDeleteRequestWithOptions(ctx context.Context, key string, owner string, options DeleteOptions) error {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateDeleteRequest(key, owner, options)
+	select {
+	case s.deleteChannel <- req:
+	case <-s.closed:
+		return common.ErrorStoreClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-req.Response:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (s *KvStore) MakeListRequest(key string) (*Entry, error) {
-	req := CreateListRequest(key)
-	s.listChannel <- req
+func (s *KvStore) MakeExpireRequest(ctx context.Context, key string, owner string, ttl time.Duration) error {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateExpireRequest(key, owner, ttl)
+	select {
+	case s.expireChannel <- req:
+	case <-s.closed:
+		return common.ErrorStoreClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-	resp := <-req.Response
-	return resp.Entry, resp.Error
+	select {
+	case err := <-req.Response:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (s *KvStore) MakeDeleteRequest(key string, owner string) error {
-	req := CreateDeleteRequest(key, owner)
-	s.deleteChannel <- req
+func (s *KvStore) MakeAclRequest(ctx context.Context, key string, owner string, change AclChange) (*Entry, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateAclRequest(key, owner, change)
+	select {
+	case s.aclChannel <- req:
+	case <-s.closed:
+		return nil, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 
-	return <-req.Response
+	select {
+	case resp := <-req.Response:
+		return resp.Entry, resp.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-func (s *KvStore) MakeShutdownRequest() {
+func (s *KvStore) MakeBatchRequest(ctx context.Context, operations []BatchOperation, owner string) ([]BatchResult, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateBatchRequest(operations, owner)
+	select {
+	case s.batchChannel <- req:
+	case <-s.closed:
+		return nil, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case resp := <-req.Response:
+		return resp.Results, resp.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// MakeWatchRequest starts a watcher. A watcher started for a caller that gave
+// up waiting is never read from, so it is dropped once its buffer fills.
+func (s *KvStore) MakeWatchRequest(ctx context.Context, query WatchQuery) (*Watcher, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateWatchRequest(query)
+	select {
+	case s.watchChannel <- req:
+	case <-s.closed:
+		return nil, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case resp := <-req.Response:
+		return resp.Watcher, resp.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// MakeUnwatchRequest stops the watcher, discarding any events still queued
+// for it. It takes no context as it is how callers clean up once theirs is
+// done, and returns at once if the store has shut down.
+func (s *KvStore) MakeUnwatchRequest(watcher *Watcher) {
+	req := CreateUnwatchRequest(watcher)
+	for {
+		select {
+		case s.unwatchChannel <- req:
+			// the events are closed once the watcher is removed
+			for range watcher.Events {
+			}
+			return
+		case <-s.closed:
+			return
+		case _, ok := <-watcher.Events:
+			// closed when the store shuts down
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// MakeShutdownRequest stops the store, returning once its write log is
+// closed, or failing with common.ErrorStoreClosed if it already has.
+func (s *KvStore) MakeShutdownRequest(ctx context.Context) error {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
 
 	req := CreateShutdownRequest()
-	s.shutdownChannel <- req
+	select {
+	case s.shutdownChannel <- req:
+	case <-s.closed:
+		return common.ErrorStoreClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-req.Done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *KvStore) MakeSnapshotRequest(ctx context.Context) error {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateSnapshotRequest()
+	select {
+	case s.snapshotChannel <- req:
+	case <-s.closed:
+		return common.ErrorStoreClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-req.Response:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *KvStore) MakeStatsRequest(ctx context.Context) (Stats, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := CreateStatsRequest()
+	select {
+	case s.statsChannel <- req:
+	case <-s.closed:
+		return Stats{}, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return Stats{}, ctx.Err()
+	}
+
+	select {
+	case stats := <-req.Response:
+		return stats, nil
+	case <-ctx.Done():
+		return Stats{}, ctx.Err()
+	}
+}
+
+// requestContext bounds ctx by the store's request timeout, if it has one.
+func (s *KvStore) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.requestTimeout > 0 {
+		return context.WithTimeout(ctx, s.requestTimeout)
+	}
+	return context.WithCancel(ctx)
 }
 
 func (s *KvStore) RegisterShutdownListener(listener *ShutdownListener) {
@@ -56,54 +347,176 @@ func (s *KvStore) RegisterShutdownListener(listener *ShutdownListener) {
 
 func CreateKvStore(tracer utils.Tracer, users users.UserDatabase, depth int) *KvStore {
 
+	kvStore := newKvStore(tracer, users, depth, NewLruPolicy())
+	kvStore.monitor()
+
+	return kvStore
+}
+
+// CreateKvStoreWithConfig creates a store that replays and then appends to the
+// configured write log, if any, before it starts serving requests.
+func CreateKvStoreWithConfig(tracer utils.Tracer, users users.UserDatabase, config Config) (*KvStore, error) {
+
+	kvStore, err := createKvStore(tracer, users, config)
+	if err != nil {
+		return nil, err
+	}
+	kvStore.monitor()
+
+	return kvStore, nil
+}
+
+// createKvStore restores the store from its write log without starting its
+// monitor goroutine.
+func createKvStore(tracer utils.Tracer, users users.UserDatabase, config Config) (*KvStore, error) {
+
+	kvStore := newKvStore(tracer, users, config.Depth, NewEvictionPolicy(config.Eviction))
+	kvStore.entries.SetMaxBytes(config.MaxBytes)
+	kvStore.snapshotInterval = config.SnapshotInterval
+	kvStore.allowEmptyValues = config.AllowEmptyValues
+	kvStore.privateByDefault = config.PrivateByDefault
+	kvStore.requestTimeout = config.RequestTimeout
+	if config.ExpiryInterval > 0 {
+		kvStore.expiryInterval = config.ExpiryInterval
+	}
+	if config.WriteLog != nil {
+		if err := kvStore.restore(config.WriteLog); err != nil {
+			return nil, err
+		}
+	}
+
+	// start the event history after restoring so replayed evictions aren't reported
+	watchHistory := DefaultWatchHistory
+	if config.WatchHistory > 0 {
+		watchHistory = config.WatchHistory
+	}
+	kvStore.watchHub = newWatchHub(watchHistory)
+	kvStore.stats = Stats{Started: time.Now()}
+
+	return kvStore, nil
+}
+
+func newKvStore(tracer utils.Tracer, users users.UserDatabase, depth int, policy EvictionPolicy) *KvStore {
+
 	kvStore := &KvStore{
 		Tracer:           tracer,
-		lruData:          *NewLruEntryList(tracer, depth),
+		entries:          *NewEntryList(tracer, depth, policy),
 		putChannel:       make(chan PutRequest),
 		getChannel:       make(chan GetRequest),
 		listAllChannel:   make(chan ListAllRequest),
 		listChannel:      make(chan ListRequest),
+		scanChannel:      make(chan ScanRequest),
 		deleteChannel:    make(chan DeleteRequest),
+		expireChannel:    make(chan ExpireRequest),
+		aclChannel:       make(chan AclRequest),
 		shutdownChannel:  make(chan ShutdownRequest),
+		snapshotChannel:  make(chan SnapshotRequest),
+		batchChannel:     make(chan BatchRequest),
+		watchChannel:     make(chan WatchRequest),
+		unwatchChannel:   make(chan UnwatchRequest),
+		statsChannel:     make(chan StatsRequest),
+		holdChannel:      make(chan holdRequest),
+		closed:           make(chan struct{}),
 		shutdownListener: nil,
+		unloggedReads:    make(map[string]bool),
+		expiryInterval:   DefaultExpiryInterval,
+		watchHub:         newWatchHub(DefaultWatchHistory),
+		stats:            Stats{Started: time.Now()},
 	}
 
 	kvStore.userDatabase = users
-	kvStore.monitor()
+	kvStore.entries.SetEvictionHandler(kvStore.onEvict)
 
 	return kvStore
 }
+
 func (s *KvStore) UserDatabase() users.UserDatabase {
 	return s.userDatabase
 }
 
 func (s *KvStore) monitor() {
 	go func() {
+		var snapshots <-chan time.Time
+		if s.snapshotInterval > 0 && s.writeLog != nil {
+			ticker := time.NewTicker(s.snapshotInterval)
+			defer ticker.Stop()
+			snapshots = ticker.C
+		}
+
+		expiryTicker := time.NewTicker(s.expiryInterval)
+		defer expiryTicker.Stop()
+
 		shutdown := false
 		for !shutdown {
 			select {
 			case req := <-s.putChannel:
-				err := s.Put(req.Key, req.Value, req.Owner)
-				req.Response <- err
+				entry, err := s.PutWithOptions(req.Key, req.Value, req.Owner, req.Options)
+				req.Response <- CreatePutResponse(entry, err)
 
 			case req := <-s.getChannel:
-				value, err := s.Get(req.Key)
-				req.Response <- CreateGetResponse(value, err)
+				entry, err := s.Read(req.Key, req.User)
+				req.Response <- CreateGetResponse(entry, err)
 
 			case req := <-s.listAllChannel:
-				value := s.ListAll()
+				value := s.ListAll(req.User)
 				req.Response <- value
 
 			case req := <-s.listChannel:
-				value, err := s.List(req.Key)
+				value, err := s.List(req.Key, req.User)
 				req.Response <- CreateListResponse(value, err)
 
+			case req := <-s.scanChannel:
+				result, err := s.Scan(req.Query)
+				req.Response <- CreateScanResponse(result, err)
+
 			case req := <-s.deleteChannel:
-				err := s.Delete(req.Key, req.Owner)
+				err := s.DeleteWithOptions(req.Key, req.Owner, req.Options)
 				req.Response <- err
 
-			case <-s.shutdownChannel:
+			case req := <-s.expireChannel:
+				err := s.Expire(req.Key, req.Owner, req.TTL)
+				req.Response <- err
+
+			case req := <-s.aclChannel:
+				entry, err := s.ChangeAcl(req.Key, req.Owner, req.Change)
+				req.Response <- CreateAclResponse(entry, err)
+
+			case req := <-s.batchChannel:
+				results, err := s.Batch(req.Operations, req.Owner)
+				req.Response <- CreateBatchResponse(results, err)
+
+			case req := <-s.watchChannel:
+				watcher, err := s.Watch(req.Query)
+				req.Response <- CreateWatchResponse(watcher, err)
+
+			case req := <-s.unwatchChannel:
+				s.Unwatch(req.Watcher)
+
+			case req := <-s.statsChannel:
+				req.Response <- s.Stats()
+
+			case req := <-s.holdChannel:
+				req.held <- true
+				<-req.release
+
+			case req := <-s.snapshotChannel:
+				err := s.Snapshot()
+				req.Response <- err
+
+			case <-snapshots:
+				s.Snapshot()
+
+			case <-expiryTicker.C:
+				s.expireDue()
+				s.recordReads()
+
+			case req := <-s.shutdownChannel:
 				shutdown = true
+				close(s.closed)
+				s.recordReads()
+				s.closeWriteLog()
+				s.watchHub.close()
+				close(req.Done)
 				if s.shutdownListener != nil {
 
 					time.Sleep(500 * time.Millisecond)
@@ -114,57 +527,177 @@ func (s *KvStore) monitor() {
 	}()
 }
 
-func (s *KvStore) Put(key string, value string, owner string) error {
+func (s *KvStore) Put(key string, value []byte, owner string) error {
+	_, err := s.PutWithOptions(key, value, owner, PutOptions{})
+	return err
+}
+
+// PutWithOptions returns a copy of the entry written.
+func (s *KvStore) PutWithOptions(key string, value []byte, owner string, options PutOptions) (*Entry, error) {
 
-	entry, err := s.lruData.FindEntry(key)
-	if err != nil {
-		s.lruData.AddEntry(key, value, owner)
-		return nil
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	if len(value) == 0 && !s.allowEmptyValues {
+		return nil, common.ErrorStoreValueNotSet
+	}
+	if _, err := ParseVisibility(string(options.Visibility)); err != nil {
+		return nil, err
+	}
 
-	} else if entry.Owner == owner || s.userDatabase.IsAdmin(owner) {
-		s.lruData.UpdateEntry(key, value)
-		return nil
+	entry, _ := s.findLiveEntry(key)
+	if err := s.authorise(owner, entry, AccessWrite); err != nil {
+		s.Tracer.LogError("User", owner, " cannot update key.")
+		return nil, err
 	}
-	s.Tracer.LogError("User", owner, " cannot update key.")
-	return common.ErrorUnauthorisedOwner
+	// letting others read a key is up to its owner, not users it's shared with
+	if entry != nil && options.Visibility != "" && !s.owns(owner, entry) && !s.userDatabase.HasPermission(owner, users.PermissionOverride) {
+		return nil, common.ErrorUnauthorisedOwner
+	}
+	written := s.staged(key, value, owner, entry, s.version+1, options)
+	if !s.entries.Fits(written) {
+		return nil, common.ErrorValueTooLarge
+	}
+	if err := options.Precondition.Check(entry); err != nil {
+		return nil, err
+	}
+
+	// a write the log can't keep is refused before anyone can see it
+	if err := s.recordEntry(LogOpPut, written); err != nil {
+		return nil, err
+	}
+	s.applyPut(written)
+	return written.Clone(), nil
 }
 
-func (s *KvStore) Get(key string) (string, error) {
+// staged returns the entry as writing value to key, replacing entry if there
+// is one, would leave it, without changing the store.
+func (s *KvStore) staged(key string, value []byte, owner string, entry *Entry, version uint64, options PutOptions) *Entry {
 
-	value, err := s.lruData.ReadEntry(key)
+	var written *Entry
+	if entry == nil {
+		written = NewEntry(key, value, owner)
+		if options.Visibility == "" && s.privateByDefault {
+			options.Visibility = VisibilityPrivate
+		}
+	} else {
+		written = entry.Clone()
+		written.Age, written.TTL = 0, 0
+		written.WriteValue(value)
+	}
+
+	written.Version = version
+	written.Flags = options.Flags
+	written.ContentType = options.ContentType
+	written.ContentEncoding = options.ContentEncoding
+	if options.Visibility != "" {
+		written.Private = options.Visibility == VisibilityPrivate
+	}
+	written.SetExpiry(options.TTL)
+	return written
+}
+
+// applyPut makes a staged write visible once it has been recorded.
+func (s *KvStore) applyPut(written *Entry) {
+	s.version = written.Version
+	s.entries.RestoreEntry(written)
+	s.scheduleExpiry(written)
+	s.stats.Puts++
+	s.publish(EventPut, written)
+}
+
+func (s *KvStore) Get(key string, user string) (string, error) {
+
+	entry, err := s.Read(key, user)
 	if err != nil {
 		return "", err
 	}
-	return value, nil
+	return string(entry.Value), nil
 }
 
-func (s *KvStore) ListAll() []*Entry {
+// Read returns a copy of the entry, counting as a use of the key, if user
+// may read it.
+func (s *KvStore) Read(key string, user string) (*Entry, error) {
 
-	return s.lruData.ListAll()
+	s.stats.Gets++
+	entry, err := s.findLiveEntry(key)
+	if err != nil {
+		s.stats.Misses++
+		return nil, err
+	}
+	if err := s.authorise(user, entry, AccessRead); err != nil {
+		return nil, err
+	}
+
+	s.stats.Hits++
+	s.entries.ReadEntry(key)
+	if s.writeLog != nil {
+		s.unloggedReads[key] = true
+	}
+	return entry.Clone(), nil
+}
+
+// ListAll returns a copy of the entries user may read.
+func (s *KvStore) ListAll(user string) []*Entry {
+
+	s.expireDue()
+	entries := s.entries.ListAll()
+	readable := entries[:0]
+	for _, entry := range entries {
+		if s.readable(user, entry) {
+			readable = append(readable, entry)
+		}
+	}
+	return readable
 }
 
-func (s *KvStore) List(key string) (*Entry, error) {
+// List returns a copy of the entry, as if it didn't exist if user may not
+// read it.
+func (s *KvStore) List(key string, user string) (*Entry, error) {
 
-	entry, err := s.lruData.FindEntry(key)
+	entry, err := s.findLiveEntry(key)
 	if err != nil {
 		return nil, err
 	}
+	if !s.readable(user, entry) {
+		return nil, common.ErrorKeyNotFound
+	}
 
 	return entry.Clone(), nil
 }
 
 func (s *KvStore) Delete(key string, owner string) error {
+	return s.DeleteWithOptions(key, owner, DeleteOptions{})
+}
 
-	entry, err := s.lruData.FindEntry(key)
+func (s *KvStore) DeleteWithOptions(key string, owner string, options DeleteOptions) error {
+
+	entry, err := s.findLiveEntry(key)
 	if err != nil {
+		if preconditionErr := options.Precondition.Check(nil); preconditionErr != nil {
+			return preconditionErr
+		}
 		return err
 	}
 
-	if entry.Owner == owner || s.userDatabase.IsAdmin(owner) {
-		s.lruData.DeleteEntry(key)
-		return nil
+	if err := s.authorise(owner, entry, AccessDelete); err != nil {
+		s.Tracer.LogError("User", owner, " cannot delete key.")
+		return err
+	}
+	if err := options.Precondition.Check(entry); err != nil {
+		return err
 	}
 
-	s.Tracer.LogError("User", owner, " cannot delete key.")
-	return common.ErrorUnauthorisedOwner
+	if err := s.recordEntry(LogOpDelete, entry); err != nil {
+		return err
+	}
+	s.applyDelete(entry)
+	return nil
+}
+
+// applyDelete removes the entry once its deletion has been recorded.
+func (s *KvStore) applyDelete(entry *Entry) {
+	s.entries.DeleteEntry(entry.Key)
+	s.stats.Deletes++
+	s.publish(EventDelete, entry)
 }
@@ -1,9 +1,13 @@
 package store_test
 
 import (
+	"context"
 	"demo-store/common"
 	"demo-store/store"
+	"demo-store/users"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestUsers(t *testing.T) {
@@ -26,10 +30,10 @@ func TestPutOwnerCanChangeTheValue(t *testing.T) {
 	value2 := "data2"
 	owner := "testUser1"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value1, owner)
-	mockStore.Put(key, value2, owner)
-	newEntry, _ := mockStore.List(key)
-	if newEntry.Value != value2 {
+	mockStore.Put(key, []byte(value1), owner)
+	mockStore.Put(key, []byte(value2), owner)
+	newEntry, _ := mockStore.List(key, owner1)
+	if string(newEntry.Value) != value2 {
 		t.Errorf("Second Put unexpected value got %s want %s", value2, newEntry.Value)
 	}
 }
@@ -42,9 +46,9 @@ func TestPutDifferntOwnerCannotChangeExistingValue(t *testing.T) {
 	owner := "tesUser1"
 	owner2 := "testUser2"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value1, owner)
+	mockStore.Put(key, []byte(value1), owner)
 
-	err := mockStore.Put(key, value2, owner2)
+	err := mockStore.Put(key, []byte(value2), owner2)
 	if err != common.ErrorUnauthorisedOwner {
 		t.Errorf("Unexpected error got %v want %v", err, common.ErrorUnauthorisedOwner)
 	}
@@ -56,14 +60,14 @@ func TestPutReturnsErrorIfKeyNotExist(t *testing.T) {
 	value := "data1"
 	owner := "testUser1"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner)
+	mockStore.Put(key, []byte(value), owner)
 
-	newEntry, _ := mockStore.List(key)
+	newEntry, _ := mockStore.List(key, owner1)
 	if newEntry == nil {
 		t.Errorf("Expected key %s not found", key)
 	}
 
-	if newEntry.Value != value {
+	if string(newEntry.Value) != value {
 		t.Errorf("Expected value %s not found", value)
 	}
 	if newEntry.Owner != owner {
@@ -77,12 +81,12 @@ func TestListAllReturnsItemAdded(t *testing.T) {
 	value := "data1"
 	owner := "testUser1"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner)
+	mockStore.Put(key, []byte(value), owner)
 
-	entries := mockStore.ListAll()
+	entries := mockStore.ListAll(owner1)
 
 	newEntry := entries[0].String()
-	expected := store.NewEntry(key, value, owner).String()
+	expected := store.NewEntry(key, []byte(value), owner).String()
 
 	if newEntry != expected {
 		t.Errorf("Get unexpected error got %v want %v", newEntry, expected)
@@ -93,7 +97,7 @@ func TestListReturnsEmtpyList(t *testing.T) {
 
 	mockStore := NewMockStore()
 
-	entries := mockStore.ListAll()
+	entries := mockStore.ListAll(owner1)
 	if entries == nil {
 		t.Errorf("Unexpected error: List is nil,")
 	}
@@ -109,9 +113,9 @@ func TestListKeyReturnsTheKeyAndEntry(t *testing.T) {
 	value := "data1"
 	owner := "testUser1"
 	store := NewMockStore()
-	store.Put(key, value, owner)
+	store.Put(key, []byte(value), owner)
 
-	entry, error := store.List(key)
+	entry, error := store.List(key, owner1)
 	if error != nil {
 		t.Errorf("Expected key %s not found", key)
 	}
@@ -120,7 +124,7 @@ func TestListKeyReturnsTheKeyAndEntry(t *testing.T) {
 		t.Errorf("Expected value %s not found", value)
 	}
 
-	if entry.Value != value {
+	if string(entry.Value) != value {
 		t.Errorf("Expected value %s not found", value)
 	}
 
@@ -134,7 +138,7 @@ func TestListKeyReturnsErrorIfKeyNotExists(t *testing.T) {
 	key := "key1"
 	mockStore := NewMockStore()
 
-	_, error := mockStore.List(key)
+	_, error := mockStore.List(key, owner1)
 	if error != common.ErrorKeyNotFound {
 		t.Errorf("Unexpected error: got %v want %v,", error, common.ErrorKeyNotFound)
 	}
@@ -146,8 +150,8 @@ func TestGetReturnsValueFromMatchingKey(t *testing.T) {
 	value := "data1"
 	owner := "testUser1"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner)
-	entryValue, _ := mockStore.Get(key)
+	mockStore.Put(key, []byte(value), owner)
+	entryValue, _ := mockStore.Get(key, owner1)
 
 	if entryValue != value {
 		t.Errorf("Unexpected error: got %v want %v,", entryValue, value)
@@ -158,7 +162,7 @@ func TestGetReturnsErrorIfKeyNotExists(t *testing.T) {
 
 	key := "key1"
 	mockStore := NewMockStore()
-	_, err := mockStore.Get(key)
+	_, err := mockStore.Get(key, owner1)
 	if err != common.ErrorKeyNotFound {
 		t.Errorf("Unexpected error: got %v want %v,", err, common.ErrorKeyNotFound)
 	}
@@ -181,12 +185,12 @@ func TestDeleteRemovesKey(t *testing.T) {
 	value := "data1"
 	owner1 := "testUser1"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner1)
+	mockStore.Put(key, []byte(value), owner1)
 	err := mockStore.Delete(key, owner1)
 	if err != nil {
 		t.Errorf("Unexpected error: got %v want %v,", err, "nil")
 	}
-	_, err = mockStore.List(key)
+	_, err = mockStore.List(key, owner1)
 	if err != common.ErrorKeyNotFound {
 		t.Errorf("Unexpected error: got %v want %v,", err, common.ErrorKeyNotFound)
 	}
@@ -199,7 +203,7 @@ func TestDeleteOnlyAuthorisedOwnerCanRemoveKey(t *testing.T) {
 	owner1 := "testUser1"
 	owner2 := "testUser2"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner1)
+	mockStore.Put(key, []byte(value), owner1)
 	err := mockStore.Delete(key, owner2)
 	if err != common.ErrorUnauthorisedOwner {
 		t.Errorf("Unexpected error: got %v want %v,", err, common.ErrorKeyNotFound)
@@ -214,7 +218,7 @@ func TestDeleteAdminCanOverrideUsery(t *testing.T) {
 	owner2 := "admin"
 	mockStore := NewMockStore()
 	mockStore.UserDatabase().AddUser("admin", "111")
-	mockStore.Put(key, value, owner1)
+	mockStore.Put(key, []byte(value), owner1)
 	err := mockStore.Delete(key, owner2)
 	if err != nil {
 		t.Errorf("Unexpected error: got %v want %v,", err, "nil")
@@ -228,7 +232,7 @@ func TestDeleteOnlyOwnerCanDleeteKey(t *testing.T) {
 	owner1 := "testUser1"
 	owner2 := "testUser2"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner1)
+	mockStore.Put(key, []byte(value), owner1)
 	err := mockStore.Delete(key, owner2)
 	if err != common.ErrorUnauthorisedOwner {
 		t.Errorf("Unexpected error: got %v want %v,", err, "nil")
@@ -242,10 +246,168 @@ func TestPutOriginalOwnerAfterAdminOverridwsValueOnlyAuthorisedOwnerCanRemoveKey
 	owner1 := "testUser1"
 
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner1)
-	mockStore.Put(key, "updated value", "admin")
-	err := mockStore.Put(key, "override updated value", owner1)
+	mockStore.Put(key, []byte(value), owner1)
+	mockStore.Put(key, []byte("updated value"), "admin")
+	err := mockStore.Put(key, []byte("override updated value"), owner1)
 	if err != nil {
 		t.Errorf("Unexpected error: got %v want %v,", err, common.ErrorKeyNotFound)
 	}
 }
+
+func TestPutRejectsEmptyValueUnlessAllowed(t *testing.T) {
+
+	operations := []store.BatchOperation{{Op: store.BatchOpPut, Key: key2}}
+
+	mockStore := NewMockStore()
+	if err := mockStore.MakePutRequest(context.Background(), key1, "", owner1); err != common.ErrorStoreValueNotSet {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorStoreValueNotSet)
+	}
+	if _, err := mockStore.MakeBatchRequest(context.Background(), operations, owner1); !errors.Is(err, common.ErrorStoreValueNotSet) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorStoreValueNotSet)
+	}
+
+	config := store.Config{AllowEmptyValues: true}
+	allowing, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), config)
+	if err := allowing.MakePutRequest(context.Background(), key1, "", owner1); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if _, err := allowing.MakeBatchRequest(context.Background(), operations, owner1); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	entry, err := allowing.MakeReadRequest(context.Background(), key1, owner1)
+	if err != nil || len(entry.Value) != 0 {
+		t.Errorf("Returned unexpected entry: got %v, %v want an empty value", entry, err)
+	}
+}
+
+func TestPutRejectsValueLargerThanMaxBytes(t *testing.T) {
+
+	config := store.Config{MaxBytes: 2 * store.EntryOverhead}
+	mockStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), config)
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+
+	large := make([]byte, 2*store.EntryOverhead)
+	if err := mockStore.MakePutRequestWithOptions(context.Background(), key2, large, owner2, store.PutOptions{}); err != common.ErrorValueTooLarge {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorValueTooLarge)
+	}
+	operations := []store.BatchOperation{{Op: store.BatchOpPut, Key: key2, Value: large}}
+	if _, err := mockStore.MakeBatchRequest(context.Background(), operations, owner2); !errors.Is(err, common.ErrorValueTooLarge) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorValueTooLarge)
+	}
+
+	// the rejected writes didn't evict anything
+	if value, err := mockStore.MakeGetRequest(context.Background(), key1, owner1); err != nil || value != value1 {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, value1)
+	}
+}
+
+func TestPutEvictsToStayWithinMaxBytes(t *testing.T) {
+
+	config := store.Config{MaxBytes: 2 * store.EntryOverhead}
+	mockStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), config)
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	options := store.PutOptions{ContentType: "text/csv"}
+	mockStore.MakePutRequestWithOptions(context.Background(), key2, make([]byte, store.EntryOverhead/2), owner2, options)
+
+	stats, _ := mockStore.MakeStatsRequest(context.Background())
+	if stats.Keys != 1 || stats.Evictions != 1 || stats.MaxBytes != config.MaxBytes {
+		t.Errorf("Returned unexpected stats: got %+v", stats)
+	}
+	entry, _ := mockStore.MakeListRequest(context.Background(), key2, owner1)
+	if stats.Bytes != entry.Size() {
+		t.Errorf("Returned unexpected bytes: got %v want %v", stats.Bytes, entry.Size())
+	}
+}
+
+func TestRequestsAfterShutdownFailWithStoreClosed(t *testing.T) {
+
+	stores := []store.Store{NewMockStore(), NewMockShardedStore(t, store.Config{})}
+	for _, kvStore := range stores {
+		kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+		watcher, _ := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Prefix: true})
+
+		if err := kvStore.MakeShutdownRequest(context.Background()); err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		kvStore.MakeUnwatchRequest(watcher)
+
+		if _, err := kvStore.MakeGetRequest(context.Background(), key1, owner1); err != common.ErrorStoreClosed {
+			t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorStoreClosed)
+		}
+		if err := kvStore.MakePutRequest(context.Background(), key2, value2, owner2); err != common.ErrorStoreClosed {
+			t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorStoreClosed)
+		}
+		if _, err := kvStore.MakeListAllRequest(context.Background(), owner1); err != common.ErrorStoreClosed {
+			t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorStoreClosed)
+		}
+		if _, err := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Prefix: true}); err != common.ErrorStoreClosed {
+			t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorStoreClosed)
+		}
+		if err := kvStore.MakeShutdownRequest(context.Background()); err != common.ErrorStoreClosed {
+			t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorStoreClosed)
+		}
+	}
+}
+
+// stalledUserDatabase holds up the store's monitor goroutine when checking if
+// a user may override another's key until released, standing in for a store
+// that stopped answering.
+type stalledUserDatabase struct {
+	users.UserDatabase
+	stalled chan bool
+	release chan bool
+}
+
+func (u *stalledUserDatabase) HasPermission(username string, permission users.Permission) bool {
+	if permission != users.PermissionOverride {
+		return u.UserDatabase.HasPermission(username, permission)
+	}
+	u.stalled <- true
+	<-u.release
+	return false
+}
+
+func TestRequestsGiveUpOnStalledStore(t *testing.T) {
+
+	userDatabase := &stalledUserDatabase{UserDatabase: users.CreateUserDatabase(), stalled: make(chan bool), release: make(chan bool)}
+	kvStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), userDatabase, store.Config{RequestTimeout: 50 * time.Millisecond})
+	defer close(userDatabase.release)
+
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	go kvStore.MakePutRequest(context.Background(), key1, value2, owner2)
+	<-userDatabase.stalled
+
+	if _, err := kvStore.MakeGetRequest(context.Background(), key1, owner1); err != context.DeadlineExceeded {
+		t.Errorf("Returned unexpected error: got %v want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := kvStore.MakeGetRequest(ctx, key1, owner1); err != context.Canceled {
+		t.Errorf("Returned unexpected error: got %v want %v", err, context.Canceled)
+	}
+}
+
+func TestRolesLimitWhoCanWriteKeys(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.UserDatabase().AddUserWithRoles("reader", "1111", []users.Role{users.RoleReader})
+	mockStore.UserDatabase().AddUserWithRoles("editor", "2222", []users.Role{users.RoleAdmin})
+	mockStore.Put(key1, []byte(value1), owner1)
+
+	if err := mockStore.Put(key2, []byte(value1), "reader"); err != common.ErrorPermissionDenied {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPermissionDenied)
+	}
+	if err := mockStore.Delete(key1, "reader"); err != common.ErrorPermissionDenied {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPermissionDenied)
+	}
+	if err := mockStore.Put(key1, []byte(value2), "editor"); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	mockStore.UserDatabase().SetRoles("editor", []users.Role{users.RoleWriter})
+	if err := mockStore.Delete(key1, "editor"); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+}
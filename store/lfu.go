@@ -0,0 +1,81 @@
+package store
+
+import "container/list"
+
+type lfuBucket struct {
+	count int
+	keys  *keyList
+}
+
+// LfuPolicy evicts the least frequently used key, the least recently used of
+// them on a tie. Keys are kept in buckets of equal use counts, ordered by
+// count, so every operation takes constant time.
+type LfuPolicy struct {
+	buckets *list.List
+	keys    map[string]*list.Element
+}
+
+func NewLfuPolicy() *LfuPolicy {
+	return &LfuPolicy{buckets: list.New(), keys: make(map[string]*list.Element)}
+}
+
+func (p *LfuPolicy) Add(key string) {
+	front := p.buckets.Front()
+	if front == nil || front.Value.(*lfuBucket).count != 1 {
+		front = p.buckets.PushFront(&lfuBucket{count: 1, keys: newKeyList()})
+	}
+	p.insert(key, front)
+}
+
+func (p *LfuPolicy) Touch(key string) {
+	elem, ok := p.keys[key]
+	if !ok {
+		return
+	}
+
+	bucket := elem.Value.(*lfuBucket)
+	next := elem.Next()
+	if next == nil || next.Value.(*lfuBucket).count != bucket.count+1 {
+		next = p.buckets.InsertAfter(&lfuBucket{count: bucket.count + 1, keys: newKeyList()}, elem)
+	}
+
+	p.Remove(key)
+	p.insert(key, next)
+}
+
+func (p *LfuPolicy) Remove(key string) {
+	elem, ok := p.keys[key]
+	if !ok {
+		return
+	}
+
+	bucket := elem.Value.(*lfuBucket)
+	bucket.keys.Remove(key)
+	delete(p.keys, key)
+	if bucket.keys.Len() == 0 {
+		p.buckets.Remove(elem)
+	}
+}
+
+func (p *LfuPolicy) Evict(keep string) (string, bool) {
+	for elem := p.buckets.Front(); elem != nil; elem = elem.Next() {
+		if key, ok := elem.Value.(*lfuBucket).keys.Back(keep); ok {
+			p.Remove(key)
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Count is how many times key was used while tracked.
+func (p *LfuPolicy) Count(key string) int {
+	if elem, ok := p.keys[key]; ok {
+		return elem.Value.(*lfuBucket).count
+	}
+	return 0
+}
+
+func (p *LfuPolicy) insert(key string, elem *list.Element) {
+	elem.Value.(*lfuBucket).keys.PushFront(key)
+	p.keys[key] = elem
+}
@@ -19,14 +19,14 @@ func TestLruListReadEntry(t *testing.T) {
 
 	list := store.NewLruEntryList(CreateMockTracer(), 0)
 
-	list.AddEntry(key1, value1, owner1)
+	list.AddEntry(key1, []byte(value1), owner1)
 
 	value, err := list.ReadEntry(key1)
 
 	if err != nil {
 		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
 	}
-	if value != value1 {
+	if string(value) != value1 {
 		t.Errorf("Returned unexpected value: got %v want %v", value, value1)
 	}
 }
@@ -35,16 +35,16 @@ func TestLruListUpdateEntryIfKeyExists(t *testing.T) {
 
 	list := store.NewLruEntryList(CreateMockTracer(), 0)
 
-	list.AddEntry(key1, value1, owner1)
+	list.AddEntry(key1, []byte(value1), owner1)
 
-	err := list.UpdateEntry(key1, value2)
+	err := list.UpdateEntry(key1, []byte(value2))
 
 	if err != nil {
 		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
 	}
 
 	value, err := list.ReadEntry(key1)
-	if value != value2 {
+	if string(value) != value2 {
 		t.Errorf("Returned unexpected value: got %v want %v", value, value1)
 	}
 }
@@ -53,7 +53,7 @@ func TestLruListUpdateEntryFailsIfKeyExists(t *testing.T) {
 
 	list := store.NewLruEntryList(CreateMockTracer(), 0)
 
-	err := list.UpdateEntry(key1, value2)
+	err := list.UpdateEntry(key1, []byte(value2))
 
 	expected := common.ErrorKeyNotFound
 	if err != expected {
@@ -65,10 +65,10 @@ func TestLruListUpdateEntryUpdatesWritesCount(t *testing.T) {
 
 	list := store.NewLruEntryList(CreateMockTracer(), 0)
 
-	list.AddEntry(key1, value1, owner1)
+	list.AddEntry(key1, []byte(value1), owner1)
 	numbOfWrites := 10
 	for i := 0; i < numbOfWrites; i++ {
-		list.UpdateEntry(key1, value2)
+		list.UpdateEntry(key1, []byte(value2))
 	}
 
 	expected := numbOfWrites + 1 // include the initial added write
@@ -95,7 +95,7 @@ func TestLruListReadEntryUpdatesReadCount(t *testing.T) {
 
 	list := store.NewLruEntryList(CreateMockTracer(), 0)
 
-	list.AddEntry(key1, value1, owner1)
+	list.AddEntry(key1, []byte(value1), owner1)
 	numbOfReads := 10
 	for i := 0; i < numbOfReads; i++ {
 		list.ReadEntry(key1)
@@ -123,7 +123,7 @@ func TestLruListFindEntryFailsIfKeyExists(t *testing.T) {
 func TestLruListFindEntryReturnsEntry(t *testing.T) {
 
 	list := store.NewLruEntryList(CreateMockTracer(), 0)
-	list.AddEntry(key1, value1, owner1)
+	list.AddEntry(key1, []byte(value1), owner1)
 	entry, _ := list.FindEntry(key1)
 
 	expected := key1
@@ -131,7 +131,7 @@ func TestLruListFindEntryReturnsEntry(t *testing.T) {
 		t.Errorf("Returned unexpected error: got %v want %v", entry.Key, expected)
 	}
 
-	expectedEntry := store.NewEntry(key1, value1, owner1).String()
+	expectedEntry := store.NewEntry(key1, []byte(value1), owner1).String()
 	if entry.String() != expectedEntry {
 		t.Errorf("Returned unexpected error: got %v want %v", entry.String(), expectedEntry)
 	}
@@ -152,7 +152,7 @@ func TestLruDeleteEntryFailsIfKeyExists(t *testing.T) {
 func TestLruDeleteEntryRemovesEntry(t *testing.T) {
 
 	list := store.NewLruEntryList(CreateMockTracer(), 0)
-	list.AddEntry(key1, value1, owner1)
+	list.AddEntry(key1, []byte(value1), owner1)
 	list.DeleteEntry(key1)
 
 	_, err := list.FindEntry(key1)
@@ -168,7 +168,7 @@ func TestLruDepthRemoveTheFirstItemsAdded(t *testing.T) {
 
 	numberOfInserts := 15
 	for i := 0; i < numberOfInserts; i++ {
-		list.AddEntry(fmt.Sprint("key", i), value1, owner1)
+		list.AddEntry(fmt.Sprint("key", i), []byte(value1), owner1)
 	}
 
 	numberOfRemoved := 5
@@ -188,20 +188,20 @@ func TestLruDepthPushesFirsKeyToTopAfterARead(t *testing.T) {
 
 	numberOfInserts := 5
 	for i := 0; i < numberOfInserts; i++ {
-		list.AddEntry(fmt.Sprint("key", i), value1, owner1)
+		list.AddEntry(fmt.Sprint("key", i), []byte(value1), owner1)
 	}
 
 	expected := value2
-	list.UpdateEntry("key0", expected)
+	list.UpdateEntry("key0", []byte(expected))
 
 	numberOfNewInserts := 2
 	for i := numberOfInserts; i < numberOfNewInserts; i++ {
-		list.AddEntry(fmt.Sprint("key", i), value1, owner1)
+		list.AddEntry(fmt.Sprint("key", i), []byte(value1), owner1)
 	}
 
 	entry, _ := list.FindEntry("key0")
 
-	if entry.Value != expected {
+	if string(entry.Value) != expected {
 		t.Errorf("Returned unexpected value: got %v want %v", entry.Value, expected)
 	}
 }
@@ -211,16 +211,67 @@ func TestListAllReturnsAllEntrie(t *testing.T) {
 
 	numberOfInserts := 15
 	for i := 0; i < numberOfInserts; i++ {
-		list.AddEntry(fmt.Sprint("key", i), fmt.Sprint("value", i), fmt.Sprint("owner", i))
+		list.AddEntry(fmt.Sprint("key", i), []byte(fmt.Sprint("value", i)), fmt.Sprint("owner", i))
 	}
 
 	entries := list.ListAll()
 
 	for i, entry := range entries {
 
-		expectedEntry := store.NewEntry(fmt.Sprint("key", i), fmt.Sprint("value", i), fmt.Sprint("owner", i))
+		expectedEntry := store.NewEntry(fmt.Sprint("key", i), []byte(fmt.Sprint("value", i)), fmt.Sprint("owner", i))
 		if entry.String() != expectedEntry.String() {
 			t.Errorf("Returned unexpected key: got %v want %v", entry.Key, expectedEntry.String())
 		}
 	}
 }
+
+func TestLruListEvictsLeastRecentlyUsedOverMaxBytes(t *testing.T) {
+
+	size := store.NewEntry("key0", []byte(value1), owner1).Size()
+	list := store.NewLruEntryList(CreateMockTracer(), 0)
+	list.SetMaxBytes(3 * size)
+
+	for i := 0; i < 3; i++ {
+		list.AddEntry(fmt.Sprint("key", i), []byte(value1), owner1)
+	}
+	list.ReadEntry("key0")
+	list.AddEntry("key3", []byte(value1), owner1)
+
+	if _, err := list.FindEntry("key1"); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+	if list.Len() != 3 || list.Bytes() != 3*size {
+		t.Errorf("Returned unexpected size: got %v keys %v bytes want %v keys %v bytes", list.Len(), list.Bytes(), 3, 3*size)
+	}
+
+	// growing a value evicts as much as it needs to
+	list.UpdateEntry("key3", make([]byte, len(value1)+int(size)))
+	if list.Len() != 2 || list.Bytes() > 3*size {
+		t.Errorf("Returned unexpected size: got %v keys %v bytes want %v keys", list.Len(), list.Bytes(), 2)
+	}
+	if _, err := list.FindEntry("key2"); err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+}
+
+func TestLruListAppliesDepthAndMaxBytesTogether(t *testing.T) {
+
+	size := store.NewEntry("key0", []byte(value1), owner1).Size()
+	list := store.NewLruEntryList(CreateMockTracer(), 2)
+	list.SetMaxBytes(10 * size)
+
+	for i := 0; i < 3; i++ {
+		list.AddEntry(fmt.Sprint("key", i), []byte(value1), owner1)
+	}
+	if list.Len() != 2 {
+		t.Errorf("Returned unexpected length: got %v want %v", list.Len(), 2)
+	}
+
+	list.SetMaxBytes(size)
+	if list.Len() != 1 {
+		t.Errorf("Returned unexpected length: got %v want %v", list.Len(), 1)
+	}
+	if _, err := list.FindEntry("key2"); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+}
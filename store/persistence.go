@@ -0,0 +1,205 @@
+package store
+
+import (
+	"demo-store/common"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// restore rebuilds the store from the latest snapshot and the write log
+// segments written after it, then starts recording every further mutation.
+func (s *KvStore) restore(writeLog *WriteLog) error {
+
+	fromSegment := 0
+	snapshot, err := ReadSnapshot(filepath.Join(writeLog.Dir(), SnapshotFileName))
+	if err == nil {
+		for i := range snapshot.Entries {
+			s.entries.RestoreEntry(snapshot.Entries[i].Entry())
+			s.restoreVersion(snapshot.Entries[i].Version)
+		}
+		s.restoreVersion(snapshot.LastVersion)
+		fromSegment = snapshot.Segment
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	err = writeLog.Replay(fromSegment, s.applyLogRecord)
+	if err != nil {
+		return err
+	}
+
+	s.writeLog = writeLog
+	for _, elem := range s.entries.data {
+		s.scheduleExpiry(elem.Value.(*Entry))
+	}
+
+	s.Tracer.LogInfo("Restored", len(s.entries.data), "keys from", writeLog.Path())
+	return nil
+}
+
+func (s *KvStore) restoreVersion(version uint64) {
+	if version > s.version {
+		s.version = version
+	}
+}
+
+func (s *KvStore) applyLogRecord(record LogRecord) error {
+
+	s.restoreVersion(record.Version)
+	switch record.Op {
+	case LogOpPut:
+		s.entries.RestoreEntry(record.Entry())
+
+	case LogOpRead:
+		entry, err := s.entries.FindEntry(record.Key)
+		if err != nil {
+			// evicted by a smaller depth than the log was written with
+			return nil
+		}
+		entry.Reads = record.Reads
+		entry.Timestamp = record.Timestamp
+		s.entries.RestoreEntry(entry)
+
+	case LogOpBatch:
+		// a batch is checked whole first, so a corrupt one stops the replay
+		// rather than being half applied
+		for _, nested := range record.Records {
+			if nested.Op != LogOpPut && nested.Op != LogOpRead && nested.Op != LogOpDelete {
+				return fmt.Errorf("%w: batch holds unknown operation %q", common.ErrorWriteLogCorrupt, nested.Op)
+			}
+		}
+		for _, nested := range record.Records {
+			if err := s.applyLogRecord(nested); err != nil {
+				return err
+			}
+		}
+
+	case LogOpDelete, LogOpEvict, LogOpExpire:
+		if _, ok := s.entries.data[record.Key]; ok {
+			s.entries.DeleteEntry(record.Key)
+		}
+
+	default:
+		s.Tracer.LogWarning("Skipping unknown write log operation", record.Op)
+	}
+
+	return nil
+}
+
+// Snapshot writes the whole store, in LRU order, next to the write log and
+// drops the log segments it supersedes.
+func (s *KvStore) Snapshot() error {
+
+	if s.writeLog == nil {
+		return common.ErrorPersistenceDisabled
+	}
+
+	segment, err := s.writeLog.Rotate()
+	if err != nil {
+		return err
+	}
+
+	// the snapshot holds the read counters, so they needn't be logged
+	s.unloggedReads = make(map[string]bool)
+
+	entries := s.entries.ListAll()
+	snapshot := Snapshot{Segment: segment, LastVersion: s.version, Entries: make([]LogRecord, 0, len(entries))}
+	for _, entry := range entries {
+		snapshot.Entries = append(snapshot.Entries, CreateLogRecord(LogOpPut, entry))
+	}
+
+	err = WriteSnapshot(filepath.Join(s.writeLog.Dir(), SnapshotFileName), &snapshot)
+	if err != nil {
+		s.Tracer.LogError("Error writing snapshot", err)
+		return err
+	}
+
+	s.Tracer.LogInfo("Snapshot of", len(entries), "keys written, write log at segment", segment)
+	return s.writeLog.RemoveSegmentsBefore(segment)
+}
+
+func (s *KvStore) record(op string, key string) error {
+
+	if s.writeLog == nil {
+		return nil
+	}
+
+	entry, err := s.entries.FindEntry(key)
+	if err != nil {
+		return err
+	}
+	return s.recordEntry(op, entry)
+}
+
+func (s *KvStore) recordEntry(op string, entry *Entry) error {
+
+	if s.writeLog == nil {
+		return nil
+	}
+
+	err := s.writeLog.Append(CreateLogRecord(op, entry))
+	if err != nil {
+		s.Tracer.LogError("Error appending to write log", err)
+	}
+	return err
+}
+
+func (s *KvStore) recordEntries(records []LogRecord) error {
+
+	if s.writeLog == nil {
+		return nil
+	}
+
+	err := s.writeLog.Append(LogRecord{Op: LogOpBatch, Records: records, Timestamp: time.Now()})
+	if err != nil {
+		s.Tracer.LogError("Error appending to write log", err)
+	}
+	return err
+}
+
+// recordReads appends the counters and timestamps of the keys read since the
+// last call in one record, oldest read first, rather than one record per read.
+// An append that fails only loses those counters, so reads never fail for it.
+func (s *KvStore) recordReads() {
+
+	if s.writeLog == nil || len(s.unloggedReads) == 0 {
+		return
+	}
+
+	records := make([]LogRecord, 0, len(s.unloggedReads))
+	for key := range s.unloggedReads {
+		if elem, ok := s.entries.data[key]; ok {
+			records = append(records, CreateLogRecord(LogOpRead, elem.Value.(*Entry)))
+		}
+	}
+	s.unloggedReads = make(map[string]bool)
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	if len(records) > 0 {
+		s.recordEntries(records)
+	}
+}
+
+func (s *KvStore) onEvict(entry *Entry) {
+	s.stats.Evictions++
+	s.publish(EventEvict, entry)
+	s.recordEntry(LogOpEvict, &Entry{Key: entry.Key, Version: entry.Version, Timestamp: time.Now()})
+}
+
+func (s *KvStore) closeWriteLog() {
+
+	if s.writeLog == nil {
+		return
+	}
+
+	if err := s.writeLog.Close(); err != nil {
+		s.Tracer.LogError("Error closing write log", err)
+	}
+	s.writeLog = nil
+}
@@ -0,0 +1,45 @@
+package store
+
+import "demo-store/common"
+
+// VersionMatch matches an entry if it exists and, unless Any is set, has one
+// of the listed versions.
+type VersionMatch struct {
+	Any      bool
+	Versions []uint64
+}
+
+func (m *VersionMatch) Matches(entry *Entry) bool {
+	if entry == nil {
+		return false
+	}
+	if m.Any {
+		return true
+	}
+
+	for _, version := range m.Versions {
+		if version == entry.Version {
+			return true
+		}
+	}
+	return false
+}
+
+// Precondition guards a write on the current version of an entry, following
+// the semantics of the HTTP If-Match and If-None-Match headers.
+type Precondition struct {
+	IfMatch     *VersionMatch
+	IfNoneMatch *VersionMatch
+}
+
+// Check returns common.ErrorPreconditionFailed unless the precondition holds
+// for the entry, which is nil if the key does not exist.
+func (p *Precondition) Check(entry *Entry) error {
+	if p.IfMatch != nil && !p.IfMatch.Matches(entry) {
+		return common.ErrorPreconditionFailed
+	}
+	if p.IfNoneMatch != nil && p.IfNoneMatch.Matches(entry) {
+		return common.ErrorPreconditionFailed
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"testing"
+)
+
+func ifMatch(versions ...uint64) store.Precondition {
+	return store.Precondition{IfMatch: &store.VersionMatch{Versions: versions}}
+}
+
+var createOnly = store.Precondition{IfNoneMatch: &store.VersionMatch{Any: true}}
+
+func TestPutIncreasesVersion(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	first, _ := mockStore.MakeListRequest(context.Background(), key1, owner1)
+	mockStore.MakePutRequest(context.Background(), key2, value2, owner2)
+	mockStore.MakePutRequest(context.Background(), key1, value2, owner1)
+	second, _ := mockStore.MakeListRequest(context.Background(), key1, owner1)
+
+	if first.Version == 0 || second.Version <= first.Version {
+		t.Errorf("Returned unexpected version: got %v want greater than %v", second.Version, first.Version)
+	}
+
+	mockStore.MakeGetRequest(context.Background(), key1, owner1)
+	read, _ := mockStore.MakeReadRequest(context.Background(), key1, owner1)
+	if read.Version != second.Version {
+		t.Errorf("Returned unexpected version: got %v want %v", read.Version, second.Version)
+	}
+}
+
+func TestPutIfMatchRequiresCurrentVersion(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	entry, _ := mockStore.MakeListRequest(context.Background(), key1, owner1)
+
+	err := mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value2), owner1, store.PutOptions{Precondition: ifMatch(entry.Version)})
+	if err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	err = mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, store.PutOptions{Precondition: ifMatch(entry.Version)})
+	if err != common.ErrorPreconditionFailed {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPreconditionFailed)
+	}
+
+	value, _ := mockStore.MakeGetRequest(context.Background(), key1, owner1)
+	if value != value2 {
+		t.Errorf("Returned unexpected value: got %v want %v", value, value2)
+	}
+}
+
+func TestPutIfNoneMatchAnyIsCreateOnly(t *testing.T) {
+
+	mockStore := NewMockStore()
+
+	err := mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, store.PutOptions{Precondition: createOnly})
+	if err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	err = mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value2), owner1, store.PutOptions{Precondition: createOnly})
+	if err != common.ErrorPreconditionFailed {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPreconditionFailed)
+	}
+}
+
+func TestPutOwnershipIsCheckedBeforePrecondition(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+
+	err := mockStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value2), owner2, store.PutOptions{Precondition: createOnly})
+	if err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+}
+
+func TestDeleteIfMatch(t *testing.T) {
+
+	mockStore := NewMockStore()
+	mockStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	entry, _ := mockStore.MakeListRequest(context.Background(), key1, owner1)
+
+	err := mockStore.MakeDeleteRequestWithOptions(context.Background(), key1, owner1, store.DeleteOptions{Precondition: ifMatch(entry.Version + 1)})
+	if err != common.ErrorPreconditionFailed {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPreconditionFailed)
+	}
+
+	err = mockStore.MakeDeleteRequestWithOptions(context.Background(), key1, owner1, store.DeleteOptions{Precondition: ifMatch(entry.Version)})
+	if err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	err = mockStore.MakeDeleteRequestWithOptions(context.Background(), key1, owner1, store.DeleteOptions{Precondition: ifMatch(entry.Version)})
+	if err != common.ErrorPreconditionFailed {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPreconditionFailed)
+	}
+}
+
+func TestVersionsAreNotReusedAfterRestart(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+	deleted, _ := kvStore.MakeListRequest(context.Background(), key2, owner1)
+	kvStore.MakeDeleteRequest(context.Background(), key2, owner2)
+	kvStore.MakeSnapshotRequest(context.Background())
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+
+	entry, _ := kvStore.MakeListRequest(context.Background(), key2, owner1)
+	if entry.Version <= deleted.Version {
+		t.Errorf("Returned unexpected version: got %v want greater than %v", entry.Version, deleted.Version)
+	}
+}
@@ -1,74 +1,204 @@
 package store
 
+import "time"
+
 type PutRequest struct {
 	Key      string
-	Value    string
+	Value    []byte
 	Owner    string
-	Response chan error
+	Options  PutOptions
+	Response chan PutResponse
 }
 
 type GetRequest struct {
 	Key      string
+	User     string
 	Response chan GetResponse
 }
 
 type ListAllRequest struct {
+	User     string
 	Response chan []*Entry
 }
 
 type ListRequest struct {
 	Key      string
+	User     string
 	Response chan ListResponse
 }
 
+type ScanRequest struct {
+	Query    ScanQuery
+	Response chan ScanResponse
+}
+
 type DeleteRequest struct {
 	Key      string
 	Owner    string
+	Options  DeleteOptions
+	Response chan error
+}
+
+type ExpireRequest struct {
+	Key      string
+	Owner    string
+	TTL      time.Duration
 	Response chan error
 }
 
+type AclRequest struct {
+	Key      string
+	Owner    string
+	Change   AclChange
+	Response chan AclResponse
+}
+
+type BatchRequest struct {
+	Operations []BatchOperation
+	Owner      string
+	Response   chan BatchResponse
+}
+
+type WatchRequest struct {
+	Query    WatchQuery
+	Response chan WatchResponse
+}
+
+type UnwatchRequest struct {
+	Watcher *Watcher
+}
+
+type StatsRequest struct {
+	Response chan Stats
+}
+
 type ShutdownRequest struct {
+	// Done is closed once the store has flushed and closed its write log.
+	Done chan struct{}
+}
+
+type SnapshotRequest struct {
+	Response chan error
 }
 
 type ShutdownListener struct {
 	Listener chan bool
 }
 
+type PutResponse struct {
+	Entry *Entry
+	Error error
+}
+
 type GetResponse struct {
-	Value string
+	Entry *Entry
 	Error error
 }
+type AclResponse struct {
+	Entry *Entry
+	Error error
+}
+
+type BatchResponse struct {
+	Results []BatchResult
+	Error   error
+}
+
+type WatchResponse struct {
+	Watcher *Watcher
+	Error   error
+}
+
+type ScanResponse struct {
+	Result *ScanResult
+	Error  error
+}
+
 type ListResponse struct {
 	Entry *Entry
 	Error error
 }
 
-func CreatePutRequest(key string, value string, owner string) PutRequest {
-	return PutRequest{Key: key, Value: value, Owner: owner, Response: make(chan error)}
+// Responses are buffered so the monitor goroutine never waits for a caller
+// that gave up on its request.
+func CreatePutRequest(key string, value []byte, owner string, options PutOptions) PutRequest {
+	return PutRequest{Key: key, Value: value, Owner: owner, Options: options, Response: make(chan PutResponse, 1)}
+}
+
+func CreateGetRequest(key string, user string) GetRequest {
+	return GetRequest{Key: key, User: user, Response: make(chan GetResponse, 1)}
+}
+
+func CreateListAllRequest(user string) ListAllRequest {
+	return ListAllRequest{User: user, Response: make(chan []*Entry, 1)}
+}
+
+func CreateListRequest(key string, user string) ListRequest {
+	return ListRequest{Key: key, User: user, Response: make(chan ListResponse, 1)}
+}
+
+func CreateScanRequest(query ScanQuery) ScanRequest {
+	return ScanRequest{Query: query, Response: make(chan ScanResponse, 1)}
+}
+
+func CreateDeleteRequest(key string, owner string, options DeleteOptions) DeleteRequest {
+	return DeleteRequest{Key: key, Owner: owner, Options: options, Response: make(chan error, 1)}
 }
 
-func CreateGetRequest(key string) GetRequest {
-	return GetRequest{Key: key, Response: make(chan GetResponse)}
+func CreateExpireRequest(key string, owner string, ttl time.Duration) ExpireRequest {
+	return ExpireRequest{Key: key, Owner: owner, TTL: ttl, Response: make(chan error, 1)}
 }
 
-func CreateListAllRequest() ListAllRequest {
-	return ListAllRequest{Response: make(chan []*Entry)}
+func CreateAclRequest(key string, owner string, change AclChange) AclRequest {
+	return AclRequest{Key: key, Owner: owner, Change: change, Response: make(chan AclResponse, 1)}
 }
 
-func CreateListRequest(key string) ListRequest {
-	return ListRequest{Key: key, Response: make(chan ListResponse)}
+func CreateBatchRequest(operations []BatchOperation, owner string) BatchRequest {
+	return BatchRequest{Operations: operations, Owner: owner, Response: make(chan BatchResponse, 1)}
 }
 
-func CreateDeleteRequest(key string, owner string) DeleteRequest {
-	return DeleteRequest{Key: key, Owner: owner, Response: make(chan error)}
+func CreateWatchRequest(query WatchQuery) WatchRequest {
+	return WatchRequest{Query: query, Response: make(chan WatchResponse, 1)}
+}
+
+func CreateUnwatchRequest(watcher *Watcher) UnwatchRequest {
+	return UnwatchRequest{Watcher: watcher}
+}
+
+func CreateStatsRequest() StatsRequest {
+	return StatsRequest{Response: make(chan Stats, 1)}
 }
 
 func CreateShutdownRequest() ShutdownRequest {
-	return ShutdownRequest{}
+	return ShutdownRequest{Done: make(chan struct{})}
+}
+
+func CreateSnapshotRequest() SnapshotRequest {
+	return SnapshotRequest{Response: make(chan error, 1)}
+}
+
+func CreatePutResponse(entry *Entry, err error) PutResponse {
+	return PutResponse{Entry: entry, Error: err}
+}
+
+func CreateGetResponse(entry *Entry, err error) GetResponse {
+	return GetResponse{Entry: entry, Error: err}
+}
+
+func CreateAclResponse(entry *Entry, err error) AclResponse {
+	return AclResponse{Entry: entry, Error: err}
+}
+
+func CreateBatchResponse(results []BatchResult, err error) BatchResponse {
+	return BatchResponse{Results: results, Error: err}
+}
+
+func CreateWatchResponse(watcher *Watcher, err error) WatchResponse {
+	return WatchResponse{Watcher: watcher, Error: err}
 }
 
-func CreateGetResponse(value string, err error) GetResponse {
-	return GetResponse{Value: value, Error: err}
+func CreateScanResponse(result *ScanResult, err error) ScanResponse {
+	return ScanResponse{Result: result, Error: err}
 }
 
 func CreateListResponse(entry *Entry, err error) ListResponse {
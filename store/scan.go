@@ -0,0 +1,330 @@
+package store
+
+import (
+	"demo-store/common"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SortByKey     = "key"
+	SortByOwner   = "owner"
+	SortByReads   = "reads"
+	SortByWrites  = "writes"
+	SortByAge     = "age"
+	SortByTtl     = "ttl"
+	SortByVersion = "version"
+)
+
+// ScanQuery selects a page of entries. Start is inclusive, End exclusive and
+// Cursor resumes after the last entry of a previous page with the same Sort.
+// Entries are ordered by key unless Sort names another Entry field, ties being
+// broken by key. A zero Limit returns every matching entry.
+//
+// A Delimiter lists the keys under Prefix like a directory: keys with the
+// delimiter after the prefix are rolled up into a single common prefix ending
+// at the delimiter, which counts towards the Limit like an entry. Delimiters
+// are only supported in ascending key order.
+type ScanQuery struct {
+	Prefix     string
+	Delimiter  string
+	Start      string
+	End        string
+	Cursor     string
+	Limit      int
+	Filter     ScanFilter
+	Sort       string
+	Descending bool
+
+	// Reader is the user scanning, leaving out the keys they may not read.
+	Reader string
+}
+
+// ScanFilter restricts a scan to entries matching every field that is set.
+type ScanFilter struct {
+	Owner  string
+	Age    Range
+	Reads  Range
+	Writes Range
+}
+
+// Range holds inclusive bounds, nil meaning unbounded.
+type Range struct {
+	Min *int64
+	Max *int64
+}
+
+func (r Range) Contains(value int64) bool {
+	if r.Min != nil && value < *r.Min {
+		return false
+	}
+	if r.Max != nil && value > *r.Max {
+		return false
+	}
+	return true
+}
+
+func (f *ScanFilter) Matches(entry *Entry, now time.Time) bool {
+	if f.Owner != "" && entry.Owner != f.Owner {
+		return false
+	}
+	return f.Age.Contains(now.Sub(entry.Timestamp).Milliseconds()) &&
+		f.Reads.Contains(int64(entry.Reads)) &&
+		f.Writes.Contains(int64(entry.Writes))
+}
+
+type ScanResult struct {
+	Entries        []*Entry `json:"entries"`
+	CommonPrefixes []string `json:"common_prefixes,omitempty"`
+	NextCursor     string   `json:"next_cursor,omitempty"`
+}
+
+// scanPosition is where an entry falls in a sort order: by Text or Number,
+// whichever the sort uses, then by Key.
+type scanPosition struct {
+	Text   string `json:"t,omitempty"`
+	Number int64  `json:"n,omitempty"`
+	Key    string `json:"k"`
+
+	// Under places the position after every key starting with Key, once a
+	// page ends on a common prefix.
+	Under bool `json:"u,omitempty"`
+}
+
+// scanCursor records the sort a page was taken with so a cursor can't be
+// replayed against a different order.
+type scanCursor struct {
+	Sort       string       `json:"s"`
+	Descending bool         `json:"d,omitempty"`
+	Position   scanPosition `json:"p"`
+}
+
+func (c *scanCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (*scanCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, common.ErrorInvalidQuery
+	}
+
+	var decoded scanCursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, common.ErrorInvalidQuery
+	}
+	return &decoded, nil
+}
+
+// position places the entry in the sort order. Age and ttl sort on the
+// timestamps behind them so positions don't drift while a client pages.
+func position(sortBy string, entry *Entry) scanPosition {
+	switch sortBy {
+	case SortByOwner:
+		return scanPosition{Text: entry.Owner, Key: entry.Key}
+	case SortByReads:
+		return scanPosition{Number: int64(entry.Reads), Key: entry.Key}
+	case SortByWrites:
+		return scanPosition{Number: int64(entry.Writes), Key: entry.Key}
+	case SortByAge:
+		return scanPosition{Number: -entry.Timestamp.UnixNano(), Key: entry.Key}
+	case SortByTtl:
+		// entries that never expire sort after those that do
+		if entry.Expires.IsZero() {
+			return scanPosition{Number: math.MaxInt64, Key: entry.Key}
+		}
+		return scanPosition{Number: entry.Expires.UnixNano(), Key: entry.Key}
+	case SortByVersion:
+		return scanPosition{Number: int64(entry.Version), Key: entry.Key}
+	default:
+		return scanPosition{Key: entry.Key}
+	}
+}
+
+func (p scanPosition) less(other scanPosition) bool {
+	if p.Text != other.Text {
+		return p.Text < other.Text
+	}
+	if p.Number != other.Number {
+		return p.Number < other.Number
+	}
+	return p.Key < other.Key
+}
+
+func IsSortField(field string) bool {
+	switch field {
+	case SortByKey, SortByOwner, SortByReads, SortByWrites, SortByAge, SortByTtl, SortByVersion:
+		return true
+	}
+	return false
+}
+
+// Scan returns a page of entries. Pages in ascending key order walk the key
+// index from the first key the query allows, so their cost depends on the
+// page rather than the size of the store; other orders sort the entries in
+// range first.
+func (s *KvStore) Scan(query ScanQuery) (*ScanResult, error) {
+
+	if query.Limit < 0 {
+		return nil, common.ErrorInvalidQuery
+	}
+	if query.Sort == "" {
+		query.Sort = SortByKey
+	}
+	if !IsSortField(query.Sort) {
+		return nil, common.ErrorInvalidQuery
+	}
+	if query.Delimiter != "" && (query.Sort != SortByKey || query.Descending) {
+		return nil, common.ErrorInvalidQuery
+	}
+
+	var after *scanPosition
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != query.Sort || cursor.Descending != query.Descending {
+			return nil, common.ErrorInvalidQuery
+		}
+		after = &cursor.Position
+	}
+
+	s.expireDue()
+
+	if query.Sort == SortByKey && !query.Descending {
+		return s.scanIndex(query, after), nil
+	}
+	return s.scanSorted(query, after), nil
+}
+
+func (s *KvStore) scanIndex(query ScanQuery, after *scanPosition) *ScanResult {
+
+	from := query.Start
+	if query.Prefix > from {
+		from = query.Prefix
+	}
+	if after != nil && after.Key > from {
+		from = after.Key
+		if end := prefixEnd(after.Key); after.Under && end != "" {
+			from = end
+		}
+	}
+
+	now := time.Now()
+	result := &ScanResult{Entries: []*Entry{}}
+	var last scanPosition
+	s.scanRange(query, from, func(entry *Entry) bool {
+		if after != nil && (entry.Key <= after.Key || (after.Under && strings.HasPrefix(entry.Key, after.Key))) {
+			return true
+		}
+		if !query.Filter.Matches(entry, now) || !s.readable(query.Reader, entry) {
+			return true
+		}
+
+		commonPrefix := query.commonPrefix(entry.Key)
+		if commonPrefix != "" && last.Under && last.Key == commonPrefix {
+			return true
+		}
+		if query.Limit > 0 && len(result.Entries)+len(result.CommonPrefixes) == query.Limit {
+			cursor := scanCursor{Sort: query.Sort, Position: last}
+			result.NextCursor = cursor.encode()
+			return false
+		}
+
+		if commonPrefix != "" {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix)
+			last = scanPosition{Key: commonPrefix, Under: true}
+		} else {
+			result.Entries = append(result.Entries, entry.Clone())
+			last = position(query.Sort, entry)
+		}
+		return true
+	})
+
+	return result
+}
+
+// commonPrefix returns the prefix the key is rolled up into when listing with
+// a delimiter, or "" if the key is listed itself.
+func (q *ScanQuery) commonPrefix(key string) string {
+	if q.Delimiter == "" {
+		return ""
+	}
+	rest := strings.TrimPrefix(key, q.Prefix)
+	if i := strings.Index(rest, q.Delimiter); i >= 0 {
+		return q.Prefix + rest[:i+len(q.Delimiter)]
+	}
+	return ""
+}
+
+func (s *KvStore) scanSorted(query ScanQuery, after *scanPosition) *ScanResult {
+
+	from := query.Start
+	if query.Prefix > from {
+		from = query.Prefix
+	}
+
+	now := time.Now()
+	var matched []*Entry
+	s.scanRange(query, from, func(entry *Entry) bool {
+		if query.Filter.Matches(entry, now) && s.readable(query.Reader, entry) {
+			matched = append(matched, entry)
+		}
+		return true
+	})
+
+	before := func(a, b scanPosition) bool {
+		if query.Descending {
+			return b.less(a)
+		}
+		return a.less(b)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return before(position(query.Sort, matched[i]), position(query.Sort, matched[j]))
+	})
+
+	result := &ScanResult{Entries: []*Entry{}}
+	for _, entry := range matched {
+		if after != nil && !before(*after, position(query.Sort, entry)) {
+			continue
+		}
+		if query.Limit > 0 && len(result.Entries) == query.Limit {
+			result.NextCursor = s.cursorAfter(query, result.Entries)
+			break
+		}
+		result.Entries = append(result.Entries, entry.Clone())
+	}
+
+	return result
+}
+
+// scanRange visits the entries from the given key onwards that fall within the
+// query's prefix and end, in key order, until visit returns false.
+func (s *KvStore) scanRange(query ScanQuery, from string, visit func(entry *Entry) bool) {
+	s.entries.index.Ascend(from, func(key string) bool {
+		if query.End != "" && key >= query.End {
+			return false
+		}
+		if !strings.HasPrefix(key, query.Prefix) {
+			return false
+		}
+
+		entry, err := s.entries.FindEntry(key)
+		if err != nil {
+			return true
+		}
+		return visit(entry)
+	})
+}
+
+func (s *KvStore) cursorAfter(query ScanQuery, entries []*Entry) string {
+	last := entries[len(entries)-1]
+	cursor := scanCursor{Sort: query.Sort, Descending: query.Descending, Position: position(query.Sort, last)}
+	return cursor.encode()
+}
@@ -0,0 +1,309 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/users"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func scannedKeys(result *store.ScanResult) []string {
+	keys := []string{}
+	for _, entry := range result.Entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func newMockScanStore(keys ...string) *store.KvStore {
+	kvStore := NewMockStore()
+	for _, key := range keys {
+		kvStore.MakePutRequest(context.Background(), key, value1, owner1)
+	}
+	return kvStore
+}
+
+func TestScanReturnsKeysInOrder(t *testing.T) {
+
+	kvStore := newMockScanStore("c", "a", "b")
+
+	result, err := kvStore.MakeScanRequest(context.Background(), store.ScanQuery{})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if keys := fmt.Sprint(scannedKeys(result)); keys != "[a b c]" {
+		t.Errorf("Returned unexpected keys: got %v want %v", keys, "[a b c]")
+	}
+	if result.NextCursor != "" {
+		t.Errorf("Returned unexpected cursor: got %v want %v", result.NextCursor, "")
+	}
+}
+
+func TestScanFiltersByPrefixAndRange(t *testing.T) {
+
+	kvStore := newMockScanStore("a/1", "a/2", "a/3", "b/1", "ab")
+
+	tests := []struct {
+		query    store.ScanQuery
+		expected string
+	}{
+		{store.ScanQuery{Prefix: "a/"}, "[a/1 a/2 a/3]"},
+		{store.ScanQuery{Start: "a/2"}, "[a/2 a/3 ab b/1]"},
+		{store.ScanQuery{End: "a/3"}, "[a/1 a/2]"},
+		{store.ScanQuery{Prefix: "a/", Start: "a/2", End: "a/3"}, "[a/2]"},
+		{store.ScanQuery{Prefix: "c"}, "[]"},
+	}
+
+	for _, test := range tests {
+		result, err := kvStore.MakeScanRequest(context.Background(), test.query)
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		if keys := fmt.Sprint(scannedKeys(result)); keys != test.expected {
+			t.Errorf("Returned unexpected keys for %+v: got %v want %v", test.query, keys, test.expected)
+		}
+	}
+}
+
+func TestScanPagesWithCursor(t *testing.T) {
+
+	kvStore := newMockScanStore("k1", "k2", "k3", "k4", "k5")
+
+	var pages []string
+	query := store.ScanQuery{Limit: 2}
+	for {
+		result, err := kvStore.MakeScanRequest(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		pages = append(pages, fmt.Sprint(scannedKeys(result)))
+		if result.NextCursor == "" {
+			break
+		}
+		query.Cursor = result.NextCursor
+	}
+
+	expected := "[[k1 k2] [k3 k4] [k5]]"
+	if fmt.Sprint(pages) != expected {
+		t.Errorf("Returned unexpected pages: got %v want %v", pages, expected)
+	}
+}
+
+func TestScanCursorSurvivesDeletedKey(t *testing.T) {
+
+	kvStore := newMockScanStore("k1", "k2", "k3")
+
+	result, _ := kvStore.MakeScanRequest(context.Background(), store.ScanQuery{Limit: 2})
+	kvStore.MakeDeleteRequest(context.Background(), "k2", owner1)
+
+	result, err := kvStore.MakeScanRequest(context.Background(), store.ScanQuery{Limit: 2, Cursor: result.NextCursor})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if keys := fmt.Sprint(scannedKeys(result)); keys != "[k3]" {
+		t.Errorf("Returned unexpected keys: got %v want %v", keys, "[k3]")
+	}
+}
+
+func TestScanSkipsExpiredEntries(t *testing.T) {
+
+	kvStore := newMockScanStore("k1")
+	kvStore.MakePutRequestWithOptions(context.Background(), "k2", []byte(value1), owner1, store.PutOptions{TTL: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+
+	result, _ := kvStore.MakeScanRequest(context.Background(), store.ScanQuery{})
+	if keys := fmt.Sprint(scannedKeys(result)); keys != "[k1]" {
+		t.Errorf("Returned unexpected keys: got %v want %v", keys, "[k1]")
+	}
+}
+
+func TestScanRejectsInvalidCursor(t *testing.T) {
+
+	kvStore := newMockScanStore("k1")
+
+	_, err := kvStore.MakeScanRequest(context.Background(), store.ScanQuery{Cursor: "not a cursor"})
+	if !errors.Is(err, common.ErrorInvalidQuery) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorInvalidQuery)
+	}
+}
+
+func TestEvictedKeysLeaveIndex(t *testing.T) {
+
+	kvStore := store.CreateKvStore(CreateMockTracer(), users.CreateUserDatabase(), 2)
+	for _, key := range []string{"k1", "k2", "k3"} {
+		kvStore.MakePutRequest(context.Background(), key, value1, owner1)
+	}
+
+	result, _ := kvStore.MakeScanRequest(context.Background(), store.ScanQuery{})
+	if keys := fmt.Sprint(scannedKeys(result)); keys != "[k2 k3]" {
+		t.Errorf("Returned unexpected keys: got %v want %v", keys, "[k2 k3]")
+	}
+}
+
+func int64Pointer(value int64) *int64 {
+	return &value
+}
+
+func TestScanFiltersByOwnerAndCounters(t *testing.T) {
+
+	kvStore := NewMockStore()
+	kvStore.MakePutRequest(context.Background(), "k1", value1, owner1)
+	kvStore.MakePutRequest(context.Background(), "k2", value1, owner2)
+	kvStore.MakePutRequest(context.Background(), "k3", value1, owner1)
+	kvStore.MakePutRequest(context.Background(), "k3", value2, owner1)
+	kvStore.MakeGetRequest(context.Background(), "k1", owner1)
+
+	tests := []struct {
+		filter   store.ScanFilter
+		expected string
+	}{
+		{store.ScanFilter{Owner: owner1}, "[k1 k3]"},
+		{store.ScanFilter{Reads: store.Range{Min: int64Pointer(1)}}, "[k1]"},
+		{store.ScanFilter{Reads: store.Range{Max: int64Pointer(0)}}, "[k2 k3]"},
+		{store.ScanFilter{Owner: owner1, Writes: store.Range{Min: int64Pointer(2)}}, "[k3]"},
+		{store.ScanFilter{Age: store.Range{Min: int64Pointer(time.Hour.Milliseconds())}}, "[]"},
+	}
+
+	for _, test := range tests {
+		result, err := kvStore.MakeScanRequest(context.Background(), store.ScanQuery{Filter: test.filter})
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		if keys := fmt.Sprint(scannedKeys(result)); keys != test.expected {
+			t.Errorf("Returned unexpected keys for %+v: got %v want %v", test.filter, keys, test.expected)
+		}
+	}
+}
+
+func TestScanSortsByField(t *testing.T) {
+
+	kvStore := newMockScanStore("a", "b", "c")
+	kvStore.MakeGetRequest(context.Background(), "b", owner1)
+	kvStore.MakeGetRequest(context.Background(), "b", owner1)
+	kvStore.MakeGetRequest(context.Background(), "c", owner1)
+
+	tests := []struct {
+		query    store.ScanQuery
+		expected string
+	}{
+		{store.ScanQuery{Sort: store.SortByReads}, "[a c b]"},
+		{store.ScanQuery{Sort: store.SortByReads, Descending: true}, "[b c a]"},
+		{store.ScanQuery{Sort: store.SortByKey, Descending: true}, "[c b a]"},
+		{store.ScanQuery{Sort: store.SortByAge}, "[c b a]"},
+		{store.ScanQuery{Sort: store.SortByVersion, Descending: true}, "[c b a]"},
+	}
+
+	for _, test := range tests {
+		result, err := kvStore.MakeScanRequest(context.Background(), test.query)
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		if keys := fmt.Sprint(scannedKeys(result)); keys != test.expected {
+			t.Errorf("Returned unexpected keys for %+v: got %v want %v", test.query, keys, test.expected)
+		}
+	}
+}
+
+func TestScanPagesSortedResults(t *testing.T) {
+
+	kvStore := newMockScanStore("k1", "k2", "k3", "k4", "k5")
+	kvStore.MakeGetRequest(context.Background(), "k2", owner1)
+	kvStore.MakeGetRequest(context.Background(), "k4", owner1)
+
+	var pages []string
+	query := store.ScanQuery{Limit: 2, Sort: store.SortByReads, Descending: true}
+	for {
+		result, err := kvStore.MakeScanRequest(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		pages = append(pages, fmt.Sprint(scannedKeys(result)))
+		if result.NextCursor == "" {
+			break
+		}
+		query.Cursor = result.NextCursor
+	}
+
+	expected := "[[k4 k2] [k5 k3] [k1]]"
+	if fmt.Sprint(pages) != expected {
+		t.Errorf("Returned unexpected pages: got %v want %v", pages, expected)
+	}
+}
+
+func TestScanRejectsCursorFromAnotherSort(t *testing.T) {
+
+	kvStore := newMockScanStore("k1", "k2")
+
+	result, _ := kvStore.MakeScanRequest(context.Background(), store.ScanQuery{Limit: 1})
+	_, err := kvStore.MakeScanRequest(context.Background(), store.ScanQuery{Limit: 1, Sort: store.SortByReads, Cursor: result.NextCursor})
+	if !errors.Is(err, common.ErrorInvalidQuery) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorInvalidQuery)
+	}
+}
+
+func TestScanWithDelimiterListsCommonPrefixes(t *testing.T) {
+
+	kvStore := newMockScanStore("team/a", "team/db/host", "team/db/port", "team/web/host", "team/z", "teams", "other/x")
+
+	tests := []struct {
+		query    store.ScanQuery
+		keys     string
+		prefixes string
+	}{
+		{store.ScanQuery{Prefix: "team/", Delimiter: "/"}, "[team/a team/z]", "[team/db/ team/web/]"},
+		{store.ScanQuery{Delimiter: "/"}, "[teams]", "[other/ team/]"},
+		{store.ScanQuery{Prefix: "team/db/", Delimiter: "/"}, "[team/db/host team/db/port]", "[]"},
+	}
+
+	for _, test := range tests {
+		result, err := kvStore.MakeScanRequest(context.Background(), test.query)
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		if keys := fmt.Sprint(scannedKeys(result)); keys != test.keys {
+			t.Errorf("Returned unexpected keys for %+v: got %v want %v", test.query, keys, test.keys)
+		}
+		if prefixes := fmt.Sprint(result.CommonPrefixes); prefixes != test.prefixes {
+			t.Errorf("Returned unexpected prefixes for %+v: got %v want %v", test.query, prefixes, test.prefixes)
+		}
+	}
+}
+
+func TestScanWithDelimiterPagesPastCommonPrefixes(t *testing.T) {
+
+	kvStore := newMockScanStore("a", "b/1", "b/2", "b/3", "c", "d/1")
+
+	var pages []string
+	query := store.ScanQuery{Delimiter: "/", Limit: 2}
+	for {
+		result, err := kvStore.MakeScanRequest(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		pages = append(pages, fmt.Sprintf("%v%v", scannedKeys(result), result.CommonPrefixes))
+		if result.NextCursor == "" {
+			break
+		}
+		query.Cursor = result.NextCursor
+	}
+
+	expected := "[[a][b/] [c][d/]]"
+	if fmt.Sprint(pages) != expected {
+		t.Errorf("Returned unexpected pages: got %v want %v", pages, expected)
+	}
+}
+
+func TestScanRejectsDelimiterWithSort(t *testing.T) {
+
+	kvStore := newMockScanStore("a/1")
+
+	for _, query := range []store.ScanQuery{{Delimiter: "/", Sort: store.SortByReads}, {Delimiter: "/", Descending: true}} {
+		if _, err := kvStore.MakeScanRequest(context.Background(), query); !errors.Is(err, common.ErrorInvalidQuery) {
+			t.Errorf("Returned unexpected error for %+v: got %v want %v", query, err, common.ErrorInvalidQuery)
+		}
+	}
+}
@@ -0,0 +1,502 @@
+package store
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/users"
+	"demo-store/utils"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ShardCountFileName records how many shards a data directory was written
+// with, since keys would be looked for in the wrong shard with any other.
+const ShardCountFileName = "shards"
+
+type ShardConfig struct {
+	// Shards is how many KvStores the keys are spread over.
+	Shards int
+
+	// LimitsPerShard applies the Depth and MaxBytes of the store's Config to
+	// every shard rather than sharing them out between the shards.
+	LimitsPerShard bool
+
+	// WriteLogs holds a write log for each shard if the store is persistent,
+	// see OpenShardWriteLogs. The WriteLog of the store's Config is ignored.
+	WriteLogs []*WriteLog
+}
+
+// ShardedStore spreads keys over several KvStores by the hash of the key, so
+// requests for keys in different shards are served by different goroutines
+// rather than queueing behind each other.
+//
+// Each shard evicts on its own. Limits shared out between the shards keep the
+// store as a whole within them, but a shard may evict while others still have
+// room, so eviction only approximates the policy across the whole store.
+//
+// ListAll orders the entries of every shard by when they were last used, as a
+// single KvStore would. Batches are atomic in memory, holding every shard they
+// touch while they apply, but with persistence each shard logs its part
+// separately. Watchers see the events of every shard, numbered in one
+// sequence.
+type ShardedStore struct {
+	Tracer           utils.Tracer
+	shards           []*KvStore
+	userDatabase     users.UserDatabase
+	shutdownListener *ShutdownListener
+}
+
+// holdRequest pauses a shard's monitor goroutine, handing its entries to the
+// sender until release is closed.
+type holdRequest struct {
+	held    chan bool
+	release chan bool
+}
+
+func CreateShardedStore(tracer utils.Tracer, users users.UserDatabase, config Config, shardConfig ShardConfig) (*ShardedStore, error) {
+
+	if shardConfig.Shards < 1 {
+		return nil, fmt.Errorf("invalid number of shards %d", shardConfig.Shards)
+	}
+	if shardConfig.WriteLogs != nil && len(shardConfig.WriteLogs) != shardConfig.Shards {
+		return nil, fmt.Errorf("%d write logs for %d shards", len(shardConfig.WriteLogs), shardConfig.Shards)
+	}
+
+	watchHistory := DefaultWatchHistory
+	if config.WatchHistory > 0 {
+		watchHistory = config.WatchHistory
+	}
+	hub := newWatchHub(watchHistory)
+
+	sharded := &ShardedStore{Tracer: tracer, shards: make([]*KvStore, shardConfig.Shards), userDatabase: users}
+	for i := range sharded.shards {
+		shardStoreConfig := config
+		shardStoreConfig.WriteLog = nil
+		if shardConfig.WriteLogs != nil {
+			shardStoreConfig.WriteLog = shardConfig.WriteLogs[i]
+		}
+		if !shardConfig.LimitsPerShard {
+			shardStoreConfig.Depth = int(shareOf(int64(config.Depth), shardConfig.Shards, i))
+			shardStoreConfig.MaxBytes = shareOf(config.MaxBytes, shardConfig.Shards, i)
+		}
+
+		shard, err := createKvStore(tracer, users, shardStoreConfig)
+		if err != nil {
+			return nil, err
+		}
+		shard.watchHub = hub
+		sharded.shards[i] = shard
+	}
+
+	for _, shard := range sharded.shards {
+		shard.monitor()
+	}
+	return sharded, nil
+}
+
+// shareOf is the part of limit given to shard i of n, zero staying unbounded.
+// Every shard gets at least one so none is left unbounded.
+func shareOf(limit int64, n int, i int) int64 {
+	if limit == 0 {
+		return 0
+	}
+
+	share := limit / int64(n)
+	if int64(i) < limit%int64(n) {
+		share++
+	}
+	if share == 0 {
+		return 1
+	}
+	return share
+}
+
+// OpenShardWriteLogs opens the write log of each shard in a directory of its
+// own under dir, refusing a dir written with a different number of shards.
+func OpenShardWriteLogs(tracer utils.Tracer, dir string, shards int, policy FsyncPolicy, interval time.Duration) ([]*WriteLog, error) {
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	countPath := filepath.Join(dir, ShardCountFileName)
+	data, err := os.ReadFile(countPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := os.WriteFile(countPath, []byte(strconv.Itoa(shards)), 0666); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		count, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || count != shards {
+			return nil, fmt.Errorf("%s was written with %s shards, not %d", dir, strings.TrimSpace(string(data)), shards)
+		}
+	}
+
+	writeLogs := make([]*WriteLog, shards)
+	for i := range writeLogs {
+		writeLogs[i], err = OpenWriteLog(tracer, filepath.Join(dir, fmt.Sprintf("shard-%d", i)), policy, interval)
+		if err != nil {
+			for _, opened := range writeLogs[:i] {
+				opened.Close()
+			}
+			return nil, err
+		}
+	}
+	return writeLogs, nil
+}
+
+func (s *ShardedStore) shardIndex(key string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(len(s.shards)))
+}
+
+func (s *ShardedStore) shard(key string) *KvStore {
+	return s.shards[s.shardIndex(key)]
+}
+
+// eachShard runs request against every shard at once.
+func (s *ShardedStore) eachShard(request func(i int, shard *KvStore)) {
+	var group sync.WaitGroup
+	for i, shard := range s.shards {
+		group.Add(1)
+		go func(i int, shard *KvStore) {
+			defer group.Done()
+			request(i, shard)
+		}(i, shard)
+	}
+	group.Wait()
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ShardedStore) RegisterShutdownListener(listener *ShutdownListener) {
+	s.shutdownListener = listener
+}
+
+func (s *ShardedStore) UserDatabase() users.UserDatabase {
+	return s.userDatabase
+}
+
+// MakePutRequest writes a text value, see MakePutRequestWithOptions for
+// values of any kind.
+func (s *ShardedStore) MakePutRequest(ctx context.Context, key string, value string, owner string) error {
+	return s.MakePutRequestWithOptions(ctx, key, []byte(value), owner, PutOptions{})
+}
+
+func (s *ShardedStore) MakePutRequestWithOptions(ctx context.Context, key string, value []byte, owner string, options PutOptions) error {
+	return s.shard(key).MakePutRequestWithOptions(ctx, key, value, owner, options)
+}
+
+func (s *ShardedStore) MakeWriteRequest(ctx context.Context, key string, value []byte, owner string, options PutOptions) (*Entry, error) {
+	return s.shard(key).MakeWriteRequest(ctx, key, value, owner, options)
+}
+
+func (s *ShardedStore) MakeGetRequest(ctx context.Context, key string, user string) (string, error) {
+	return s.shard(key).MakeGetRequest(ctx, key, user)
+}
+
+func (s *ShardedStore) MakeReadRequest(ctx context.Context, key string, user string) (*Entry, error) {
+	return s.shard(key).MakeReadRequest(ctx, key, user)
+}
+
+func (s *ShardedStore) MakeListRequest(ctx context.Context, key string, user string) (*Entry, error) {
+	return s.shard(key).MakeListRequest(ctx, key, user)
+}
+
+func (s *ShardedStore) MakeDeleteRequest(ctx context.Context, key string, owner string) error {
+	return s.shard(key).MakeDeleteRequest(ctx, key, owner)
+}
+
+func (s *ShardedStore) MakeDeleteRequestWithOptions(ctx context.Context, key string, owner string, options DeleteOptions) error {
+	return s.shard(key).MakeDeleteRequestWithOptions(ctx, key, owner, options)
+}
+
+func (s *ShardedStore) MakeExpireRequest(ctx context.Context, key string, owner string, ttl time.Duration) error {
+	return s.shard(key).MakeExpireRequest(ctx, key, owner, ttl)
+}
+
+func (s *ShardedStore) MakeAclRequest(ctx context.Context, key string, owner string, change AclChange) (*Entry, error) {
+	return s.shard(key).MakeAclRequest(ctx, key, owner, change)
+}
+
+// MakeListAllRequest returns the entries of every shard, least recently used
+// first.
+func (s *ShardedStore) MakeListAllRequest(ctx context.Context, user string) ([]*Entry, error) {
+
+	lists := make([][]*Entry, len(s.shards))
+	errs := make([]error, len(s.shards))
+	s.eachShard(func(i int, shard *KvStore) {
+		lists[i], errs[i] = shard.MakeListAllRequest(ctx, user)
+	})
+	if err := firstError(errs); err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, list := range lists {
+		entries = append(entries, list...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+// MakeScanRequest takes the page from every shard and keeps the first entries
+// of them all, so the result and its cursor are those a single KvStore would
+// return.
+func (s *ShardedStore) MakeScanRequest(ctx context.Context, query ScanQuery) (*ScanResult, error) {
+
+	if query.Sort == "" {
+		query.Sort = SortByKey
+	}
+
+	results := make([]*ScanResult, len(s.shards))
+	errs := make([]error, len(s.shards))
+	s.eachShard(func(i int, shard *KvStore) {
+		results[i], errs[i] = shard.MakeScanRequest(ctx, query)
+	})
+	if err := firstError(errs); err != nil {
+		return nil, err
+	}
+
+	return mergeScanResults(query, results), nil
+}
+
+type scanItem struct {
+	position scanPosition
+	entry    *Entry
+}
+
+func mergeScanResults(query ScanQuery, results []*ScanResult) *ScanResult {
+
+	var items []scanItem
+	prefixes := make(map[string]bool)
+	more := false
+	for _, result := range results {
+		for _, entry := range result.Entries {
+			items = append(items, scanItem{position: position(query.Sort, entry), entry: entry})
+		}
+		for _, prefix := range result.CommonPrefixes {
+			if !prefixes[prefix] {
+				prefixes[prefix] = true
+				items = append(items, scanItem{position: scanPosition{Key: prefix, Under: true}})
+			}
+		}
+		more = more || result.NextCursor != ""
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if query.Descending {
+			return items[j].position.less(items[i].position)
+		}
+		return items[i].position.less(items[j].position)
+	})
+	if query.Limit > 0 && len(items) > query.Limit {
+		items = items[:query.Limit]
+		more = true
+	}
+
+	merged := &ScanResult{Entries: []*Entry{}}
+	for _, item := range items {
+		if item.entry != nil {
+			merged.Entries = append(merged.Entries, item.entry)
+		} else {
+			merged.CommonPrefixes = append(merged.CommonPrefixes, item.position.Key)
+		}
+	}
+	if more && len(items) > 0 {
+		cursor := scanCursor{Sort: query.Sort, Descending: query.Descending, Position: items[len(items)-1].position}
+		merged.NextCursor = cursor.encode()
+	}
+	return merged
+}
+
+// MakeBatchRequest applies the batch in one shard if it can. Otherwise it holds
+// every shard the batch touches, in order so concurrent batches can't
+// deadlock, and applies each shard's part once all of them are valid.
+func (s *ShardedStore) MakeBatchRequest(ctx context.Context, operations []BatchOperation, owner string) ([]BatchResult, error) {
+
+	parts := make(map[int][]int)
+	for i, operation := range operations {
+		shard := s.shardIndex(operation.Key)
+		parts[shard] = append(parts[shard], i)
+	}
+	if len(parts) <= 1 {
+		shard := s.shards[0]
+		if len(operations) > 0 {
+			shard = s.shard(operations[0].Key)
+		}
+		return shard.MakeBatchRequest(ctx, operations, owner)
+	}
+
+	indexes := make([]int, 0, len(parts))
+	for index := range parts {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		release, err := s.shards[index].hold(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	partOperations := func(index int) []BatchOperation {
+		part := make([]BatchOperation, 0, len(parts[index]))
+		for _, i := range parts[index] {
+			part = append(part, operations[i])
+		}
+		return part
+	}
+
+	// report the first operation of the whole batch that would fail
+	var failed *BatchError
+	for _, index := range indexes {
+		_, err := s.shards[index].validateBatch(partOperations(index), owner)
+		var batchErr *BatchError
+		if errors.As(err, &batchErr) {
+			batchErr = &BatchError{Index: parts[index][batchErr.Index], Err: batchErr.Err}
+			if failed == nil || batchErr.Index < failed.Index {
+				failed = batchErr
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if failed != nil {
+		return nil, failed
+	}
+
+	results := make([]BatchResult, len(operations))
+	for _, index := range indexes {
+		partResults, err := s.shards[index].Batch(partOperations(index), owner)
+		for j, result := range partResults {
+			results[parts[index][j]] = result
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// hold pauses the monitor goroutine, returning the function that resumes it.
+func (s *KvStore) hold(ctx context.Context) (func(), error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	req := holdRequest{held: make(chan bool, 1), release: make(chan bool)}
+	select {
+	case s.holdChannel <- req:
+	case <-s.closed:
+		return nil, common.ErrorStoreClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// answered as soon as the monitor goroutine receives it
+	<-req.held
+	return func() { close(req.release) }, nil
+}
+
+// MakeWatchRequest watches the keys of every shard. The shards share one
+// watch hub, so the request doesn't need to go through any of them.
+func (s *ShardedStore) MakeWatchRequest(ctx context.Context, query WatchQuery) (*Watcher, error) {
+	select {
+	case <-s.shards[0].closed:
+		return nil, common.ErrorStoreClosed
+	default:
+		return s.shards[0].watchHub.watch(query, s.shards[0].readable)
+	}
+}
+
+func (s *ShardedStore) MakeUnwatchRequest(watcher *Watcher) {
+	s.shards[0].watchHub.unwatch(watcher)
+	for range watcher.Events {
+	}
+}
+
+// MakeShutdownRequest stops every shard, failing with common.ErrorStoreClosed
+// if the store already has.
+func (s *ShardedStore) MakeShutdownRequest(ctx context.Context) error {
+
+	errs := make([]error, len(s.shards))
+	s.eachShard(func(i int, shard *KvStore) {
+		errs[i] = shard.MakeShutdownRequest(ctx)
+	})
+	if err := firstError(errs); err != nil {
+		return err
+	}
+
+	if s.shutdownListener != nil {
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			s.shutdownListener.Listener <- true
+		}()
+	}
+	return nil
+}
+
+// MakeSnapshotRequest snapshots every shard, returning the first error.
+func (s *ShardedStore) MakeSnapshotRequest(ctx context.Context) error {
+
+	errs := make([]error, len(s.shards))
+	s.eachShard(func(i int, shard *KvStore) {
+		errs[i] = shard.MakeSnapshotRequest(ctx)
+	})
+	return firstError(errs)
+}
+
+// MakeStatsRequest adds up the stats of every shard.
+func (s *ShardedStore) MakeStatsRequest(ctx context.Context) (Stats, error) {
+
+	shardStats := make([]Stats, len(s.shards))
+	errs := make([]error, len(s.shards))
+	s.eachShard(func(i int, shard *KvStore) {
+		shardStats[i], errs[i] = shard.MakeStatsRequest(ctx)
+	})
+	if err := firstError(errs); err != nil {
+		return Stats{}, err
+	}
+
+	stats := shardStats[0]
+	for _, shard := range shardStats[1:] {
+		stats.Keys += shard.Keys
+		stats.Bytes += shard.Bytes
+		stats.MaxKeys += shard.MaxKeys
+		stats.MaxBytes += shard.MaxBytes
+		stats.Gets += shard.Gets
+		stats.Hits += shard.Hits
+		stats.Misses += shard.Misses
+		stats.Puts += shard.Puts
+		stats.Deletes += shard.Deletes
+		stats.Evictions += shard.Evictions
+		stats.Expirations += shard.Expirations
+		if shard.Started.Before(stats.Started) {
+			stats.Started = shard.Started
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,291 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/users"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testShards = 4
+
+func NewMockShardedStore(t testing.TB, config store.Config) *store.ShardedStore {
+	sharded, err := store.CreateShardedStore(CreateMockTracer(), users.CreateUserDatabase(), config, store.ShardConfig{Shards: testShards})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	return sharded
+}
+
+func TestShardedStoreServesEveryKey(t *testing.T) {
+
+	var kvStore store.Store = NewMockShardedStore(t, store.Config{})
+	for i := 0; i < 50; i++ {
+		kvStore.MakePutRequest(context.Background(), fmt.Sprint("key", i), fmt.Sprint("value", i), owner1)
+	}
+	kvStore.MakeDeleteRequest(context.Background(), "key7", owner1)
+
+	for i := 0; i < 50; i++ {
+		value, err := kvStore.MakeGetRequest(context.Background(), fmt.Sprint("key", i), owner1)
+		if i == 7 {
+			if err != common.ErrorKeyNotFound {
+				t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+			}
+			continue
+		}
+		if err != nil || value != fmt.Sprint("value", i) {
+			t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, fmt.Sprint("value", i))
+		}
+	}
+
+	if err := kvStore.MakeDeleteRequest(context.Background(), "key8", owner2); err != common.ErrorUnauthorisedOwner {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+	if stats, _ := kvStore.MakeStatsRequest(context.Background()); stats.Keys != 49 || stats.Puts != 50 || stats.Deletes != 1 {
+		t.Errorf("Returned unexpected stats: got %+v", stats)
+	}
+}
+
+func TestShardedStoreListsLeastRecentlyUsedFirst(t *testing.T) {
+
+	sharded := NewMockShardedStore(t, store.Config{})
+	keys := []string{"d", "a", "c", "e", "b"}
+	for _, key := range keys {
+		sharded.MakePutRequest(context.Background(), key, value1, owner1)
+		time.Sleep(time.Millisecond)
+	}
+	sharded.MakeGetRequest(context.Background(), "d", owner1)
+	keys = append(keys[1:], "d")
+
+	entries, _ := sharded.MakeListAllRequest(context.Background(), owner1)
+	if len(entries) != len(keys) {
+		t.Fatalf("Returned unexpected entries: got %v want %v", len(entries), len(keys))
+	}
+	for i, entry := range entries {
+		if entry.Key != keys[i] {
+			t.Errorf("Returned unexpected key: got %v want %v", entry.Key, keys[i])
+		}
+	}
+}
+
+func TestShardedStoreScansLikeKvStore(t *testing.T) {
+
+	sharded := NewMockShardedStore(t, store.Config{})
+	kvStore := NewMockStore()
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("dir%d/key%02d", i%3, i)
+		if i%4 == 0 {
+			key = fmt.Sprintf("key%02d", i)
+		}
+		sharded.MakePutRequest(context.Background(), key, value1, owner1)
+		kvStore.MakePutRequest(context.Background(), key, value1, owner1)
+	}
+
+	queries := []store.ScanQuery{
+		{Limit: 4},
+		{Limit: 3, Delimiter: "/"},
+		{Limit: 5, Sort: store.SortByKey, Descending: true},
+		{Limit: 2, Prefix: "dir1/"},
+	}
+	for _, query := range queries {
+		expected := scanAll(t, kvStore, query)
+		got := scanAll(t, sharded, query)
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("Returned unexpected pages for %+v: got %v want %v", query, got, expected)
+		}
+	}
+}
+
+// scanAll pages through the query, returning the keys and common prefixes of
+// each page.
+func scanAll(t *testing.T, kvStore store.Store, query store.ScanQuery) [][]string {
+
+	var pages [][]string
+	for {
+		result, err := kvStore.MakeScanRequest(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+
+		var page []string
+		for _, entry := range result.Entries {
+			page = append(page, entry.Key)
+		}
+		pages = append(pages, append(page, result.CommonPrefixes...))
+
+		if result.NextCursor == "" {
+			return pages
+		}
+		query.Cursor = result.NextCursor
+	}
+}
+
+func TestShardedStoreBatchIsAtomicAcrossShards(t *testing.T) {
+
+	sharded := NewMockShardedStore(t, store.Config{})
+	sharded.MakePutRequest(context.Background(), "taken", value1, owner2)
+
+	var operations []store.BatchOperation
+	for i := 0; i < 10; i++ {
+		operations = append(operations, store.BatchOperation{Op: store.BatchOpPut, Key: fmt.Sprint("key", i), Value: []byte(value1)})
+	}
+	failing := append(operations, store.BatchOperation{Op: store.BatchOpPut, Key: "taken", Value: []byte(value1)})
+
+	_, err := sharded.MakeBatchRequest(context.Background(), failing, owner1)
+	var batchErr *store.BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 10 || !errors.Is(err, common.ErrorUnauthorisedOwner) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorUnauthorisedOwner)
+	}
+	if stats, _ := sharded.MakeStatsRequest(context.Background()); stats.Keys != 1 {
+		t.Errorf("Returned unexpected keys: got %v want %v", stats.Keys, 1)
+	}
+
+	operations = append(operations, store.BatchOperation{Op: store.BatchOpGet, Key: "key3"})
+	results, err := sharded.MakeBatchRequest(context.Background(), operations, owner1)
+	if err != nil || len(results) != len(operations) {
+		t.Fatalf("Returned unexpected results: got %v, %v want %v results", results, err, len(operations))
+	}
+	for i, result := range results {
+		if result.Key != operations[i].Key {
+			t.Errorf("Returned unexpected key: got %v want %v", result.Key, operations[i].Key)
+		}
+	}
+	if results[10].Value != value1 {
+		t.Errorf("Returned unexpected value: got %v want %v", results[10].Value, value1)
+	}
+}
+
+func TestShardedStoreSharesOrAppliesLimitsPerShard(t *testing.T) {
+
+	for _, perShard := range []bool{false, true} {
+		config := store.Config{Depth: 8}
+		shardConfig := store.ShardConfig{Shards: testShards, LimitsPerShard: perShard}
+		sharded, _ := store.CreateShardedStore(CreateMockTracer(), users.CreateUserDatabase(), config, shardConfig)
+		for i := 0; i < 200; i++ {
+			sharded.MakePutRequest(context.Background(), fmt.Sprint("key", i), value1, owner1)
+		}
+
+		expected := config.Depth
+		if perShard {
+			expected *= testShards
+		}
+		if stats, _ := sharded.MakeStatsRequest(context.Background()); stats.Keys != expected || stats.MaxKeys != expected {
+			t.Errorf("Returned unexpected keys: got %v of %v want %v", stats.Keys, stats.MaxKeys, expected)
+		}
+	}
+}
+
+func TestShardedStoreWatchesEveryShard(t *testing.T) {
+
+	sharded := NewMockShardedStore(t, store.Config{})
+	watcher, _ := sharded.MakeWatchRequest(context.Background(), store.WatchQuery{Prefix: true})
+	defer sharded.MakeUnwatchRequest(watcher)
+
+	for i := 0; i < 10; i++ {
+		sharded.MakePutRequest(context.Background(), fmt.Sprint("key", i), value1, owner1)
+	}
+
+	seen := make(map[uint64]bool)
+	for i := 0; i < 10; i++ {
+		event := <-watcher.Events
+		if event.Sequence < 1 || event.Sequence > 10 || seen[event.Sequence] {
+			t.Errorf("Returned unexpected sequence: got %v", event.Sequence)
+		}
+		seen[event.Sequence] = true
+	}
+}
+
+func TestShardedStoreRestoresFromWriteLogs(t *testing.T) {
+
+	dir := t.TempDir()
+	open := func(shards int) (*store.ShardedStore, error) {
+		writeLogs, err := store.OpenShardWriteLogs(CreateMockTracer(), dir, shards, store.FsyncNever, 0)
+		if err != nil {
+			return nil, err
+		}
+		shardConfig := store.ShardConfig{Shards: shards, WriteLogs: writeLogs}
+		return store.CreateShardedStore(CreateMockTracer(), users.CreateUserDatabase(), store.Config{}, shardConfig)
+	}
+
+	sharded, _ := open(testShards)
+	for i := 0; i < 20; i++ {
+		sharded.MakePutRequest(context.Background(), fmt.Sprint("key", i), fmt.Sprint("value", i), owner1)
+	}
+	sharded.MakeShutdownRequest(context.Background())
+
+	if _, err := open(testShards + 1); err == nil {
+		t.Errorf("Returned unexpected error: got %v want an error", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "shard-0")); err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	restored, err := open(testShards)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	for i := 0; i < 20; i++ {
+		if value, err := restored.MakeGetRequest(context.Background(), fmt.Sprint("key", i), owner1); err != nil || value != fmt.Sprint("value", i) {
+			t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, fmt.Sprint("value", i))
+		}
+	}
+}
+
+// benchmarkStress writes unique keys from every goroutine, as the harness's
+// Stress test does, reading back every readsPerWrite keys in between.
+func benchmarkStress(b *testing.B, kvStore store.Store, readsPerWrite int) {
+
+	var next int64
+	b.SetParallelism(4)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		goroutine := strconv.FormatInt(atomic.AddInt64(&next, 1), 10)
+		for i := 0; pb.Next(); i++ {
+			key := goroutine + "_" + strconv.Itoa(i/(readsPerWrite+1))
+			if i%(readsPerWrite+1) == 0 {
+				kvStore.MakePutRequest(context.Background(), key, strconv.Itoa(i), owner1)
+			} else {
+				kvStore.MakeGetRequest(context.Background(), key, owner1)
+			}
+		}
+	})
+}
+
+func BenchmarkStress(b *testing.B) {
+
+	stores := []struct {
+		name   string
+		create func() store.Store
+	}{
+		{"kvstore", func() store.Store { return NewMockStore() }},
+		{"sharded", func() store.Store {
+			sharded, _ := store.CreateShardedStore(CreateMockTracer(), users.CreateUserDatabase(), store.Config{}, store.ShardConfig{Shards: runtime.GOMAXPROCS(0)})
+			return sharded
+		}},
+	}
+	workloads := []struct {
+		name          string
+		readsPerWrite int
+	}{
+		{"writes", 0},
+		{"reads", 9},
+	}
+
+	for _, workload := range workloads {
+		for _, kvStore := range stores {
+			workload, kvStore := workload, kvStore
+			b.Run(workload.name+"/"+kvStore.name, func(b *testing.B) {
+				benchmarkStress(b, kvStore.create(), workload.readsPerWrite)
+			})
+		}
+	}
+}
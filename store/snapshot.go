@@ -0,0 +1,121 @@
+package store
+
+import (
+	"bytes"
+	"demo-store/common"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+const SnapshotFileName = "store.snapshot"
+const SnapshotVersion uint32 = 2
+
+var snapshotMagic = [8]byte{'D', 'S', 'S', 'N', 'A', 'P', 0, 0}
+
+// Snapshot is a point in time copy of the store. Entries are ordered least
+// recently used first, Segment is the first write log segment holding
+// mutations made after the snapshot was taken and LastVersion the highest
+// entry version handed out so far.
+type Snapshot struct {
+	Segment     int
+	LastVersion uint64
+	Entries     []LogRecord
+}
+
+// snapshotPrefix starts every snapshot file and selects the header layout
+// that follows it.
+type snapshotPrefix struct {
+	Magic   [8]byte
+	Version uint32
+}
+
+// snapshotHeader is followed on disk by Length bytes of JSON encoded entries
+// and a CRC32 (IEEE) of everything before it.
+type snapshotHeader struct {
+	Segment     uint64
+	LastVersion uint64
+	Length      uint64
+}
+
+// snapshotHeaderV1 predates entry versions.
+type snapshotHeaderV1 struct {
+	Segment uint64
+	Length  uint64
+}
+
+func WriteSnapshot(path string, snapshot *Snapshot) error {
+
+	payload, err := json.Marshal(snapshot.Entries)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	prefix := snapshotPrefix{Magic: snapshotMagic, Version: SnapshotVersion}
+	header := snapshotHeader{Segment: uint64(snapshot.Segment), LastVersion: snapshot.LastVersion, Length: uint64(len(payload))}
+	binary.Write(&buffer, binary.LittleEndian, prefix)
+	binary.Write(&buffer, binary.LittleEndian, header)
+	buffer.Write(payload)
+	binary.Write(&buffer, binary.LittleEndian, crc32.ChecksumIEEE(buffer.Bytes()))
+
+	return common.WriteFileAtomic(path, buffer.Bytes())
+}
+
+// ReadSnapshot loads and verifies a snapshot, returning os.ErrNotExist if
+// there is none and common.ErrorSnapshotCorrupt if it fails verification.
+func ReadSnapshot(path string) (*Snapshot, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bytes.NewReader(data)
+	var prefix snapshotPrefix
+	if err := binary.Read(reader, binary.LittleEndian, &prefix); err != nil {
+		return nil, fmt.Errorf("%w: %s: truncated header", common.ErrorSnapshotCorrupt, path)
+	}
+	if prefix.Magic != snapshotMagic {
+		return nil, fmt.Errorf("%w: %s: not a snapshot file", common.ErrorSnapshotCorrupt, path)
+	}
+
+	var header snapshotHeader
+	switch prefix.Version {
+	case 1:
+		var headerV1 snapshotHeaderV1
+		err = binary.Read(reader, binary.LittleEndian, &headerV1)
+		header = snapshotHeader{Segment: headerV1.Segment, Length: headerV1.Length}
+	case SnapshotVersion:
+		err = binary.Read(reader, binary.LittleEndian, &header)
+	default:
+		return nil, fmt.Errorf("%w: %s: unsupported version %d", common.ErrorSnapshotCorrupt, path, prefix.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: truncated header", common.ErrorSnapshotCorrupt, path)
+	}
+
+	headerSize := len(data) - reader.Len()
+	if uint64(reader.Len()) != header.Length+4 {
+		return nil, fmt.Errorf("%w: %s: expected %d bytes of entries", common.ErrorSnapshotCorrupt, path, header.Length)
+	}
+
+	payload := make([]byte, header.Length)
+	io.ReadFull(reader, payload)
+	var checksum uint32
+	binary.Read(reader, binary.LittleEndian, &checksum)
+
+	if crc32.ChecksumIEEE(data[:headerSize+len(payload)]) != checksum {
+		return nil, fmt.Errorf("%w: %s: checksum mismatch", common.ErrorSnapshotCorrupt, path)
+	}
+
+	snapshot := Snapshot{Segment: int(header.Segment), LastVersion: header.LastVersion}
+	if err := json.Unmarshal(payload, &snapshot.Entries); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", common.ErrorSnapshotCorrupt, path, err)
+	}
+
+	return &snapshot, nil
+}
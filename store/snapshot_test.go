@@ -0,0 +1,124 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), store.SnapshotFileName)
+	snapshot := store.Snapshot{Segment: 3}
+	for i := 0; i < 3; i++ {
+		entry := store.NewEntry(fmt.Sprint("key", i), []byte(fmt.Sprint("value", i)), owner1)
+		snapshot.Entries = append(snapshot.Entries, store.CreateLogRecord(store.LogOpPut, entry))
+	}
+
+	if err := store.WriteSnapshot(path, &snapshot); err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	loaded, err := store.ReadSnapshot(path)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if loaded.Segment != snapshot.Segment {
+		t.Errorf("Returned unexpected segment: got %v want %v", loaded.Segment, snapshot.Segment)
+	}
+	for i, record := range loaded.Entries {
+		if record.Entry().String() != snapshot.Entries[i].Entry().String() {
+			t.Errorf("Returned unexpected entry: got %v want %v", record.Entry(), snapshot.Entries[i].Entry())
+		}
+	}
+}
+
+func TestSnapshotDetectsCorruption(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), store.SnapshotFileName)
+	snapshot := store.Snapshot{Segment: 1, Entries: []store.LogRecord{store.CreateLogRecord(store.LogOpPut, store.NewEntry(key1, []byte(value1), owner1))}}
+	store.WriteSnapshot(path, &snapshot)
+
+	data, _ := os.ReadFile(path)
+	data[len(data)-10] ^= 0xff
+	os.WriteFile(path, data, 0666)
+
+	_, err := store.ReadSnapshot(path)
+	if !errors.Is(err, common.ErrorSnapshotCorrupt) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorSnapshotCorrupt)
+	}
+
+	os.WriteFile(path, data[:10], 0666)
+	_, err = store.ReadSnapshot(path)
+	if !errors.Is(err, common.ErrorSnapshotCorrupt) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorSnapshotCorrupt)
+	}
+}
+
+func TestSnapshotRequiresPersistence(t *testing.T) {
+
+	mockStore := NewMockStore()
+
+	err := mockStore.MakeSnapshotRequest(context.Background())
+	if err != common.ErrorPersistenceDisabled {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorPersistenceDisabled)
+	}
+}
+
+func TestSnapshotCompactsWriteLog(t *testing.T) {
+
+	dir := t.TempDir()
+	depth := 3
+	kvStore := NewMockPersistentStore(t, dir, depth)
+	for i := 0; i < 4; i++ {
+		kvStore.MakePutRequest(context.Background(), fmt.Sprint("key", i), value1, owner1)
+	}
+	kvStore.MakeGetRequest(context.Background(), "key1", owner1)
+
+	if err := kvStore.MakeSnapshotRequest(context.Background()); err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	kvStore.MakePutRequest(context.Background(), "key4", value1, owner1)
+
+	if _, err := os.Stat(filepath.Join(dir, store.SegmentFileName(1))); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, os.ErrNotExist)
+	}
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, depth)
+	kvStore.MakePutRequest(context.Background(), "key5", value1, owner1)
+
+	entries, _ := kvStore.MakeListAllRequest(context.Background(), owner1)
+	expected := []string{"key1", "key4", "key5"}
+	if len(entries) != len(expected) {
+		t.Fatalf("Returned unexpected entry count: got %v want %v", len(entries), len(expected))
+	}
+	for i, entry := range entries {
+		if entry.Key != expected[i] {
+			t.Errorf("Returned unexpected key: got %v want %v", entry.Key, expected[i])
+		}
+	}
+}
+
+func TestCorruptSnapshotIsReportedOnStartup(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakeSnapshotRequest(context.Background())
+	kvStore.MakeShutdownRequest(context.Background())
+
+	path := filepath.Join(dir, store.SnapshotFileName)
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, data[:len(data)-1], 0666)
+
+	writeLog, _ := store.OpenWriteLog(CreateMockTracer(), dir, store.FsyncNever, 0)
+	_, err := store.CreateKvStoreWithConfig(CreateMockTracer(), nil, store.Config{WriteLog: writeLog})
+	if !errors.Is(err, common.ErrorSnapshotCorrupt) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorSnapshotCorrupt)
+	}
+}
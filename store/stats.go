@@ -0,0 +1,35 @@
+package store
+
+import "time"
+
+// Stats are counters of what the store has done since it started, along with
+// the current number and size of its keys.
+type Stats struct {
+	Keys  int   `json:"keys"`
+	Bytes int64 `json:"bytes"`
+
+	// MaxKeys and MaxBytes are the limits evictions keep the store within,
+	// zero if unbounded.
+	MaxKeys  int   `json:"max_keys"`
+	MaxBytes int64 `json:"max_bytes"`
+
+	Gets        uint64 `json:"gets"`
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	Puts        uint64 `json:"puts"`
+	Deletes     uint64 `json:"deletes"`
+	Evictions   uint64 `json:"evictions"`
+	Expirations uint64 `json:"expirations"`
+
+	Started time.Time `json:"started"`
+}
+
+func (s *KvStore) Stats() Stats {
+
+	stats := s.stats
+	stats.Keys = s.entries.Len()
+	stats.Bytes = s.entries.Bytes()
+	stats.MaxKeys = s.entries.depth
+	stats.MaxBytes = s.entries.maxBytes
+	return stats
+}
@@ -0,0 +1,55 @@
+package store_test
+
+import (
+	"context"
+	"demo-store/store"
+	"demo-store/users"
+	"testing"
+	"time"
+)
+
+func TestStatsCountsRequests(t *testing.T) {
+
+	kvStore := store.CreateKvStore(CreateMockTracer(), users.CreateUserDatabase(), 2)
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+	kvStore.MakeGetRequest(context.Background(), key1, owner1)
+	kvStore.MakeGetRequest(context.Background(), "missing", owner1)
+	kvStore.MakePutRequest(context.Background(), "key3", "value3", owner1)
+	kvStore.MakeDeleteRequest(context.Background(), key1, owner1)
+	kvStore.MakePutRequestWithOptions(context.Background(), key2, []byte(value2), owner2, store.PutOptions{TTL: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+	kvStore.MakeGetRequest(context.Background(), key2, owner1)
+
+	stats, _ := kvStore.MakeStatsRequest(context.Background())
+	expected := store.Stats{
+		Keys: 1, Bytes: (&store.Entry{Key: "key3", Value: []byte("value3"), Owner: owner1}).Size(), MaxKeys: 2,
+		Gets: 3, Hits: 1, Misses: 2, Puts: 4, Deletes: 1, Evictions: 1, Expirations: 1,
+		Started: stats.Started,
+	}
+	if stats != expected {
+		t.Errorf("Returned unexpected stats: got %+v want %+v", stats, expected)
+	}
+	if stats.Started.IsZero() {
+		t.Errorf("Returned unexpected start time: got %v", stats.Started)
+	}
+}
+
+func TestLruListTracksBytes(t *testing.T) {
+
+	list := store.NewLruEntryList(CreateMockTracer(), 0)
+	list.AddEntry(key1, []byte(value1), owner1)
+	list.AddEntry(key2, []byte(value2), owner2)
+	list.UpdateEntry(key1, []byte("longer value"))
+	list.DeleteEntry(key2)
+	restored := &store.Entry{Key: key1, Value: []byte("v"), Owner: owner1}
+	list.RestoreEntry(restored)
+
+	expected := restored.Size()
+	if list.Bytes() != expected {
+		t.Errorf("Returned unexpected bytes: got %v want %v", list.Bytes(), expected)
+	}
+	if list.Len() != 1 {
+		t.Errorf("Returned unexpected length: got %v want %v", list.Len(), 1)
+	}
+}
@@ -1,6 +1,7 @@
 package store_test
 
 import (
+	"context"
 	"demo-store/common"
 	"demo-store/store"
 	"demo-store/users"
@@ -16,12 +17,12 @@ func TestPutRequestInsertsToStore(t *testing.T) {
 	owner := "testUser1"
 	mockStore := NewMockStore()
 
-	err := mockStore.MakePutRequest(key, value1, owner)
+	err := mockStore.MakePutRequest(context.Background(), key, value1, owner)
 	if err != nil {
 		t.Errorf("Put unexpected value got %s want %s", err, "nil")
 	}
 
-	value, _ := mockStore.MakeGetRequest(key)
+	value, _ := mockStore.MakeGetRequest(context.Background(), key, owner1)
 	if value != value1 {
 		t.Errorf("Get unexpected value got %s want %s", value1, value)
 	}
@@ -32,7 +33,7 @@ func TestGetReturnsErrorIfItemNotInStore(t *testing.T) {
 	key := "key1"
 	mockStore := NewMockStore()
 
-	_, err := mockStore.MakeGetRequest(key)
+	_, err := mockStore.MakeGetRequest(context.Background(), key, owner1)
 	if err != common.ErrorKeyNotFound {
 		t.Errorf("Get unexpected error got %v want %v", err, common.ErrorKeyNotFound)
 	}
@@ -44,11 +45,11 @@ func TestListAllReturnsAllItems(t *testing.T) {
 	value := "data1"
 	owner := "testUser1"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner)
+	mockStore.Put(key, []byte(value), owner)
 
-	entries := mockStore.MakeListAllRequest()
+	entries, _ := mockStore.MakeListAllRequest(context.Background(), owner1)
 	newEntry := entries[0].String()
-	expected := store.NewEntry(key, value, owner).String()
+	expected := store.NewEntry(key, []byte(value), owner).String()
 
 	if newEntry != expected {
 		t.Errorf("Get unexpected error got %v want %v", newEntry, expected)
@@ -61,11 +62,11 @@ func TestListReturnsItem(t *testing.T) {
 	value := "data1"
 	owner := "testUser1"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner)
+	mockStore.Put(key, []byte(value), owner)
 
-	entry, _ := mockStore.MakeListRequest(key)
+	entry, _ := mockStore.MakeListRequest(context.Background(), key, owner1)
 	newEntry := entry.String()
-	expected := store.NewEntry(key, value, owner).String()
+	expected := store.NewEntry(key, []byte(value), owner).String()
 
 	if newEntry != expected {
 		t.Errorf("Get unexpected error got %v want %v", newEntry, expected)
@@ -78,11 +79,11 @@ func TestDeleteRemovesItem(t *testing.T) {
 	value := "data1"
 	owner := "testUser1"
 	mockStore := NewMockStore()
-	mockStore.Put(key, value, owner)
+	mockStore.Put(key, []byte(value), owner)
 
-	mockStore.MakeDeleteRequest(key, owner)
+	mockStore.MakeDeleteRequest(context.Background(), key, owner)
 
-	_, err := mockStore.MakeListRequest(key)
+	_, err := mockStore.MakeListRequest(context.Background(), key, owner1)
 	if err != common.ErrorKeyNotFound {
 		t.Errorf("Get unexpected error got %v want %v", common.ErrorKeyNotFound, err)
 	}
@@ -93,7 +94,7 @@ func TestShutdown(t *testing.T) {
 	mockStore := NewMockStore()
 	sl := store.CreateShutdownListener()
 	mockStore.RegisterShutdownListener(sl)
-	mockStore.MakeShutdownRequest()
+	mockStore.MakeShutdownRequest(context.Background())
 
 	go func() {
 		resp := <-sl.Listener
@@ -0,0 +1,190 @@
+package store
+
+import "hash/maphash"
+
+const (
+	// tinyLfuWindowPercent is the share of keys held in the admission window.
+	tinyLfuWindowPercent = 1
+
+	// tinyLfuProtectedPercent is the share of the main region kept for keys
+	// used again since they were admitted.
+	tinyLfuProtectedPercent = 80
+
+	sketchDepth    = 4
+	sketchMaxCount = 15
+	sketchMinWidth = 64
+
+	// sketchSamples is how many increments, per counter in a row, the sketch
+	// takes before halving its counters.
+	sketchSamples = 10
+)
+
+// frequencySketch estimates how often keys were used with a count-min sketch
+// of small counters, halved periodically so past popularity fades.
+type frequencySketch struct {
+	counters  []uint8
+	width     uint64
+	additions int
+	seed      maphash.Seed
+}
+
+func newFrequencySketch(width int) *frequencySketch {
+	size := uint64(sketchMinWidth)
+	for size < uint64(width) {
+		size *= 2
+	}
+	return &frequencySketch{counters: make([]uint8, sketchDepth*size), width: size, seed: maphash.MakeSeed()}
+}
+
+func (s *frequencySketch) indexes(key string) [sketchDepth]uint64 {
+	hash := maphash.String(s.seed, key)
+	low, high := hash&0xffffffff, (hash>>32)|1
+
+	var indexes [sketchDepth]uint64
+	for i := range indexes {
+		indexes[i] = uint64(i)*s.width + (low+uint64(i)*high)&(s.width-1)
+	}
+	return indexes
+}
+
+func (s *frequencySketch) Increment(key string) {
+	for _, index := range s.indexes(key) {
+		if s.counters[index] < sketchMaxCount {
+			s.counters[index]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= sketchSamples*int(s.width) {
+		for i := range s.counters {
+			s.counters[i] /= 2
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *frequencySketch) Estimate(key string) uint8 {
+	estimate := uint8(sketchMaxCount)
+	for _, index := range s.indexes(key) {
+		if s.counters[index] < estimate {
+			estimate = s.counters[index]
+		}
+	}
+	return estimate
+}
+
+// TinyLfuPolicy is Window TinyLFU. New keys enter a small LRU window and, once
+// the list is full, a key leaving the window is only admitted to the main
+// region if it was used more often than the key it would replace, according to
+// a sketch that also remembers keys no longer held. Keys touched once by a
+// scan so rarely displace popular ones. The main region is a segmented LRU
+// protecting keys used again since they were admitted.
+type TinyLfuPolicy struct {
+	window    *keyList
+	probation *keyList
+	protected *keyList
+	sketch    *frequencySketch
+
+	// full is set by the first eviction; until then keys move from the window
+	// to the main region without having to be admitted
+	full bool
+}
+
+func NewTinyLfuPolicy() *TinyLfuPolicy {
+	return &TinyLfuPolicy{window: newKeyList(), probation: newKeyList(), protected: newKeyList(), sketch: newFrequencySketch(0)}
+}
+
+func (p *TinyLfuPolicy) Add(key string) {
+
+	if size := p.len() + 1; uint64(size) > p.sketch.width {
+		p.sketch = newFrequencySketch(size)
+	}
+	p.sketch.Increment(key)
+	p.window.PushFront(key)
+
+	if !p.full {
+		for p.window.Len() > p.windowSize() {
+			moved, _ := p.window.PopBack("")
+			p.probation.PushFront(moved)
+		}
+	}
+}
+
+func (p *TinyLfuPolicy) Touch(key string) {
+
+	p.sketch.Increment(key)
+	switch {
+	case p.window.Contains(key):
+		p.window.MoveToFront(key)
+
+	case p.probation.Contains(key):
+		p.probation.Remove(key)
+		p.protected.PushFront(key)
+		for p.protected.Len() > p.protectedSize() {
+			demoted, _ := p.protected.PopBack("")
+			p.probation.PushFront(demoted)
+		}
+
+	case p.protected.Contains(key):
+		p.protected.MoveToFront(key)
+	}
+}
+
+func (p *TinyLfuPolicy) Remove(key string) {
+	if !p.window.Remove(key) && !p.probation.Remove(key) {
+		p.protected.Remove(key)
+	}
+}
+
+func (p *TinyLfuPolicy) Evict(keep string) (string, bool) {
+
+	p.full = true
+	if p.window.Len() > p.windowSize() {
+		if candidate, ok := p.window.Back(keep); ok {
+			return p.admit(candidate, keep), true
+		}
+	}
+
+	for _, keys := range []*keyList{p.probation, p.protected, p.window} {
+		if key, ok := keys.PopBack(keep); ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// admit moves candidate from the window to the main region if it was used more
+// often than the key the main region would evict, returning the key evicted.
+func (p *TinyLfuPolicy) admit(candidate string, keep string) string {
+
+	p.window.Remove(candidate)
+
+	victims := p.probation
+	victim, ok := victims.Back(keep)
+	if !ok {
+		victims = p.protected
+		victim, ok = victims.Back(keep)
+	}
+	if !ok || p.sketch.Estimate(candidate) <= p.sketch.Estimate(victim) {
+		return candidate
+	}
+
+	victims.Remove(victim)
+	p.probation.PushFront(candidate)
+	return victim
+}
+
+func (p *TinyLfuPolicy) len() int {
+	return p.window.Len() + p.probation.Len() + p.protected.Len()
+}
+
+func (p *TinyLfuPolicy) windowSize() int {
+	if size := p.len() * tinyLfuWindowPercent / 100; size > 1 {
+		return size
+	}
+	return 1
+}
+
+func (p *TinyLfuPolicy) protectedSize() int {
+	return (p.probation.Len() + p.protected.Len()) * tinyLfuProtectedPercent / 100
+}
@@ -1,30 +1,121 @@
 package store
 
 import (
+	"context"
 	"demo-store/users"
 	"demo-store/utils"
+	"time"
 )
 
+// Store is served by the API endpoints and protocol servers. Requests take
+// the caller's context and fail with its error if it is done before the store
+// answers, or with common.ErrorStoreClosed once the store has shut down.
 type Store interface {
 	RegisterShutdownListener(listener *ShutdownListener)
-	MakePutRequest(key string, value string, owner string) error
-	MakeGetRequest(key string) (string, error)
-	MakeListAllRequest() []*Entry
-	MakeListRequest(key string) (*Entry, error)
-	MakeDeleteRequest(key string, owner string) error
-	MakeShutdownRequest()
+	MakePutRequest(ctx context.Context, key string, value string, owner string) error
+	MakePutRequestWithOptions(ctx context.Context, key string, value []byte, owner string, options PutOptions) error
+	MakeWriteRequest(ctx context.Context, key string, value []byte, owner string, options PutOptions) (*Entry, error)
+	MakeGetRequest(ctx context.Context, key string, user string) (string, error)
+	MakeReadRequest(ctx context.Context, key string, user string) (*Entry, error)
+	MakeListAllRequest(ctx context.Context, user string) ([]*Entry, error)
+	MakeListRequest(ctx context.Context, key string, user string) (*Entry, error)
+	MakeScanRequest(ctx context.Context, query ScanQuery) (*ScanResult, error)
+	MakeDeleteRequest(ctx context.Context, key string, owner string) error
+	MakeExpireRequest(ctx context.Context, key string, owner string, ttl time.Duration) error
+	MakeDeleteRequestWithOptions(ctx context.Context, key string, owner string, options DeleteOptions) error
+	MakeAclRequest(ctx context.Context, key string, owner string, change AclChange) (*Entry, error)
+	MakeBatchRequest(ctx context.Context, operations []BatchOperation, owner string) ([]BatchResult, error)
+	MakeWatchRequest(ctx context.Context, query WatchQuery) (*Watcher, error)
+	MakeUnwatchRequest(watcher *Watcher)
+	MakeShutdownRequest(ctx context.Context) error
+	MakeSnapshotRequest(ctx context.Context) error
+	MakeStatsRequest(ctx context.Context) (Stats, error)
 	UserDatabase() users.UserDatabase
 }
 
 type KvStore struct {
 	Tracer           utils.Tracer
 	userDatabase     users.UserDatabase
-	lruData          LruEntryList
+	entries          EntryList
 	putChannel       chan PutRequest
 	getChannel       chan GetRequest
 	listAllChannel   chan ListAllRequest
 	listChannel      chan ListRequest
+	scanChannel      chan ScanRequest
 	deleteChannel    chan DeleteRequest
+	expireChannel    chan ExpireRequest
+	aclChannel       chan AclRequest
 	shutdownChannel  chan ShutdownRequest
+	snapshotChannel  chan SnapshotRequest
+	batchChannel     chan BatchRequest
+	watchChannel     chan WatchRequest
+	unwatchChannel   chan UnwatchRequest
+	statsChannel     chan StatsRequest
+	holdChannel      chan holdRequest
+	closed           chan struct{}
 	shutdownListener *ShutdownListener
+	writeLog         *WriteLog
+	unloggedReads    map[string]bool
+	snapshotInterval time.Duration
+	expiries         expiryQueue
+	expiryInterval   time.Duration
+	version          uint64
+	watchHub         *watchHub
+	stats            Stats
+	allowEmptyValues bool
+	privateByDefault bool
+	requestTimeout   time.Duration
+}
+
+type Config struct {
+	Depth int
+
+	// MaxBytes bounds the total size of the entries, as reported by
+	// Entry.Size, evicting keys to stay within it.
+	// It can be combined with Depth and is unbounded if zero.
+	MaxBytes int64
+
+	// Eviction chooses which keys are evicted to stay within Depth and
+	// MaxBytes, the least recently used by default.
+	Eviction Eviction
+
+	WriteLog         *WriteLog
+	SnapshotInterval time.Duration
+	ExpiryInterval   time.Duration
+	WatchHistory     int
+
+	// AllowEmptyValues lets keys be written with an empty value, which is
+	// otherwise rejected as most likely a client forgetting the value.
+	AllowEmptyValues bool
+
+	// PrivateByDefault makes keys written without a visibility private
+	// rather than public.
+	PrivateByDefault bool
+
+	// RequestTimeout bounds how long a request waits for the store, failing
+	// with context.DeadlineExceeded rather than hanging if the store stalls.
+	// Requests wait for as long as their context allows if it is zero.
+	RequestTimeout time.Duration
+}
+
+type PutOptions struct {
+	// TTL expires the entry after the given duration, zero keeps it forever.
+	TTL time.Duration
+
+	// Flags replaces the flags stored with the entry.
+	Flags uint32
+
+	// ContentType and ContentEncoding replace the ones stored with the entry.
+	ContentType     string
+	ContentEncoding string
+
+	// Visibility changes who may read the entry. If empty, existing entries
+	// keep theirs and new ones get the store's default.
+	Visibility Visibility
+
+	Precondition Precondition
+}
+
+type DeleteOptions struct {
+	Precondition Precondition
 }
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"demo-store/common"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventPut    = "put"
+	EventDelete = "delete"
+	EventExpire = "expire"
+	EventEvict  = "evict"
+)
+
+// DefaultWatchHistory is how many events are kept for watchers resuming after
+// a reconnect.
+const DefaultWatchHistory = 1024
+
+// watcherBuffer is how far a watcher may fall behind before it is dropped.
+// Dropped watchers can resume from the history like any other reconnect.
+const watcherBuffer = 256
+
+// Event describes a change to a key. Sequence numbers start at one and increase
+// by one with every event the store publishes.
+//
+// Put events carry the value, encoded in JSON as base64 so any bytes survive,
+// only when sent as they happen. The history kept for resuming watchers holds
+// no values, which would otherwise use memory outside the store's limits.
+type Event struct {
+	Sequence        uint64    `json:"sequence"`
+	Type            string    `json:"type"`
+	Key             string    `json:"key"`
+	Value           []byte    `json:"value,omitempty"`
+	ContentType     string    `json:"content_type,omitempty"`
+	ContentEncoding string    `json:"content_encoding,omitempty"`
+	Owner           string    `json:"owner,omitempty"`
+	Version         uint64    `json:"version,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+
+	// private holds the owner and ACL of a private key as it was when the
+	// event was published, so it only goes to watchers who could read it.
+	private *Entry
+}
+
+// WatchQuery selects the events to deliver. After resumes from the event with
+// that sequence number; zero watches from the latest event onwards.
+type WatchQuery struct {
+	Key    string
+	Prefix bool
+	After  uint64
+
+	// Reader is the user watching, who isn't sent events for private keys
+	// they may not read.
+	Reader string
+}
+
+func (q *WatchQuery) Matches(key string) bool {
+	if q.Prefix {
+		return strings.HasPrefix(key, q.Key)
+	}
+	return key == q.Key
+}
+
+// Watcher receives the events matching its query until it is unwatched, falls
+// too far behind or the store shuts down, when Events is closed.
+type Watcher struct {
+	Events <-chan Event
+
+	// Sequence is the last event published before the watcher started
+	// receiving live events.
+	Sequence uint64
+
+	query    WatchQuery
+	readable func(entry *Entry) bool
+	events   chan Event
+}
+
+// receives reports whether the event matches the watcher's query and is about
+// a key the watcher may read.
+func (w *Watcher) receives(event Event) bool {
+	return w.query.Matches(event.Key) && (event.private == nil || w.readable(event.private))
+}
+
+// watchHub fans events out to watchers. The shards of a ShardedStore share
+// one, publishing from their own monitor goroutines.
+type watchHub struct {
+	mutex    sync.Mutex
+	sequence uint64
+	history  []Event
+	next     int
+	watchers map[*Watcher]bool
+}
+
+func newWatchHub(historySize int) *watchHub {
+	return &watchHub{history: make([]Event, 0, historySize), watchers: make(map[*Watcher]bool)}
+}
+
+func (h *watchHub) publish(event Event) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.sequence++
+	event.Sequence = h.sequence
+
+	if cap(h.history) > 0 {
+		remembered := event
+		remembered.Value = nil
+		if len(h.history) < cap(h.history) {
+			h.history = append(h.history, remembered)
+		} else {
+			h.history[h.next] = remembered
+			h.next = (h.next + 1) % cap(h.history)
+		}
+	}
+
+	for watcher := range h.watchers {
+		if watcher.receives(event) {
+			h.deliver(watcher, event)
+		}
+	}
+}
+
+func (h *watchHub) deliver(watcher *Watcher, event Event) {
+	select {
+	case watcher.events <- event:
+	default:
+		h.remove(watcher)
+	}
+}
+
+// watch registers a watcher, first queueing the events it missed since
+// query.After. If those are no longer in the history it fails with
+// common.ErrorWatchPositionLost so the client knows to reload. readable
+// reports whether query.Reader may read a private key.
+func (h *watchHub) watch(query WatchQuery, readable func(user string, entry *Entry) bool) (*Watcher, error) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	watcher := &Watcher{query: query}
+	watcher.readable = func(entry *Entry) bool {
+		return readable(query.Reader, entry)
+	}
+
+	var missed []Event
+	if query.After != 0 && query.After != h.sequence {
+		events, ok := h.since(query.After)
+		if !ok {
+			return nil, common.ErrorWatchPositionLost
+		}
+		for _, event := range events {
+			if watcher.receives(event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	events := make(chan Event, len(missed)+watcherBuffer)
+	for _, event := range missed {
+		events <- event
+	}
+
+	watcher.Events = events
+	watcher.Sequence = h.sequence
+	watcher.events = events
+	h.watchers[watcher] = true
+	return watcher, nil
+}
+
+// since returns the events after the given sequence number, or false if some
+// of them have already left the history.
+func (h *watchHub) since(sequence uint64) ([]Event, bool) {
+
+	if sequence > h.sequence {
+		return nil, false
+	}
+
+	oldest := h.sequence - uint64(len(h.history)) + 1
+	if sequence+1 < oldest {
+		return nil, false
+	}
+
+	events := make([]Event, 0, h.sequence-sequence)
+	for i := 0; i < len(h.history); i++ {
+		event := h.history[(h.next+i)%len(h.history)]
+		if event.Sequence > sequence {
+			events = append(events, event)
+		}
+	}
+	return events, true
+}
+
+func (h *watchHub) unwatch(watcher *Watcher) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.remove(watcher)
+}
+
+// remove stops delivering to the watcher, with the mutex held.
+func (h *watchHub) remove(watcher *Watcher) {
+	if _, ok := h.watchers[watcher]; !ok {
+		return
+	}
+	delete(h.watchers, watcher)
+	close(watcher.events)
+}
+
+func (h *watchHub) close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for watcher := range h.watchers {
+		h.remove(watcher)
+	}
+}
+
+func (s *KvStore) Watch(query WatchQuery) (*Watcher, error) {
+	return s.watchHub.watch(query, s.readable)
+}
+
+func (s *KvStore) Unwatch(watcher *Watcher) {
+	s.watchHub.unwatch(watcher)
+}
+
+func (s *KvStore) publish(eventType string, entry *Entry) {
+
+	event := Event{Type: eventType, Key: entry.Key, Version: entry.Version, Timestamp: time.Now()}
+	if eventType == EventPut {
+		// the value of a private key is left out even for watchers who may
+		// read it, as they all share the event
+		if !entry.Private {
+			event.Value = entry.Value
+		}
+		event.ContentType = entry.ContentType
+		event.ContentEncoding = entry.ContentEncoding
+		event.Owner = entry.Owner
+	}
+	if entry.Private {
+		event.private = &Entry{Owner: entry.Owner, ACL: entry.ACL.Clone(), Private: true}
+	}
+	s.watchHub.publish(event)
+}
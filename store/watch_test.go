@@ -0,0 +1,228 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/users"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func receiveEvents(t *testing.T, watcher *store.Watcher, count int) []store.Event {
+	events := []store.Event{}
+	for len(events) < count {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				t.Fatalf("Returned unexpected close after %v events", len(events))
+			}
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatalf("Returned unexpected timeout after %v events", len(events))
+		}
+	}
+	return events
+}
+
+func describeEvents(events []store.Event) string {
+	described := []string{}
+	for _, event := range events {
+		described = append(described, fmt.Sprintf("%d:%s:%s", event.Sequence, event.Type, event.Key))
+	}
+	return fmt.Sprint(described)
+}
+
+func TestWatchReceivesPutAndDelete(t *testing.T) {
+
+	kvStore := NewMockStore()
+	watcher, err := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: key1})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+	kvStore.MakeDeleteRequest(context.Background(), key1, owner1)
+
+	events := receiveEvents(t, watcher, 2)
+	expected := "[1:put:key1 3:delete:key1]"
+	if describeEvents(events) != expected {
+		t.Errorf("Returned unexpected events: got %v want %v", describeEvents(events), expected)
+	}
+	if string(events[0].Value) != value1 || events[0].Owner != owner1 || events[0].Version == 0 {
+		t.Errorf("Returned unexpected event: got %+v", events[0])
+	}
+}
+
+func TestWatchPrefixReceivesExpiryAndEviction(t *testing.T) {
+
+	kvStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), store.Config{Depth: 1, ExpiryInterval: time.Millisecond})
+	watcher, _ := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: "key", Prefix: true})
+
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakePutRequestWithOptions(context.Background(), key2, []byte(value2), owner2, store.PutOptions{TTL: time.Millisecond})
+
+	events := receiveEvents(t, watcher, 4)
+	expected := "[1:put:key1 2:evict:key1 3:put:key2 4:expire:key2]"
+	if describeEvents(events) != expected {
+		t.Errorf("Returned unexpected events: got %v want %v", describeEvents(events), expected)
+	}
+}
+
+func TestWatchResumesAfterSequence(t *testing.T) {
+
+	kvStore := NewMockStore()
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+	kvStore.MakePutRequest(context.Background(), key1, value2, owner1)
+
+	watcher, err := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: key1, After: 1})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	kvStore.MakeDeleteRequest(context.Background(), key1, owner1)
+
+	events := receiveEvents(t, watcher, 2)
+	expected := "[3:put:key1 4:delete:key1]"
+	if describeEvents(events) != expected {
+		t.Errorf("Returned unexpected events: got %v want %v", describeEvents(events), expected)
+	}
+}
+
+func TestWatchFailsIfPositionLost(t *testing.T) {
+
+	kvStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), store.Config{WatchHistory: 2})
+	for i := 0; i < 4; i++ {
+		kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	}
+
+	_, err := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: key1, After: 1})
+	if !errors.Is(err, common.ErrorWatchPositionLost) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorWatchPositionLost)
+	}
+
+	_, err = kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: key1, After: 2})
+	if err != nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	_, err = kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: key1, After: 10})
+	if !errors.Is(err, common.ErrorWatchPositionLost) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorWatchPositionLost)
+	}
+}
+
+func TestUnwatchClosesEvents(t *testing.T) {
+
+	kvStore := NewMockStore()
+	watcher, _ := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: key1})
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+
+	kvStore.MakeUnwatchRequest(watcher)
+	kvStore.MakePutRequest(context.Background(), key1, value2, owner1)
+
+	if _, ok := <-watcher.Events; ok {
+		t.Errorf("Returned unexpected event after unwatch")
+	}
+}
+
+func TestShutdownClosesWatchers(t *testing.T) {
+
+	kvStore := NewMockStore()
+	watcher, _ := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Prefix: true})
+
+	kvStore.MakeShutdownRequest(context.Background())
+
+	select {
+	case _, ok := <-watcher.Events:
+		if ok {
+			t.Errorf("Returned unexpected event after shutdown")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Returned unexpected timeout waiting for close")
+	}
+	kvStore.MakeUnwatchRequest(watcher)
+}
+
+func TestWatchLeavesOutValuesOfPrivateKeys(t *testing.T) {
+
+	kvStore := NewMockStore()
+	watcher, err := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: "key", Prefix: true, Reader: owner1})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	private := store.PutOptions{Visibility: store.VisibilityPrivate}
+	kvStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, private)
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+
+	events := receiveEvents(t, watcher, 2)
+	if string(events[0].Value) != "" || string(events[1].Value) != value2 {
+		t.Errorf("Returned unexpected values: got %q, %q want %q, %q", events[0].Value, events[1].Value, "", value2)
+	}
+}
+
+func TestWatchHidesPrivateKeysFromOtherUsers(t *testing.T) {
+
+	kvStore := NewMockStore()
+	query := store.WatchQuery{Key: "key", Prefix: true, Reader: owner2}
+	watcher, err := kvStore.MakeWatchRequest(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	private := store.PutOptions{Visibility: store.VisibilityPrivate}
+	kvStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, private)
+	kvStore.MakeDeleteRequest(context.Background(), key1, owner1)
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+
+	expected := "[3:put:key2]"
+	if events := receiveEvents(t, watcher, 1); describeEvents(events) != expected {
+		t.Errorf("Returned unexpected events: got %v want %v", describeEvents(events), expected)
+	}
+
+	query.After = 1
+	resumed, err := kvStore.MakeWatchRequest(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if events := receiveEvents(t, resumed, 1); describeEvents(events) != expected {
+		t.Errorf("Returned unexpected events: got %v want %v", describeEvents(events), expected)
+	}
+}
+
+func TestWatchHistoryLeavesOutValues(t *testing.T) {
+
+	kvStore := NewMockStore()
+	watcher, err := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: key1})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	// the first event has sequence 1, and resuming after 0 means from now on
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+
+	value := []byte{0xff, 0xfe, 0x00}
+	options := store.PutOptions{ContentType: "application/octet-stream"}
+	kvStore.MakePutRequestWithOptions(context.Background(), key1, value, owner1, options)
+	kvStore.MakePutRequest(context.Background(), key1, value2, owner1)
+
+	live := receiveEvents(t, watcher, 1)[0]
+	if !bytes.Equal(live.Value, value) || live.ContentType != options.ContentType {
+		t.Errorf("Returned unexpected event: got %+v want value %v", live, value)
+	}
+
+	resumed, err := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: key1, After: live.Sequence - 1})
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	for _, event := range receiveEvents(t, resumed, 2) {
+		if event.Value != nil || event.Version == 0 {
+			t.Errorf("Returned unexpected event: got %+v want no value", event)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package store
+
+import (
+	"bufio"
+	"demo-store/common"
+	"demo-store/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	LogOpPut    = "put"
+	LogOpRead   = "read"
+	LogOpDelete = "delete"
+	LogOpEvict  = "evict"
+	LogOpExpire = "expire"
+	LogOpBatch  = "batch"
+)
+
+type FsyncPolicy int
+
+const (
+	FsyncAlways FsyncPolicy = iota
+	FsyncInterval
+	FsyncNever
+)
+
+func ParseFsyncPolicy(value string) (FsyncPolicy, error) {
+	switch value {
+	case "always":
+		return FsyncAlways, nil
+	case "interval":
+		return FsyncInterval, nil
+	case "never":
+		return FsyncNever, nil
+	}
+
+	return FsyncNever, fmt.Errorf("unknown fsync policy %q", value)
+}
+
+// LogRecord is a single mutation in the write log. Put records carry the
+// full entry, read records only the counters and timestamp that changed.
+//
+// Values are written to Data, which JSON encodes as base64 so any bytes
+// survive. Value holds the text of records written before values were binary.
+type LogRecord struct {
+	Op        string     `json:"op"`
+	Key       string     `json:"key"`
+	Value     string     `json:"value,omitempty"`
+	Data      []byte     `json:"data,omitempty"`
+	Owner     string     `json:"owner,omitempty"`
+	Reads     int        `json:"reads,omitempty"`
+	Writes    int        `json:"writes,omitempty"`
+	Version   uint64     `json:"version,omitempty"`
+	Flags     uint32     `json:"flags,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+	Expires   *time.Time `json:"expires,omitempty"`
+
+	ContentType     string `json:"content_type,omitempty"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+
+	ACL     ACL  `json:"acl,omitempty"`
+	Private bool `json:"private,omitempty"`
+
+	// Records holds the mutations of a batch, which are replayed together.
+	Records []LogRecord `json:"records,omitempty"`
+}
+
+func CreateLogRecord(op string, entry *Entry) LogRecord {
+	record := LogRecord{Op: op, Key: entry.Key, Reads: entry.Reads, Version: entry.Version, Timestamp: entry.Timestamp}
+	if op == LogOpPut {
+		record.Data = entry.Value
+		record.Owner = entry.Owner
+		record.Writes = entry.Writes
+		record.Flags = entry.Flags
+		record.ContentType = entry.ContentType
+		record.ContentEncoding = entry.ContentEncoding
+		record.ACL = entry.ACL
+		record.Private = entry.Private
+		if !entry.Expires.IsZero() {
+			expires := entry.Expires
+			record.Expires = &expires
+		}
+	}
+	return record
+}
+
+func (r *LogRecord) Entry() *Entry {
+	entry := &Entry{
+		Key:       r.Key,
+		Value:     r.Data,
+		Owner:     r.Owner,
+		Reads:     r.Reads,
+		Writes:    r.Writes,
+		Version:   r.Version,
+		Flags:     r.Flags,
+		Timestamp: r.Timestamp,
+
+		ContentType:     r.ContentType,
+		ContentEncoding: r.ContentEncoding,
+
+		ACL:     r.ACL,
+		Private: r.Private,
+	}
+	if entry.Value == nil {
+		entry.Value = []byte(r.Value)
+	}
+	if r.Expires != nil {
+		entry.Expires = *r.Expires
+	}
+	return entry
+}
+
+func SegmentFileName(segment int) string {
+	return fmt.Sprintf("store-%06d.wal", segment)
+}
+
+// WriteLog is an append-only sequence of segment files holding LogRecords, one
+// JSON document per line. Records are always appended to the newest segment.
+type WriteLog struct {
+	tracer  utils.Tracer
+	dir     string
+	policy  FsyncPolicy
+	segment int
+	file    *os.File
+	dirty   bool
+	mutex   sync.Mutex
+	stop    chan bool
+}
+
+func OpenWriteLog(tracer utils.Tracer, dir string, policy FsyncPolicy, interval time.Duration) (*WriteLog, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	writeLog := &WriteLog{tracer: tracer, dir: dir, policy: policy, segment: 1, stop: make(chan bool)}
+
+	segments, err := writeLog.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > 0 {
+		writeLog.segment = segments[len(segments)-1]
+	}
+
+	writeLog.file, err = os.OpenFile(writeLog.Path(), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == FsyncInterval && interval > 0 {
+		go writeLog.syncEvery(interval)
+	}
+
+	return writeLog, nil
+}
+
+func (w *WriteLog) Dir() string {
+	return w.dir
+}
+
+// Path returns the segment currently being appended to.
+func (w *WriteLog) Path() string {
+	return filepath.Join(w.dir, SegmentFileName(w.segment))
+}
+
+// Replay calls apply for every record in segments from the given one onwards,
+// oldest first. A torn record at the end of a segment (a crash mid-write) is
+// dropped and truncated away so later appends start on a clean line.
+func (w *WriteLog) Replay(fromSegment int, apply func(record LogRecord) error) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		if segment < fromSegment || segment == w.segment {
+			continue
+		}
+
+		file, err := os.OpenFile(filepath.Join(w.dir, SegmentFileName(segment)), os.O_RDWR, 0666)
+		if err != nil {
+			return err
+		}
+		err = w.replayFile(file, apply)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.segment < fromSegment {
+		return nil
+	}
+	return w.replayFile(w.file, apply)
+}
+
+func (w *WriteLog) replayFile(file *os.File, apply func(record LogRecord) error) error {
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	line := 0
+	for {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(data) > 0 {
+				w.tracer.LogWarning("Dropping incomplete record at the end of", file.Name())
+				if err := file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		line++
+		var record LogRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("%w: %s line %d: %v", common.ErrorWriteLogCorrupt, file.Name(), line, err)
+		}
+		if err := apply(record); err != nil {
+			return err
+		}
+		offset += int64(len(data))
+	}
+
+	_, err := file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// Rotate closes the current segment and starts appending to a new one,
+// returning the new segment number.
+func (w *WriteLog) Rotate() (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.dirty = true
+	if err := w.sync(); err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(w.dir, SegmentFileName(w.segment+1)), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return 0, err
+	}
+
+	w.file.Close()
+	w.file = file
+	w.segment++
+
+	return w.segment, nil
+}
+
+// RemoveSegmentsBefore deletes every segment older than the given one.
+func (w *WriteLog) RemoveSegmentsBefore(segment int) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range segments {
+		if existing >= segment {
+			break
+		}
+		if err := os.Remove(filepath.Join(w.dir, SegmentFileName(existing))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WriteLog) segments() ([]int, error) {
+	names, err := filepath.Glob(filepath.Join(w.dir, "store-*.wal"))
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]int, 0, len(names))
+	for _, name := range names {
+		var segment int
+		if _, err := fmt.Sscanf(filepath.Base(name), "store-%06d.wal", &segment); err == nil {
+			segments = append(segments, segment)
+		}
+	}
+
+	sort.Ints(segments)
+	return segments, nil
+}
+
+func (w *WriteLog) Append(record LogRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, err := w.file.Write(data); err != nil {
+		return err
+	}
+
+	if w.policy == FsyncAlways {
+		return w.file.Sync()
+	}
+	w.dirty = true
+	return nil
+}
+
+func (w *WriteLog) Sync() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.sync()
+}
+
+func (w *WriteLog) sync() error {
+	if !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.file.Sync()
+}
+
+func (w *WriteLog) Close() error {
+	close(w.stop)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.sync(); err != nil {
+		w.tracer.LogError("Error syncing write log", err)
+	}
+	return w.file.Close()
+}
+
+func (w *WriteLog) syncEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.Sync(); err != nil {
+				w.tracer.LogError("Error syncing write log", err)
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"demo-store/common"
+	"demo-store/store"
+	"demo-store/users"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func NewMockPersistentStore(t *testing.T, dir string, depth int) *store.KvStore {
+	writeLog, err := store.OpenWriteLog(CreateMockTracer(), dir, store.FsyncAlways, 0)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	config := store.Config{Depth: depth, WriteLog: writeLog}
+	kvStore, err := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), config)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	return kvStore
+}
+
+func restartMockPersistentStore(t *testing.T, kvStore *store.KvStore, dir string, depth int) *store.KvStore {
+	kvStore.MakeShutdownRequest(context.Background())
+	return NewMockPersistentStore(t, dir, depth)
+}
+
+func TestParseFsyncPolicy(t *testing.T) {
+
+	expected := map[string]store.FsyncPolicy{
+		"always":   store.FsyncAlways,
+		"interval": store.FsyncInterval,
+		"never":    store.FsyncNever,
+	}
+	for value, policy := range expected {
+		got, err := store.ParseFsyncPolicy(value)
+		if err != nil || got != policy {
+			t.Errorf("Returned unexpected policy: got %v want %v", got, policy)
+		}
+	}
+
+	if _, err := store.ParseFsyncPolicy("sometimes"); err == nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "error")
+	}
+}
+
+func TestWriteLogReplaysRecordsInOrder(t *testing.T) {
+
+	dir := t.TempDir()
+	writeLog, _ := store.OpenWriteLog(CreateMockTracer(), dir, store.FsyncNever, 0)
+	for i := 0; i < 3; i++ {
+		writeLog.Append(store.CreateLogRecord(store.LogOpPut, store.NewEntry(fmt.Sprint("key", i), []byte(value1), owner1)))
+	}
+	writeLog.Close()
+
+	writeLog, _ = store.OpenWriteLog(CreateMockTracer(), dir, store.FsyncNever, 0)
+	defer writeLog.Close()
+
+	var keys []string
+	writeLog.Replay(0, func(record store.LogRecord) error {
+		keys = append(keys, record.Key)
+		return nil
+	})
+
+	for i, key := range keys {
+		expected := fmt.Sprint("key", i)
+		if key != expected {
+			t.Errorf("Returned unexpected key: got %v want %v", key, expected)
+		}
+	}
+	if len(keys) != 3 {
+		t.Errorf("Returned unexpected record count: got %v want %v", len(keys), 3)
+	}
+}
+
+func TestWriteLogDropsTornRecord(t *testing.T) {
+
+	dir := t.TempDir()
+	writeLog, _ := store.OpenWriteLog(CreateMockTracer(), dir, store.FsyncNever, 0)
+	writeLog.Append(store.CreateLogRecord(store.LogOpPut, store.NewEntry(key1, []byte(value1), owner1)))
+	writeLog.Close()
+
+	file, _ := os.OpenFile(filepath.Join(dir, store.SegmentFileName(1)), os.O_APPEND|os.O_WRONLY, 0666)
+	file.Write([]byte(`{"op":"put","key":"ke`))
+	file.Close()
+
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	kvStore.MakePutRequest(context.Background(), key2, value2, owner2)
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+
+	entries, _ := kvStore.MakeListAllRequest(context.Background(), owner1)
+	if len(entries) != 2 {
+		t.Errorf("Returned unexpected entry count: got %v want %v", len(entries), 2)
+	}
+}
+
+func TestWriteLogReportsCorruptRecord(t *testing.T) {
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, store.SegmentFileName(1)), []byte("not json\n"), 0666)
+
+	writeLog, _ := store.OpenWriteLog(CreateMockTracer(), dir, store.FsyncNever, 0)
+	_, err := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), store.Config{WriteLog: writeLog})
+
+	if !errors.Is(err, common.ErrorWriteLogCorrupt) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorWriteLogCorrupt)
+	}
+}
+
+func TestPersistentStoreRestoresEntries(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakePutRequest(context.Background(), key1, value2, owner1)
+	kvStore.MakeGetRequest(context.Background(), key1, owner1)
+	before, _ := kvStore.MakeListRequest(context.Background(), key1, owner1)
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+
+	entry, err := kvStore.MakeListRequest(context.Background(), key1, owner1)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	if entry.String() != before.String() {
+		t.Errorf("Returned unexpected entry: got %v want %v", entry.String(), before.String())
+	}
+	if entry.Reads != 1 || entry.Writes != 2 {
+		t.Errorf("Returned unexpected counters: got %v/%v want %v/%v", entry.Reads, entry.Writes, 1, 2)
+	}
+	if !entry.Timestamp.Equal(before.Timestamp) {
+		t.Errorf("Returned unexpected timestamp: got %v want %v", entry.Timestamp, before.Timestamp)
+	}
+}
+
+func TestReadsAreRecordedTogether(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	for i := 0; i < 10; i++ {
+		kvStore.MakeGetRequest(context.Background(), key1, owner1)
+	}
+	kvStore.MakeShutdownRequest(context.Background())
+
+	data, _ := os.ReadFile(filepath.Join(dir, store.SegmentFileName(1)))
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("Returned unexpected record count: got %v want %v", lines, 2)
+	}
+
+	kvStore = NewMockPersistentStore(t, dir, 0)
+	if entry, _ := kvStore.MakeListRequest(context.Background(), key1, owner1); entry == nil || entry.Reads != 10 {
+		t.Errorf("Returned unexpected entry: got %v want %v reads", entry, 10)
+	}
+}
+
+func TestWriteLogReportsCorruptBatch(t *testing.T) {
+
+	dir := t.TempDir()
+	batch := `{"op": "batch", "records": [{"op": "put", "key": "key1", "data": "dmFsdWUx"}, {"op": "rename", "key": "key1"}]}`
+	os.WriteFile(filepath.Join(dir, store.SegmentFileName(1)), []byte(batch+"\n"), 0666)
+
+	writeLog, _ := store.OpenWriteLog(CreateMockTracer(), dir, store.FsyncNever, 0)
+	_, err := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), store.Config{WriteLog: writeLog})
+
+	if !errors.Is(err, common.ErrorWriteLogCorrupt) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorWriteLogCorrupt)
+	}
+}
+
+func TestPersistentStoreRestoresFlags(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	kvStore.MakePutRequestWithOptions(context.Background(), key1, []byte(value1), owner1, store.PutOptions{Flags: 42})
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+
+	entry, _ := kvStore.MakeListRequest(context.Background(), key1, owner1)
+	if entry == nil || entry.Flags != 42 {
+		t.Errorf("Returned unexpected flags: got %v want %v", entry, 42)
+	}
+}
+
+func TestPersistentStoreRestoresBinaryValues(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	value := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, '\n'}
+	options := store.PutOptions{ContentType: "image/png", ContentEncoding: "identity"}
+	kvStore.MakePutRequestWithOptions(context.Background(), key1, value, owner1, options)
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+
+	entry, err := kvStore.MakeReadRequest(context.Background(), key1, owner1)
+	if err != nil || !bytes.Equal(entry.Value, value) {
+		t.Fatalf("Returned unexpected value: got %v, %v want %v", entry, err, value)
+	}
+	if entry.ContentType != options.ContentType || entry.ContentEncoding != options.ContentEncoding {
+		t.Errorf("Returned unexpected content type: got %v %v want %v %v", entry.ContentType, entry.ContentEncoding, options.ContentType, options.ContentEncoding)
+	}
+}
+
+func TestWriteLogReplaysTextValueRecords(t *testing.T) {
+
+	dir := t.TempDir()
+	record := `{"op":"put","key":"key1","value":"some text","owner":"owner1","writes":1,"version":1,"timestamp":"2024-01-01T00:00:00Z"}` + "\n"
+	os.WriteFile(filepath.Join(dir, store.SegmentFileName(1)), []byte(record), 0666)
+
+	kvStore := NewMockPersistentStore(t, dir, 0)
+
+	if value, err := kvStore.MakeGetRequest(context.Background(), key1, owner1); err != nil || value != "some text" {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, "some text")
+	}
+}
+
+func TestPersistentStoreRestoresDeletes(t *testing.T) {
+
+	dir := t.TempDir()
+	kvStore := NewMockPersistentStore(t, dir, 0)
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	kvStore.MakeDeleteRequest(context.Background(), key1, owner1)
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, 0)
+
+	_, err := kvStore.MakeListRequest(context.Background(), key1, owner1)
+	if err != common.ErrorKeyNotFound {
+		t.Errorf("Returned unexpected error: got %v want %v", err, common.ErrorKeyNotFound)
+	}
+}
+
+func TestPersistentStoreRestoresLruOrder(t *testing.T) {
+
+	dir := t.TempDir()
+	depth := 2
+	kvStore := NewMockPersistentStore(t, dir, depth)
+	kvStore.MakePutRequest(context.Background(), "key0", value1, owner1)
+	kvStore.MakePutRequest(context.Background(), "key1", value1, owner1)
+	kvStore.MakeGetRequest(context.Background(), "key0", owner1)
+	kvStore.MakePutRequest(context.Background(), "key2", value1, owner1)
+
+	kvStore = restartMockPersistentStore(t, kvStore, dir, depth)
+	kvStore.MakePutRequest(context.Background(), "key3", value1, owner1)
+
+	entries, _ := kvStore.MakeListAllRequest(context.Background(), owner1)
+	expected := []string{"key2", "key3"}
+	for i, entry := range entries {
+		if entry.Key != expected[i] {
+			t.Errorf("Returned unexpected key: got %v want %v", entry.Key, expected[i])
+		}
+	}
+}
+
+func TestWritesTheLogRefusesAreNotApplied(t *testing.T) {
+
+	writeLog, err := store.OpenWriteLog(CreateMockTracer(), t.TempDir(), store.FsyncAlways, 0)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	kvStore, _ := store.CreateKvStoreWithConfig(CreateMockTracer(), users.CreateUserDatabase(), store.Config{WriteLog: writeLog})
+	kvStore.MakePutRequest(context.Background(), key1, value1, owner1)
+	watcher, _ := kvStore.MakeWatchRequest(context.Background(), store.WatchQuery{Key: "key", Prefix: true})
+
+	// appends fail from here on, as they would on a full disk
+	writeLog.Close()
+
+	if err := kvStore.MakePutRequest(context.Background(), key1, value2, owner1); err == nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "an append error")
+	}
+	if err := kvStore.MakePutRequest(context.Background(), key2, value2, owner1); err == nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "an append error")
+	}
+	if err := kvStore.MakeDeleteRequest(context.Background(), key1, owner1); err == nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "an append error")
+	}
+	batch := []store.BatchOperation{{Op: store.BatchOpPut, Key: key2, Value: []byte(value2)}, {Op: store.BatchOpDelete, Key: key1}}
+	if _, err := kvStore.MakeBatchRequest(context.Background(), batch, owner1); err == nil {
+		t.Errorf("Returned unexpected error: got %v want %v", err, "an append error")
+	}
+
+	if value, err := kvStore.MakeGetRequest(context.Background(), key1, owner1); err != nil || value != value1 {
+		t.Errorf("Returned unexpected value: got %v, %v want %v", value, err, value1)
+	}
+
+	entries, _ := kvStore.MakeListAllRequest(context.Background(), owner1)
+	if len(entries) != 1 || string(entries[0].Value) != value1 || entries[0].Version != 1 {
+		t.Errorf("Returned unexpected entries: got %v want only %v", entries, key1)
+	}
+	if stats, _ := kvStore.MakeStatsRequest(context.Background()); stats.Puts != 1 || stats.Deletes != 0 {
+		t.Errorf("Returned unexpected stats: got %+v want %v puts", stats, 1)
+	}
+	select {
+	case event := <-watcher.Events:
+		t.Errorf("Returned unexpected event: got %+v want none", event)
+	default:
+	}
+}
@@ -0,0 +1,227 @@
+package users
+
+import (
+	"demo-store/common"
+	"sort"
+	"strings"
+)
+
+// Group is a named set of users that can own keys and be granted access to
+// them, so keys don't depend on any one user staying around.
+type Group struct {
+	Name    string
+	Members []string `json:",omitempty"`
+}
+
+// GroupPrefix marks a key owner or ACL grantee as a group rather than a user.
+// Usernames can't contain ':', so the two never clash.
+const GroupPrefix = "group:"
+
+var ErrorGroupNotFound = common.ErrorGroupNotFound
+var ErrorGroupExists = common.ErrorGroupExists
+var ErrorInvalidGroup = common.ErrorInvalidGroup
+
+// GroupOwner returns how the group is named as a key owner or ACL grantee.
+func GroupOwner(name string) string {
+	return GroupPrefix + name
+}
+
+// ParseGroupOwner returns the group an owner or grantee names, if it names one.
+func ParseGroupOwner(owner string) (string, bool) {
+	if !strings.HasPrefix(owner, GroupPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(owner, GroupPrefix), true
+}
+
+// ValidateGroup rejects empty group names and names that couldn't be used in
+// a URL path or owner.
+func ValidateGroup(name string) error {
+	if name == "" || strings.ContainsAny(name, ":/") {
+		return ErrorInvalidGroup
+	}
+	return nil
+}
+
+// Includes reports whether owner is the user or a group the user is a member
+// of. A group is only ever matched through its members, never by a user of
+// the same name.
+func (u *UserStorage) Includes(owner string, username string) bool {
+	name, ok := ParseGroupOwner(owner)
+	if !ok {
+		return owner == username
+	}
+
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	group, ok := u.groups[name]
+	return ok && containsMember(group.Members, username)
+}
+
+// FindGroup returns a copy of the group.
+func (u *UserStorage) FindGroup(name string) (*Group, error) {
+
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	group, ok := u.groups[name]
+	if !ok {
+		return nil, ErrorGroupNotFound
+	}
+	return group.clone(), nil
+}
+
+// ListGroups returns a copy of every group, ordered by name.
+func (u *UserStorage) ListGroups() []Group {
+
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	groups := make([]Group, 0, len(u.groups))
+	for _, group := range u.groups {
+		groups = append(groups, *group.clone())
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+func (u *UserStorage) AddGroup(name string) error {
+
+	if err := ValidateGroup(name); err != nil {
+		return err
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if _, ok := u.groups[name]; ok {
+		return ErrorGroupExists
+	}
+
+	u.groups[name] = &Group{Name: name}
+	return u.commitGroup(name, nil)
+}
+
+// AddGroupMember adds a user the database knows to the group.
+func (u *UserStorage) AddGroupMember(name string, username string) error {
+
+	if _, err := u.FindUser(username); err != nil {
+		return err
+	}
+
+	return u.updateGroup(name, func(group *Group) {
+		if !containsMember(group.Members, username) {
+			group.Members = append(group.Members, username)
+			sort.Strings(group.Members)
+		}
+	})
+}
+
+func (u *UserStorage) RemoveGroupMember(name string, username string) error {
+	return u.updateGroup(name, func(group *Group) {
+		group.Members = removeMember(group.Members, username)
+	})
+}
+
+// DeleteGroup removes the group. Keys it owns are left to users allowed to
+// override.
+func (u *UserStorage) DeleteGroup(name string) error {
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	group, ok := u.groups[name]
+	if !ok {
+		return ErrorGroupNotFound
+	}
+
+	delete(u.groups, name)
+	return u.commitGroup(name, group)
+}
+
+// updateGroup changes a copy of the group, replacing the group with it once
+// saved.
+func (u *UserStorage) updateGroup(name string, change func(group *Group)) error {
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	group, ok := u.groups[name]
+	if !ok {
+		return ErrorGroupNotFound
+	}
+
+	updated := group.clone()
+	change(updated)
+
+	u.groups[name] = updated
+	return u.commitGroup(name, group)
+}
+
+// commitGroup saves the groups after a change to name, putting back previous
+// if the change can't be saved. It is called with the mutex held.
+func (u *UserStorage) commitGroup(name string, previous *Group) error {
+
+	if u.path == "" {
+		return nil
+	}
+	if err := u.saveGroups(u.path); err != nil {
+		if previous != nil {
+			u.groups[name] = previous
+		} else {
+			delete(u.groups, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// removeFromGroups takes a deleted user out of every group, so a new user
+// given the same name doesn't join their groups. It is called with the mutex
+// held and returns the groups as they were.
+func (u *UserStorage) removeFromGroups(username string) map[string]*Group {
+
+	previous := make(map[string]*Group)
+	for name, group := range u.groups {
+		if containsMember(group.Members, username) {
+			updated := group.clone()
+			updated.Members = removeMember(updated.Members, username)
+			previous[name] = group
+			u.groups[name] = updated
+		}
+	}
+	return previous
+}
+
+// restoreGroups puts back the groups removeFromGroups changed.
+func (u *UserStorage) restoreGroups(groups map[string]*Group) {
+	for name, group := range groups {
+		u.groups[name] = group
+	}
+}
+
+func (g *Group) clone() *Group {
+	return &Group{Name: g.Name, Members: append([]string(nil), g.Members...)}
+}
+
+func containsMember(members []string, username string) bool {
+	for _, member := range members {
+		if member == username {
+			return true
+		}
+	}
+	return false
+}
+
+func removeMember(members []string, username string) []string {
+	var kept []string
+	for _, member := range members {
+		if member != username {
+			kept = append(kept, member)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,100 @@
+package users
+
+import "demo-store/common"
+
+// Role names a set of permissions given to a user.
+type Role string
+
+const (
+	// RoleAdmin may do anything, including managing users and changing
+	// keys owned by others.
+	RoleAdmin Role = "admin"
+	// RoleWriter may read keys and write keys of their own.
+	RoleWriter Role = "writer"
+	// RoleReader may only read keys.
+	RoleReader Role = "reader"
+	// RoleOperator may read keys and run the store: shutting it down, taking
+	// snapshots and reading its stats.
+	RoleOperator Role = "operator"
+)
+
+// Permission is something a user may be allowed to do.
+type Permission int
+
+const (
+	PermissionRead Permission = iota
+	PermissionWrite
+	// PermissionOverride allows changing and deleting keys owned by others.
+	PermissionOverride
+	// PermissionOperate allows shutting down the store, taking snapshots and
+	// reading stats.
+	PermissionOperate
+	PermissionManageUsers
+)
+
+var ErrorInvalidRole = common.ErrorInvalidRole
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:    {PermissionRead, PermissionWrite, PermissionOverride, PermissionOperate, PermissionManageUsers},
+	RoleWriter:   {PermissionRead, PermissionWrite},
+	RoleReader:   {PermissionRead},
+	RoleOperator: {PermissionRead, PermissionOperate},
+}
+
+// DefaultRoles are the roles of a user who wasn't given any: the admin user
+// is an admin and everyone else a writer. Users saved before roles existed
+// are given these when loaded.
+func DefaultRoles(username string) []Role {
+	if username == AdminUserName {
+		return []Role{RoleAdmin}
+	}
+	return []Role{RoleWriter}
+}
+
+// UnknownUserRoles are the roles of users the database doesn't know. They may
+// write keys of their own, as anyone may own keys, but nothing more.
+var UnknownUserRoles = []Role{RoleWriter}
+
+// RolesHavePermission reports whether any of the roles grants permission.
+func RolesHavePermission(roles []Role, permission Permission) bool {
+	for _, role := range roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidateRoles rejects an empty list and roles that don't exist.
+func ValidateRoles(roles []Role) error {
+	if len(roles) == 0 {
+		return ErrorInvalidRole
+	}
+	for _, role := range roles {
+		if _, ok := rolePermissions[role]; !ok {
+			return ErrorInvalidRole
+		}
+	}
+	return nil
+}
+
+// HasRole reports whether role is one of roles.
+func HasRole(roles []Role, role Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleNames returns the roles as strings, as they appear in tokens.
+func RoleNames(roles []Role) []string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return names
+}
@@ -3,22 +3,35 @@ package users
 import (
 	"demo-store/common"
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 )
 
-func CreateUserDatabase() UserDatabase {
+// UsersFileName is the file in the cache directory holding the users.
+const UsersFileName = "users.dat"
 
-	userStorage := UserStorage{}
-	userStorage.data = make(map[string]*User)
+// GroupsFileName is the file in the cache directory holding the groups. Caches
+// saved before groups existed don't have one.
+const GroupsFileName = "groups.dat"
 
-	return &userStorage
+func CreateUserDatabase() UserDatabase {
+	return newUserStorage("")
 }
 
+func newUserStorage(path string) *UserStorage {
+	return &UserStorage{data: make(map[string]*User), groups: make(map[string]*Group), path: path}
+}
+
+// Load returns the users cached in path, saving changes back to it. If there
+// are none yet it starts without any, creating the cache on the first change,
+// but a cache it can't read is left alone and changes are kept in memory.
 func Load(path string) UserDatabase {
 	usersDatabase, err := LoadFromCache(path)
-	if err != nil {
+	if errors.Is(err, os.ErrNotExist) {
+		usersDatabase = newUserStorage(path)
+	} else if err != nil {
 		usersDatabase = CreateUserDatabase()
 	}
 
@@ -31,12 +44,12 @@ func LoadFromCache(path string) (UserDatabase, error) {
 		return nil, os.ErrNotExist
 	}
 
-	file, err := os.Open(filepath.Join(path, "users.dat"))
-	defer file.Close()
+	file, err := os.Open(filepath.Join(path, UsersFileName))
 	if err != nil {
 		//log.Fatal(fmt.Printf("Error opening user.dat file %s", err))
 		return nil, err
 	}
+	defer file.Close()
 
 	data, err := io.ReadAll(file)
 	if err != nil {
@@ -50,35 +63,86 @@ func LoadFromCache(path string) (UserDatabase, error) {
 		return nil, serr
 	}
 
-	userStorage := UserStorage{}
-	userStorage.data = make(map[string]*User)
-
+	userStorage := newUserStorage(path)
 	for _, user := range users {
-		userStorage.data[user.UserName] = &User{UserName: user.UserName, HashPassword: user.HashPassword}
+		// users saved before roles existed get the roles they had implicitly,
+		// and are saved with them on the next change
+		roles := user.Roles
+		if len(roles) == 0 {
+			roles = DefaultRoles(user.UserName)
+		}
+		userStorage.data[user.UserName] = &User{UserName: user.UserName, HashPassword: user.HashPassword, Disabled: user.Disabled, Roles: roles}
+	}
+
+	groups, err := loadGroups(path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		userStorage.groups[groups[i].Name] = &groups[i]
 	}
 
-	return &userStorage, nil
+	return userStorage, nil
 }
 
+// SaveToCache writes the users to path. The file is replaced in one step so a
+// crash leaves either the old or the new users, never part of them.
 func SaveToCache(path string, user *UserStorage) error {
+
+	user.mutex.RLock()
+	defer user.mutex.RUnlock()
+
+	return user.save(path)
+}
+
+// save writes the users to path with the mutex held.
+func (u *UserStorage) save(path string) error {
 	common.CreateDirIfNotExists(path)
 
-	file, err := os.Create(filepath.Join(path, "users.dat"))
-	defer file.Close()
+	values := make([]*User, 0, len(u.data))
+	for k := range u.data {
+		values = append(values, u.data[k])
+	}
+	json, err := common.ToJson(values)
+	if err != nil {
+		return err
+	}
 
+	return common.WriteFileAtomic(filepath.Join(path, UsersFileName), []byte(json))
+}
+
+// saveGroups writes the groups to path with the mutex held.
+func (u *UserStorage) saveGroups(path string) error {
+	common.CreateDirIfNotExists(path)
+
+	values := make([]*Group, 0, len(u.groups))
+	for k := range u.groups {
+		values = append(values, u.groups[k])
+	}
+	json, err := common.ToJson(values)
 	if err != nil {
-		//log.Fatal(fmt.Printf("Error initialising user.dat file %s", err))
 		return err
 	}
 
-	values := make([]*User, 0, len(user.data))
-	for k := range user.data {
-		values = append(values, user.data[k])
+	return common.WriteFileAtomic(filepath.Join(path, GroupsFileName), []byte(json))
+}
+
+// loadGroups reads the groups cached in path, if there are any.
+func loadGroups(path string) ([]Group, error) {
+
+	data, err := os.ReadFile(filepath.Join(path, GroupsFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	json, _ := common.ToJson(values)
-	file.Write([]byte(json))
-	return nil
+	var groups []Group
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
 }
 
 func ToObject(jsonString string) ([]User, error) {
@@ -4,6 +4,8 @@ import (
 	"demo-store/common"
 	"demo-store/users"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -132,3 +134,156 @@ func TestUserDatabaseLoadWhenUserCacheExists(t *testing.T) {
 		t.Errorf("Unexpected error : got %v want %v,", err, "nil")
 	}
 }
+
+func TestAuthFailsForDisabledUser(t *testing.T) {
+
+	storage := users.CreateUserDatabase()
+	storage.AddUser("user1", "11111")
+
+	if err := storage.SetDisabled("user1", true); err != nil {
+		t.Errorf("Unexpected error: got %v want %v,", err, "nil")
+	}
+	if err := storage.Authenticate("user1", "11111"); err != users.ErrorUserDisabled {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorUserDisabled)
+	}
+	if err := storage.CheckUser("user1"); err != users.ErrorUserDisabled {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorUserDisabled)
+	}
+}
+
+func TestAdminUserCannotBeDisabledOrDeleted(t *testing.T) {
+
+	storage := users.CreateUserDatabase()
+	storage.AddUser(users.AdminUserName, "admin")
+
+	if err := storage.SetDisabled(users.AdminUserName, true); err != users.ErrorUserProtected {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorUserProtected)
+	}
+	if err := storage.DeleteUser(users.AdminUserName); err != users.ErrorUserProtected {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorUserProtected)
+	}
+	if err := storage.DeleteUser("user1"); err != users.ErrorUserNotFound {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorUserNotFound)
+	}
+}
+
+func TestAddUserRejectsInvalidUsers(t *testing.T) {
+
+	storage := users.CreateUserDatabase()
+	for _, username := range []string{"", "user:1", "user/1"} {
+		if err := storage.AddUser(username, "11111"); err != users.ErrorInvalidUser {
+			t.Errorf("Unexpected error for %q: got %v want %v,", username, err, users.ErrorInvalidUser)
+		}
+	}
+}
+
+func TestUserChangesAreSavedToCache(t *testing.T) {
+
+	dir := t.TempDir()
+	storage := users.Load(dir)
+	storage.AddUser("user1", "11111")
+	storage.SetPassword("user1", "22222")
+
+	if _, err := os.Stat(filepath.Join(dir, users.UsersFileName+".tmp")); !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: got %v want %v,", err, os.ErrNotExist)
+	}
+
+	reloaded := users.Load(dir)
+	if err := reloaded.Authenticate("user1", "22222"); err != nil {
+		t.Errorf("Unexpected error: got %v want %v,", err, "nil")
+	}
+
+	storage.DeleteUser("user1")
+	if _, err := users.Load(dir).FindUser("user1"); err != users.ErrorUserNotFound {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorUserNotFound)
+	}
+}
+
+func TestRolesGrantPermissions(t *testing.T) {
+
+	storage := users.CreateUserDatabase()
+	storage.AddUser(users.AdminUserName, "admin")
+	storage.AddUserWithRoles("user1", "11111", []users.Role{users.RoleOperator})
+
+	tests := []struct {
+		username   string
+		permission users.Permission
+		expected   bool
+	}{
+		{users.AdminUserName, users.PermissionManageUsers, true},
+		{"user1", users.PermissionOperate, true},
+		{"user1", users.PermissionWrite, false},
+		{"unknown", users.PermissionWrite, true},
+		{"unknown", users.PermissionOperate, false},
+	}
+	for _, test := range tests {
+		if got := storage.HasPermission(test.username, test.permission); got != test.expected {
+			t.Errorf("Unexpected permission %v for %v: got %v want %v,", test.permission, test.username, got, test.expected)
+		}
+	}
+
+	if err := storage.SetRoles(users.AdminUserName, []users.Role{users.RoleWriter}); err != users.ErrorUserProtected {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorUserProtected)
+	}
+	if err := storage.SetRoles("user1", []users.Role{"superuser"}); err != users.ErrorInvalidRole {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorInvalidRole)
+	}
+	storage.SetDisabled("user1", true)
+	if storage.HasPermission("user1", users.PermissionRead) {
+		t.Errorf("Unexpected permission for disabled user: got %v want %v,", true, false)
+	}
+}
+
+func TestUsersWithoutRolesAreMigratedOnLoad(t *testing.T) {
+
+	dir := t.TempDir()
+	saved := `[{"UserName": "admin", "HashPassword": "x"}, {"UserName": "user1", "HashPassword": "y"}]`
+	os.WriteFile(filepath.Join(dir, users.UsersFileName), []byte(saved), 0644)
+
+	storage := users.Load(dir)
+	if !storage.IsAdmin("admin") || storage.IsAdmin("user1") {
+		t.Errorf("Unexpected admin: got %v, %v want %v, %v,", storage.IsAdmin("admin"), storage.IsAdmin("user1"), true, false)
+	}
+	if !storage.HasPermission("user1", users.PermissionWrite) {
+		t.Errorf("Unexpected permission for user1: got %v want %v,", false, true)
+	}
+}
+
+func TestGroupMembersAreIncludedInGroupOwner(t *testing.T) {
+
+	dir := t.TempDir()
+	saved := `[{"UserName": "user1", "HashPassword": "x"}, {"UserName": "user2", "HashPassword": "y"}]`
+	os.WriteFile(filepath.Join(dir, users.UsersFileName), []byte(saved), 0644)
+	storage := users.Load(dir)
+
+	if err := storage.AddGroup("team"); err != nil {
+		t.Fatalf("Unexpected error: got %v want %v,", err, "nil")
+	}
+	if err := storage.AddGroup("team"); err != users.ErrorGroupExists {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorGroupExists)
+	}
+	if err := storage.AddGroup("a:team"); err != users.ErrorInvalidGroup {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorInvalidGroup)
+	}
+	if err := storage.AddGroupMember("team", "unknown"); err != users.ErrorUserNotFound {
+		t.Errorf("Unexpected error: got %v want %v,", err, users.ErrorUserNotFound)
+	}
+	storage.AddGroupMember("team", "user1")
+	storage.AddGroupMember("team", "user2")
+	storage.RemoveGroupMember("team", "user2")
+
+	owner := users.GroupOwner("team")
+	if !storage.Includes(owner, "user1") || storage.Includes(owner, "user2") || storage.Includes("team", "user1") {
+		t.Errorf("Unexpected members: got %v want %v,", storage.ListGroups(), "user1")
+	}
+
+	reloaded := users.Load(dir)
+	if group, err := reloaded.FindGroup("team"); err != nil || fmt.Sprint(group.Members) != "[user1]" {
+		t.Errorf("Unexpected group: got %v, %v want %v,", group, err, "[user1]")
+	}
+
+	storage.DeleteUser("user1")
+	if users.Load(dir).Includes(owner, "user1") {
+		t.Errorf("Unexpected member: got %v want %v,", "user1", "none")
+	}
+}
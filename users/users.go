@@ -1,31 +1,75 @@
 package users
 
 import (
-	"errors"
+	"demo-store/common"
+	"sort"
+	"strings"
+	"sync"
 )
 
 type User struct {
 	UserName     string
 	HashPassword string
+	Disabled     bool   `json:",omitempty"`
+	Roles        []Role `json:",omitempty"`
 }
 
 func CreateUser(username string, password string) *User {
 	hashPwd, _ := HashPassword(password)
-	return &User{UserName: username, HashPassword: hashPwd}
+	return &User{UserName: username, HashPassword: hashPwd, Roles: DefaultRoles(username)}
 }
 
-var ErrorUserNotFound = errors.New("User not found")
-var ErrorUserExists = errors.New("User exists")
-var ErrorUserAuthentication = errors.New("User authentication failed. Username or Password is invalid")
+// AdminUserName is the user created to administer the store. It can't be
+// disabled, deleted or lose the admin role, so the store can't be locked out
+// of its user API.
+const AdminUserName = "admin"
+
+var ErrorUserNotFound = common.ErrorUserNotFound
+var ErrorUserExists = common.ErrorUserExists
+var ErrorUserAuthentication = common.ErrorUserAuthentication
+var ErrorUserDisabled = common.ErrorUserDisabled
+var ErrorInvalidUser = common.ErrorInvalidUser
+var ErrorUserProtected = common.ErrorUserProtected
 
 type UserDatabase interface {
 	AddUser(username string, password string) error
+	AddUserWithRoles(username string, password string, roles []Role) error
 	Authenticate(username string, password string) error
 	IsAdmin(username string) bool
+
+	// HasPermission reports whether the user's roles grant permission. Users
+	// the database doesn't know have the UnknownUserRoles, and disabled users
+	// have no permissions.
+	HasPermission(username string, permission Permission) bool
+
+	// CheckUser fails with ErrorUserNotFound or ErrorUserDisabled unless the
+	// user may use the store.
+	CheckUser(username string) error
+	ListUsers() []User
+	FindUser(username string) (*User, error)
+	SetPassword(username string, password string) error
+	SetDisabled(username string, disabled bool) error
+	SetRoles(username string, roles []Role) error
+	DeleteUser(username string) error
+
+	// Includes reports whether owner, a key owner or ACL grantee, is the user
+	// or names a group the user is a member of.
+	Includes(owner string, username string) bool
+	AddGroup(name string) error
+	FindGroup(name string) (*Group, error)
+	ListGroups() []Group
+	AddGroupMember(name string, username string) error
+	RemoveGroupMember(name string, username string) error
+	DeleteGroup(name string) error
 }
 
+// UserStorage keeps the users and groups in memory and, if it was loaded from
+// a cache directory, saves them back to it after every change.
 type UserStorage struct {
-	data map[string]*User
+	mutex  sync.RWMutex
+	data   map[string]*User
+	groups map[string]*Group
+	path   string
 }
 
 func (u *UserStorage) IsAdmin(username string) bool {
@@ -34,24 +78,153 @@ func (u *UserStorage) IsAdmin(username string) bool {
 		return false
 	}
 
-	return user.UserName == "admin"
+	return HasRole(user.Roles, RoleAdmin)
 }
-func (user *UserStorage) FindUser(username string) (*User, error) {
 
-	if user, ok := user.data[username]; ok {
-		return user, nil
+func (u *UserStorage) HasPermission(username string, permission Permission) bool {
+	user, err := u.FindUser(username)
+	if err != nil {
+		return RolesHavePermission(UnknownUserRoles, permission)
+	}
+	if user.Disabled {
+		return false
+	}
+
+	return RolesHavePermission(user.Roles, permission)
+}
+
+// FindUser returns a copy of the user.
+func (u *UserStorage) FindUser(username string) (*User, error) {
+
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	if user, ok := u.data[username]; ok {
+		found := *user
+		return &found, nil
 	}
 
 	return nil, ErrorUserNotFound
 }
 
-func (user *UserStorage) AddUser(username string, password string) error {
+func (u *UserStorage) CheckUser(username string) error {
+
+	user, err := u.FindUser(username)
+	if err != nil {
+		return err
+	}
+	if user.Disabled {
+		return ErrorUserDisabled
+	}
+	return nil
+}
+
+// ListUsers returns a copy of every user, ordered by name.
+func (u *UserStorage) ListUsers() []User {
 
-	if _, ok := user.data[username]; ok {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	users := make([]User, 0, len(u.data))
+	for _, user := range u.data {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].UserName < users[j].UserName
+	})
+	return users
+}
+
+func (u *UserStorage) AddUser(username string, password string) error {
+	return u.AddUserWithRoles(username, password, DefaultRoles(username))
+}
+
+func (u *UserStorage) AddUserWithRoles(username string, password string, roles []Role) error {
+
+	if err := ValidateUser(username, password); err != nil {
+		return err
+	}
+	if err := validateUserRoles(username, roles); err != nil {
+		return err
+	}
+	user := CreateUser(username, password)
+	user.Roles = append([]Role(nil), roles...)
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if _, ok := u.data[username]; ok {
 		return ErrorUserExists
 	}
 
-	user.data[username] = CreateUser(username, password)
+	u.data[username] = user
+	return u.commit(username, nil)
+}
+
+func (u *UserStorage) SetPassword(username string, password string) error {
+
+	if err := ValidateUser(username, password); err != nil {
+		return err
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return u.update(username, func(user *User) error {
+		user.HashPassword = hash
+		return nil
+	})
+}
+
+func (u *UserStorage) SetDisabled(username string, disabled bool) error {
+	return u.update(username, func(user *User) error {
+		if disabled && user.UserName == AdminUserName {
+			return ErrorUserProtected
+		}
+		user.Disabled = disabled
+		return nil
+	})
+}
+
+func (u *UserStorage) SetRoles(username string, roles []Role) error {
+
+	if err := validateUserRoles(username, roles); err != nil {
+		return err
+	}
+
+	return u.update(username, func(user *User) error {
+		user.Roles = append([]Role(nil), roles...)
+		return nil
+	})
+}
+
+func (u *UserStorage) DeleteUser(username string) error {
+
+	if username == AdminUserName {
+		return ErrorUserProtected
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	user, ok := u.data[username]
+	if !ok {
+		return ErrorUserNotFound
+	}
+
+	delete(u.data, username)
+	groups := u.removeFromGroups(username)
+	if err := u.commit(username, user); err != nil {
+		u.restoreGroups(groups)
+		return err
+	}
+	if len(groups) > 0 && u.path != "" {
+		if err := u.saveGroups(u.path); err != nil {
+			u.restoreGroups(groups)
+			return err
+		}
+	}
 	return nil
 }
 
@@ -64,6 +237,68 @@ func (u *UserStorage) Authenticate(username string, password string) error {
 	if ok := PasswordHashMatches(password, user.HashPassword); !ok {
 		return ErrorUserAuthentication
 	}
+	if user.Disabled {
+		return ErrorUserDisabled
+	}
+
+	return nil
+}
+
+// update changes a copy of the user, replacing the user with it once saved.
+func (u *UserStorage) update(username string, change func(user *User) error) error {
 
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	user, ok := u.data[username]
+	if !ok {
+		return ErrorUserNotFound
+	}
+
+	updated := *user
+	if err := change(&updated); err != nil {
+		return err
+	}
+
+	u.data[username] = &updated
+	return u.commit(username, user)
+}
+
+// commit saves the users after a change to username, putting back previous
+// if the change can't be saved. It is called with the mutex held.
+func (u *UserStorage) commit(username string, previous *User) error {
+
+	if u.path == "" {
+		return nil
+	}
+	if err := u.save(u.path); err != nil {
+		if previous != nil {
+			u.data[username] = previous
+		} else {
+			delete(u.data, username)
+		}
+		return err
+	}
+	return nil
+}
+
+// ValidateUser rejects empty usernames and passwords, and usernames that
+// couldn't be used to log in or be named in a URL path.
+func ValidateUser(username string, password string) error {
+	if username == "" || password == "" || strings.ContainsAny(username, ":/") {
+		return ErrorInvalidUser
+	}
+	return nil
+}
+
+// validateUserRoles checks the roles exist and that the admin user keeps the
+// admin role.
+func validateUserRoles(username string, roles []Role) error {
+	if err := ValidateRoles(roles); err != nil {
+		return err
+	}
+	if username == AdminUserName && !HasRole(roles, RoleAdmin) {
+		return ErrorUserProtected
+	}
 	return nil
 }
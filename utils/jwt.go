@@ -12,13 +12,17 @@ var jwtKey = []byte("my_secret_key")
 var TokenExpirationInMinutes int = 30
 var BearerTokenHeader = "Bearer "
 
+// Claims are carried by a token. Roles tells clients what the user may do;
+// the server checks the user's current roles instead, so changes to them
+// apply before the token expires.
 type Claims struct {
-	Username string `json:"username"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles,omitempty"`
 	jwt.StandardClaims
 }
 
 type Tokenizer interface {
-	CreateToken(username string) (string, error)
+	CreateToken(username string, roles []string) (string, error)
 	GetUsernameFromToken(tokenString string) (string, error)
 }
 
@@ -30,11 +34,12 @@ func NewJwtTokenizer(tracer Tracer) *JwtTokenizer {
 	return &JwtTokenizer{Tracer: tracer}
 }
 
-func (j *JwtTokenizer) CreateToken(username string) (string, error) {
+func (j *JwtTokenizer) CreateToken(username string, roles []string) (string, error) {
 
 	expirationTime := time.Now().Add(time.Minute * time.Duration(TokenExpirationInMinutes))
 	claims := &Claims{
 		Username: username,
+		Roles:    roles,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expirationTime.Unix(),
 			Issuer:    "UserJWTService",
@@ -0,0 +1,388 @@
+// Package websocket implements the parts of RFC 6455 the store needs: the
+// opening handshake, text and binary messages, fragmentation and the ping,
+// pong and close control frames.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooLarge = 1009
+)
+
+// MaxMessageSize limits a message, after reassembling its fragments.
+const MaxMessageSize = 1 << 20
+
+const acceptGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var ErrorBadHandshake = errors.New("Bad WebSocket handshake")
+var ErrorUnsupportedVersion = errors.New("Unsupported WebSocket version")
+var ErrorProtocol = errors.New("WebSocket protocol error")
+var ErrorMessageTooLarge = errors.New("WebSocket message too large")
+var ErrorClosed = errors.New("WebSocket closed")
+
+// CloseError is returned by ReadMessage once the peer has closed the
+// connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("WebSocket closed: %d %s", e.Code, e.Reason)
+}
+
+func (e *CloseError) Unwrap() error {
+	return ErrorClosed
+}
+
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	client bool
+
+	writeMutex sync.Mutex
+	closeSent  bool
+}
+
+// AcceptKey is the Sec-WebSocket-Accept value for a Sec-WebSocket-Key.
+func AcceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + acceptGuid))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Upgrade completes the server side of the opening handshake and takes over
+// the connection. It writes nothing if the handshake is invalid, leaving the
+// caller to respond with 400 Bad Request, or 426 Upgrade Required for
+// ErrorUnsupportedVersion.
+func Upgrade(resp http.ResponseWriter, req *http.Request) (*Conn, error) {
+
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if req.Method != http.MethodGet ||
+		!headerContains(req.Header, "Connection", "upgrade") ||
+		!headerContains(req.Header, "Upgrade", "websocket") ||
+		key == "" {
+		return nil, ErrorBadHandshake
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		resp.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, ErrorUnsupportedVersion
+	}
+
+	hijacker, ok := resp.(http.Hijacker)
+	if !ok {
+		return nil, ErrorBadHandshake
+	}
+	conn, buffer, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: conn, reader: buffer.Reader}, nil
+}
+
+// Dial opens a client connection to a ws:// URL, sending header with the
+// handshake request.
+func Dial(rawUrl string, header http.Header) (*Conn, error) {
+
+	target, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "ws" {
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrorBadHandshake, target.Scheme)
+	}
+	host := target.Host
+	if target.Port() == "" {
+		host = net.JoinHostPort(target.Hostname(), "80")
+	}
+
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+target.Host+target.RequestURI(), nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %s", ErrorBadHandshake, resp.Status)
+	}
+
+	return &Conn{conn: conn, reader: reader, client: true}, nil
+}
+
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next text or binary message, answering pings and
+// reassembling fragments on the way. Once the peer closes the connection it
+// replies and returns a *CloseError.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+
+	messageType := 0
+	var message []byte
+	for {
+		final, opcode, payload, err := c.readFrame()
+		if err != nil {
+			c.failWith(err)
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := c.WriteMessage(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+
+		case PongMessage:
+			continue
+
+		case CloseMessage:
+			closeErr := &CloseError{Code: CloseNormal}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.WriteClose(closeErr.Code, "")
+			return 0, nil, closeErr
+
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				c.failWith(ErrorProtocol)
+				return 0, nil, ErrorProtocol
+			}
+			messageType = opcode
+
+		case continuationFrame:
+			if messageType == 0 {
+				c.failWith(ErrorProtocol)
+				return 0, nil, ErrorProtocol
+			}
+
+		default:
+			c.failWith(ErrorProtocol)
+			return 0, nil, ErrorProtocol
+		}
+
+		if len(message)+len(payload) > MaxMessageSize {
+			c.failWith(ErrorMessageTooLarge)
+			return 0, nil, ErrorMessageTooLarge
+		}
+		message = append(message, payload...)
+
+		if final {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				c.WriteClose(CloseInvalidPayload, "invalid UTF-8")
+				return 0, nil, ErrorProtocol
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	final := header[0]&0x80 != 0
+	opcode := int(header[0] & 0x0f)
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, ErrorProtocol
+	}
+
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		// clients must mask every frame and servers must not
+		return false, 0, nil, ErrorProtocol
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	if opcode >= CloseMessage && (length > 125 || !final) {
+		return false, 0, nil, ErrorProtocol
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, ErrorMessageTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return final, opcode, payload, nil
+}
+
+// failWith closes the connection with the status matching a read error.
+func (c *Conn) failWith(err error) {
+	switch {
+	case errors.Is(err, ErrorMessageTooLarge):
+		c.WriteClose(CloseMessageTooLarge, "message too large")
+	case errors.Is(err, ErrorProtocol):
+		c.WriteClose(CloseProtocolError, "protocol error")
+	}
+}
+
+// WriteMessage sends data as a single frame. It is safe to call from several
+// goroutines.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if c.closeSent {
+		return ErrorClosed
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+
+	frame := make([]byte, 0, len(data)+14)
+	frame = append(frame, 0x80|byte(messageType))
+
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch {
+	case len(data) < 126:
+		frame = append(frame, maskBit|byte(len(data)))
+	case len(data) <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(data)))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(data)))
+	}
+
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, data...)
+		for i := range frame[start:] {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, data...)
+	}
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// WriteClose starts the closing handshake.
+func (c *Conn) WriteClose(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return c.WriteMessage(CloseMessage, append(payload, reason...))
+}
+
+// SetReadDeadline bounds how long ReadMessage waits, a zero time waiting
+// forever.
+func (c *Conn) SetReadDeadline(deadline time.Time) error {
+	return c.conn.SetReadDeadline(deadline)
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,111 @@
+package websocket_test
+
+import (
+	"demo-store/websocket"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// startEchoServer returns the ws:// URL of a server echoing every message.
+func startEchoServer(t *testing.T) string {
+
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		conn, err := websocket.Upgrade(resp, req)
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestAcceptKey(t *testing.T) {
+
+	// the example from RFC 6455 section 1.3
+	expected := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+	if key := websocket.AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); key != expected {
+		t.Errorf("Returned unexpected key: got %v want %v", key, expected)
+	}
+}
+
+func TestMessagesRoundTrip(t *testing.T) {
+
+	conn, err := websocket.Dial(startEchoServer(t), nil)
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+	defer conn.Close()
+
+	messages := []string{"", "hello", strings.Repeat("a", 300), strings.Repeat("b", 70000)}
+	for _, message := range messages {
+		conn.WriteMessage(websocket.TextMessage, []byte(message))
+
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+		}
+		if messageType != websocket.TextMessage || string(data) != message {
+			t.Errorf("Returned unexpected message of %v bytes: got %v bytes", len(message), len(data))
+		}
+	}
+}
+
+func TestPingIsAnswered(t *testing.T) {
+
+	conn, _ := websocket.Dial(startEchoServer(t), nil)
+	defer conn.Close()
+
+	conn.WriteMessage(websocket.PingMessage, []byte("ping"))
+	conn.WriteMessage(websocket.BinaryMessage, []byte{1, 2, 3})
+
+	// the pong is skipped and the echo returned
+	messageType, data, err := conn.ReadMessage()
+	if err != nil || messageType != websocket.BinaryMessage || len(data) != 3 {
+		t.Errorf("Returned unexpected message: got %v %v %v", messageType, data, err)
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+
+	conn, _ := websocket.Dial(startEchoServer(t), nil)
+	defer conn.Close()
+
+	conn.WriteClose(websocket.CloseNormal, "done")
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseNormal {
+		t.Errorf("Returned unexpected error: got %v want %v", err, websocket.CloseNormal)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("late")); !errors.Is(err, websocket.ErrorClosed) {
+		t.Errorf("Returned unexpected error: got %v want %v", err, websocket.ErrorClosed)
+	}
+}
+
+func TestUpgradeRejectsPlainRequest(t *testing.T) {
+
+	url := startEchoServer(t)
+	resp, err := http.Get("http" + strings.TrimPrefix(url, "ws"))
+	if err != nil {
+		t.Fatalf("Returned unexpected error: got %v want %v", err, "nil")
+	}
+
+	expected := http.StatusBadRequest
+	if resp.StatusCode != expected {
+		t.Errorf("Returned unexpected code: got %v want %v", resp.StatusCode, expected)
+	}
+}